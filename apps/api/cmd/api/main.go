@@ -73,6 +73,7 @@ func main() {
 	// Initialize handlers
 	jobHandler := handler.NewJobHandler(queries, producer)
 	storageHandler := handler.NewStorageHandler(storageClient)
+	profileHandler := handler.NewProfileHandler(queries)
 
 	// Set up router
 	r := chi.NewRouter()
@@ -102,6 +103,15 @@ func main() {
 		r.Post("/", jobHandler.CreateJob)
 		r.Get("/", jobHandler.ListJobs)
 		r.Get("/{id}", jobHandler.GetJob)
+		r.Delete("/{id}", jobHandler.CancelJob)
+		r.Post("/{id}/cancel", jobHandler.CancelJob)
+	})
+
+	r.Route("/profiles", func(r chi.Router) {
+		r.Get("/", profileHandler.ListProfiles)
+		r.Post("/", profileHandler.CreateProfile)
+		r.Get("/{name}", profileHandler.GetProfile)
+		r.Put("/{name}", profileHandler.UpdateProfile)
 	})
 
 	// Create server
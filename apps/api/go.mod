@@ -3,6 +3,8 @@ module github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/api
 go 1.23
 
 require (
+	github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/packages/codecs v0.0.0
+	github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/packages/jobqueue v0.0.0
 	github.com/aws/aws-sdk-go-v2 v1.32.7
 	github.com/aws/aws-sdk-go-v2/config v1.28.7
 	github.com/aws/aws-sdk-go-v2/credentials v1.17.48
@@ -45,3 +47,8 @@ require (
 	golang.org/x/text v0.21.0 // indirect
 	google.golang.org/protobuf v1.32.0 // indirect
 )
+
+replace (
+	github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/packages/codecs => ../../packages/codecs
+	github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/packages/jobqueue => ../../packages/jobqueue
+)
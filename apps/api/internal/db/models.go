@@ -11,13 +11,59 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ChunkStatus string
+
+const (
+	ChunkStatusPending    ChunkStatus = "pending"
+	ChunkStatusProcessing ChunkStatus = "processing"
+	ChunkStatusCompleted  ChunkStatus = "completed"
+	ChunkStatusFailed     ChunkStatus = "failed"
+)
+
+func (e *ChunkStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ChunkStatus(s)
+	case string:
+		*e = ChunkStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ChunkStatus: %T", src)
+	}
+	return nil
+}
+
+type NullChunkStatus struct {
+	ChunkStatus ChunkStatus `json:"chunk_status"`
+	Valid       bool        `json:"valid"` // Valid is true if ChunkStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullChunkStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ChunkStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ChunkStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullChunkStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ChunkStatus), nil
+}
+
 type JobStatus string
 
 const (
 	JobStatusQueued     JobStatus = "queued"
 	JobStatusProcessing JobStatus = "processing"
 	JobStatusCompleted  JobStatus = "completed"
+	JobStatusPartial    JobStatus = "partial"
 	JobStatusFailed     JobStatus = "failed"
+	JobStatusCancelled  JobStatus = "cancelled"
 )
 
 func (e *JobStatus) Scan(src interface{}) error {
@@ -55,23 +101,167 @@ func (ns NullJobStatus) Value() (driver.Value, error) {
 	return string(ns.JobStatus), nil
 }
 
+type RenditionStatus string
+
+const (
+	RenditionStatusPending    RenditionStatus = "pending"
+	RenditionStatusProcessing RenditionStatus = "processing"
+	RenditionStatusCompleted  RenditionStatus = "completed"
+	RenditionStatusFailed     RenditionStatus = "failed"
+)
+
+func (e *RenditionStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = RenditionStatus(s)
+	case string:
+		*e = RenditionStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for RenditionStatus: %T", src)
+	}
+	return nil
+}
+
+type NullRenditionStatus struct {
+	RenditionStatus RenditionStatus `json:"rendition_status"`
+	Valid           bool            `json:"valid"` // Valid is true if RenditionStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullRenditionStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.RenditionStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.RenditionStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullRenditionStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.RenditionStatus), nil
+}
+
+type JobArtifact struct {
+	ID          pgtype.UUID        `json:"id"`
+	JobID       pgtype.UUID        `json:"job_id"`
+	Kind        string             `json:"kind"`
+	OutputKey   string             `json:"output_key"`
+	ContentType string             `json:"content_type"`
+	Language    *string            `json:"language"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+type JobChunk struct {
+	ID              pgtype.UUID        `json:"id"`
+	JobID           pgtype.UUID        `json:"job_id"`
+	ChunkIndex      int32              `json:"chunk_index"`
+	SourceKey       string             `json:"source_key"`
+	StartSeconds    float64            `json:"start_seconds"`
+	DurationSeconds float64            `json:"duration_seconds"`
+	Status          ChunkStatus        `json:"status"`
+	Attempts        int32              `json:"attempts"`
+	WorkerID        *string            `json:"worker_id"`
+	ErrorMessage    *string            `json:"error_message"`
+	StartedAt       pgtype.Timestamptz `json:"started_at"`
+	FinishedAt      pgtype.Timestamptz `json:"finished_at"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+}
+
 type Job struct {
-	ID           pgtype.UUID        `json:"id"`
-	InputKey     string             `json:"input_key"`
-	Status       JobStatus          `json:"status"`
-	ErrorMessage *string            `json:"error_message"`
-	RetryCount   int32              `json:"retry_count"`
-	MaxRetries   int32              `json:"max_retries"`
-	StartedAt    pgtype.Timestamptz `json:"started_at"`
-	WorkerID     *string            `json:"worker_id"`
-	CreatedAt    pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	ID              pgtype.UUID        `json:"id"`
+	InputKey        string             `json:"input_key"`
+	Status          JobStatus          `json:"status"`
+	ErrorMessage    *string            `json:"error_message"`
+	ErrorCode       *string            `json:"error_code"`
+	RetryCount      int32              `json:"retry_count"`
+	MaxRetries      int32              `json:"max_retries"`
+	StartedAt       pgtype.Timestamptz `json:"started_at"`
+	WorkerID        *string            `json:"worker_id"`
+	Options         []byte             `json:"options"`
+	HlsMasterKey    *string            `json:"hls_master_key"`
+	DashManifestKey *string            `json:"dash_manifest_key"`
+	Ladder          []byte             `json:"ladder"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+}
+
+type MediaProbe struct {
+	JobID              pgtype.UUID        `json:"job_id"`
+	DurationSeconds    *float64           `json:"duration_seconds"`
+	Container          *string            `json:"container"`
+	VideoCodec         *string            `json:"video_codec"`
+	AudioCodec         *string            `json:"audio_codec"`
+	Width              *int32             `json:"width"`
+	Height             *int32             `json:"height"`
+	FrameRate          *float64           `json:"frame_rate"`
+	BitRate            *int64             `json:"bit_rate"`
+	Rotation           int32              `json:"rotation"`
+	AudioChannels      *int32             `json:"audio_channels"`
+	AudioChannelLayout *string            `json:"audio_channel_layout"`
+	FieldOrder         *string            `json:"field_order"`
+	ColorTransfer      *string            `json:"color_transfer"`
+	ColorPrimaries     *string            `json:"color_primaries"`
+	HdrFormat          *string            `json:"hdr_format"`
+	CreatedAt          pgtype.Timestamptz `json:"created_at"`
+}
+
+type Profile struct {
+	Name             string             `json:"name"`
+	Scale            string             `json:"scale"`
+	VideoCodec       string             `json:"video_codec"`
+	AudioCodec       string             `json:"audio_codec"`
+	Preset           string             `json:"preset"`
+	RateControl      string             `json:"rate_control"`
+	Crf              *int32             `json:"crf"`
+	VideoBitrate     *string            `json:"video_bitrate"`
+	MaxRate          *string            `json:"max_rate"`
+	BufSize          *string            `json:"buf_size"`
+	KeyframeInterval *float64           `json:"keyframe_interval"`
+	AudioBitrate     *string            `json:"audio_bitrate"`
+	MaxFps           *int32             `json:"max_fps"`
+	Container        string             `json:"container"`
+	AudioOnly        bool               `json:"audio_only"`
+	AudioSampleRate  *int32             `json:"audio_sample_rate"`
+	AudioChannels    *int32             `json:"audio_channels"`
+	MinVmaf          *float64           `json:"min_vmaf"`
+	Watermark        []byte             `json:"watermark"`
+	LoudnessTarget   *float64           `json:"loudness_target"`
+	TruePeakLimit    *float64           `json:"true_peak_limit"`
+	Deinterlace      string             `json:"deinterlace"`
+	ToneMap          string             `json:"tone_map"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
 }
 
 type Rendition struct {
-	ID         pgtype.UUID        `json:"id"`
-	JobID      pgtype.UUID        `json:"job_id"`
-	Resolution string             `json:"resolution"`
-	OutputKey  *string            `json:"output_key"`
-	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	ID                   pgtype.UUID        `json:"id"`
+	JobID                pgtype.UUID        `json:"job_id"`
+	Resolution           string             `json:"resolution"`
+	OutputKey            *string            `json:"output_key"`
+	HlsPlaylistKey       *string            `json:"hls_playlist_key"`
+	DashRepresentationID *string            `json:"dash_representation_id"`
+	Status               RenditionStatus    `json:"status"`
+	ErrorMessage         *string            `json:"error_message"`
+	StartedAt            pgtype.Timestamptz `json:"started_at"`
+	FinishedAt           pgtype.Timestamptz `json:"finished_at"`
+	OutputSizeBytes      *int64             `json:"output_size_bytes"`
+	DurationSeconds      *float64           `json:"duration_seconds"`
+	TargetBitrateKbps    *int32             `json:"target_bitrate_kbps"`
+	VmafMean             *float64           `json:"vmaf_mean"`
+	VmafMin              *float64           `json:"vmaf_min"`
+	VmafP5               *float64           `json:"vmaf_p5"`
+	PsnrMean             *float64           `json:"psnr_mean"`
+	SsimMean             *float64           `json:"ssim_mean"`
+	LoudnessInputI       *float64           `json:"loudness_input_i"`
+	LoudnessInputTp      *float64           `json:"loudness_input_tp"`
+	LoudnessInputLra     *float64           `json:"loudness_input_lra"`
+	LoudnessOutputI      *float64           `json:"loudness_output_i"`
+	LoudnessOutputTp     *float64           `json:"loudness_output_tp"`
+	LoudnessOutputLra    *float64           `json:"loudness_output_lra"`
+	CreatedAt            pgtype.Timestamptz `json:"created_at"`
 }
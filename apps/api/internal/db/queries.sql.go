@@ -11,24 +11,136 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const cancelJob = `-- name: CancelJob :one
+UPDATE jobs
+SET status = 'cancelled'
+WHERE id = $1 AND status IN ('queued', 'processing')
+RETURNING id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at
+`
+
+// Cancel a job that has not finished yet; returns no rows if it already reached a final state
+func (q *Queries) CancelJob(ctx context.Context, id pgtype.UUID) (Job, error) {
+	row := q.db.QueryRow(ctx, cancelJob, id)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.InputKey,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.ErrorCode,
+		&i.RetryCount,
+		&i.MaxRetries,
+		&i.StartedAt,
+		&i.WorkerID,
+		&i.Options,
+		&i.HlsMasterKey,
+		&i.DashManifestKey,
+		&i.Ladder,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const createJob = `-- name: CreateJob :one
-INSERT INTO jobs (input_key, status)
-VALUES ($1, 'queued')
-RETURNING id, input_key, status, error_message, retry_count, max_retries, started_at, worker_id, created_at, updated_at
+INSERT INTO jobs (input_key, status, options)
+VALUES ($1, 'queued', $2)
+RETURNING id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at
 `
 
-func (q *Queries) CreateJob(ctx context.Context, inputKey string) (Job, error) {
-	row := q.db.QueryRow(ctx, createJob, inputKey)
+type CreateJobParams struct {
+	InputKey string `json:"input_key"`
+	Options  []byte `json:"options"`
+}
+
+func (q *Queries) CreateJob(ctx context.Context, arg CreateJobParams) (Job, error) {
+	row := q.db.QueryRow(ctx, createJob, arg.InputKey, arg.Options)
 	var i Job
 	err := row.Scan(
 		&i.ID,
 		&i.InputKey,
 		&i.Status,
 		&i.ErrorMessage,
+		&i.ErrorCode,
 		&i.RetryCount,
 		&i.MaxRetries,
 		&i.StartedAt,
 		&i.WorkerID,
+		&i.Options,
+		&i.HlsMasterKey,
+		&i.DashManifestKey,
+		&i.Ladder,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createProfile = `-- name: CreateProfile :one
+INSERT INTO profiles (
+    name, scale, video_codec, audio_codec, preset, crf,
+    video_bitrate, audio_bitrate, max_fps, container,
+    audio_only, audio_sample_rate, audio_channels,
+    rate_control, max_rate, buf_size, keyframe_interval, min_vmaf,
+    watermark, loudness_target, true_peak_limit, deinterlace, tone_map
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
+RETURNING name, scale, video_codec, audio_codec, preset, rate_control, crf, video_bitrate, max_rate, buf_size, keyframe_interval, audio_bitrate, max_fps, container, audio_only, audio_sample_rate, audio_channels, min_vmaf, watermark, loudness_target, true_peak_limit, deinterlace, tone_map, created_at, updated_at
+`
+
+type CreateProfileParams struct {
+	Name             string   `json:"name"`
+	Scale            string   `json:"scale"`
+	VideoCodec       string   `json:"video_codec"`
+	AudioCodec       string   `json:"audio_codec"`
+	Preset           string   `json:"preset"`
+	Crf              *int32   `json:"crf"`
+	VideoBitrate     *string  `json:"video_bitrate"`
+	AudioBitrate     *string  `json:"audio_bitrate"`
+	MaxFps           *int32   `json:"max_fps"`
+	Container        string   `json:"container"`
+	AudioOnly        bool     `json:"audio_only"`
+	AudioSampleRate  *int32   `json:"audio_sample_rate"`
+	AudioChannels    *int32   `json:"audio_channels"`
+	RateControl      string   `json:"rate_control"`
+	MaxRate          *string  `json:"max_rate"`
+	BufSize          *string  `json:"buf_size"`
+	KeyframeInterval *float64 `json:"keyframe_interval"`
+	MinVmaf          *float64 `json:"min_vmaf"`
+	Watermark        []byte   `json:"watermark"`
+	LoudnessTarget   *float64 `json:"loudness_target"`
+	TruePeakLimit    *float64 `json:"true_peak_limit"`
+	Deinterlace      string   `json:"deinterlace"`
+	ToneMap          string   `json:"tone_map"`
+}
+
+func (q *Queries) CreateProfile(ctx context.Context, arg CreateProfileParams) (Profile, error) {
+	row := q.db.QueryRow(ctx, createProfile, arg.Name, arg.Scale, arg.VideoCodec, arg.AudioCodec, arg.Preset, arg.Crf, arg.VideoBitrate, arg.AudioBitrate, arg.MaxFps, arg.Container, arg.AudioOnly, arg.AudioSampleRate, arg.AudioChannels, arg.RateControl, arg.MaxRate, arg.BufSize, arg.KeyframeInterval, arg.MinVmaf, arg.Watermark, arg.LoudnessTarget, arg.TruePeakLimit, arg.Deinterlace, arg.ToneMap)
+	var i Profile
+	err := row.Scan(
+		&i.Name,
+		&i.Scale,
+		&i.VideoCodec,
+		&i.AudioCodec,
+		&i.Preset,
+		&i.RateControl,
+		&i.Crf,
+		&i.VideoBitrate,
+		&i.MaxRate,
+		&i.BufSize,
+		&i.KeyframeInterval,
+		&i.AudioBitrate,
+		&i.MaxFps,
+		&i.Container,
+		&i.AudioOnly,
+		&i.AudioSampleRate,
+		&i.AudioChannels,
+		&i.MinVmaf,
+		&i.Watermark,
+		&i.LoudnessTarget,
+		&i.TruePeakLimit,
+		&i.Deinterlace,
+		&i.ToneMap,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -38,7 +150,7 @@ func (q *Queries) CreateJob(ctx context.Context, inputKey string) (Job, error) {
 const createRendition = `-- name: CreateRendition :one
 INSERT INTO renditions (job_id, resolution)
 VALUES ($1, $2)
-RETURNING id, job_id, resolution, output_key, created_at
+RETURNING id, job_id, resolution, output_key, hls_playlist_key, dash_representation_id, status, error_message, started_at, finished_at, output_size_bytes, duration_seconds, target_bitrate_kbps, vmaf_mean, vmaf_min, vmaf_p5, psnr_mean, ssim_mean, loudness_input_i, loudness_input_tp, loudness_input_lra, loudness_output_i, loudness_output_tp, loudness_output_lra, created_at
 `
 
 type CreateRenditionParams struct {
@@ -54,13 +166,33 @@ func (q *Queries) CreateRendition(ctx context.Context, arg CreateRenditionParams
 		&i.JobID,
 		&i.Resolution,
 		&i.OutputKey,
+		&i.HlsPlaylistKey,
+		&i.DashRepresentationID,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.OutputSizeBytes,
+		&i.DurationSeconds,
+		&i.TargetBitrateKbps,
+		&i.VmafMean,
+		&i.VmafMin,
+		&i.VmafP5,
+		&i.PsnrMean,
+		&i.SsimMean,
+		&i.LoudnessInputI,
+		&i.LoudnessInputTp,
+		&i.LoudnessInputLra,
+		&i.LoudnessOutputI,
+		&i.LoudnessOutputTp,
+		&i.LoudnessOutputLra,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
 const getJob = `-- name: GetJob :one
-SELECT id, input_key, status, error_message, retry_count, max_retries, started_at, worker_id, created_at, updated_at FROM jobs
+SELECT id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at FROM jobs
 WHERE id = $1
 `
 
@@ -72,10 +204,158 @@ func (q *Queries) GetJob(ctx context.Context, id pgtype.UUID) (Job, error) {
 		&i.InputKey,
 		&i.Status,
 		&i.ErrorMessage,
+		&i.ErrorCode,
 		&i.RetryCount,
 		&i.MaxRetries,
 		&i.StartedAt,
 		&i.WorkerID,
+		&i.Options,
+		&i.HlsMasterKey,
+		&i.DashManifestKey,
+		&i.Ladder,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getJobArtifacts = `-- name: GetJobArtifacts :many
+SELECT id, job_id, kind, output_key, content_type, language, created_at FROM job_artifacts
+WHERE job_id = $1
+ORDER BY output_key
+`
+
+func (q *Queries) GetJobArtifacts(ctx context.Context, jobID pgtype.UUID) ([]JobArtifact, error) {
+	rows, err := q.db.Query(ctx, getJobArtifacts, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []JobArtifact{}
+	for rows.Next() {
+		var i JobArtifact
+		if err := rows.Scan(
+			&i.ID,
+			&i.JobID,
+			&i.Kind,
+			&i.OutputKey,
+			&i.ContentType,
+			&i.Language,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getJobChunks = `-- name: GetJobChunks :many
+SELECT id, job_id, chunk_index, source_key, start_seconds, duration_seconds, status, attempts, worker_id, error_message, started_at, finished_at, created_at, updated_at FROM job_chunks
+WHERE job_id = $1
+ORDER BY chunk_index
+`
+
+func (q *Queries) GetJobChunks(ctx context.Context, jobID pgtype.UUID) ([]JobChunk, error) {
+	rows, err := q.db.Query(ctx, getJobChunks, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []JobChunk{}
+	for rows.Next() {
+		var i JobChunk
+		if err := rows.Scan(
+			&i.ID,
+			&i.JobID,
+			&i.ChunkIndex,
+			&i.SourceKey,
+			&i.StartSeconds,
+			&i.DurationSeconds,
+			&i.Status,
+			&i.Attempts,
+			&i.WorkerID,
+			&i.ErrorMessage,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMediaProbe = `-- name: GetMediaProbe :one
+SELECT job_id, duration_seconds, container, video_codec, audio_codec, width, height, frame_rate, bit_rate, rotation, audio_channels, audio_channel_layout, field_order, color_transfer, color_primaries, hdr_format, created_at FROM media_probes
+WHERE job_id = $1
+`
+
+func (q *Queries) GetMediaProbe(ctx context.Context, jobID pgtype.UUID) (MediaProbe, error) {
+	row := q.db.QueryRow(ctx, getMediaProbe, jobID)
+	var i MediaProbe
+	err := row.Scan(
+		&i.JobID,
+		&i.DurationSeconds,
+		&i.Container,
+		&i.VideoCodec,
+		&i.AudioCodec,
+		&i.Width,
+		&i.Height,
+		&i.FrameRate,
+		&i.BitRate,
+		&i.Rotation,
+		&i.AudioChannels,
+		&i.AudioChannelLayout,
+		&i.FieldOrder,
+		&i.ColorTransfer,
+		&i.ColorPrimaries,
+		&i.HdrFormat,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getProfile = `-- name: GetProfile :one
+SELECT name, scale, video_codec, audio_codec, preset, rate_control, crf, video_bitrate, max_rate, buf_size, keyframe_interval, audio_bitrate, max_fps, container, audio_only, audio_sample_rate, audio_channels, min_vmaf, watermark, loudness_target, true_peak_limit, deinterlace, tone_map, created_at, updated_at FROM profiles
+WHERE name = $1
+`
+
+func (q *Queries) GetProfile(ctx context.Context, name string) (Profile, error) {
+	row := q.db.QueryRow(ctx, getProfile, name)
+	var i Profile
+	err := row.Scan(
+		&i.Name,
+		&i.Scale,
+		&i.VideoCodec,
+		&i.AudioCodec,
+		&i.Preset,
+		&i.RateControl,
+		&i.Crf,
+		&i.VideoBitrate,
+		&i.MaxRate,
+		&i.BufSize,
+		&i.KeyframeInterval,
+		&i.AudioBitrate,
+		&i.MaxFps,
+		&i.Container,
+		&i.AudioOnly,
+		&i.AudioSampleRate,
+		&i.AudioChannels,
+		&i.MinVmaf,
+		&i.Watermark,
+		&i.LoudnessTarget,
+		&i.TruePeakLimit,
+		&i.Deinterlace,
+		&i.ToneMap,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -83,7 +363,7 @@ func (q *Queries) GetJob(ctx context.Context, id pgtype.UUID) (Job, error) {
 }
 
 const getRendition = `-- name: GetRendition :one
-SELECT id, job_id, resolution, output_key, created_at FROM renditions
+SELECT id, job_id, resolution, output_key, hls_playlist_key, dash_representation_id, status, error_message, started_at, finished_at, output_size_bytes, duration_seconds, target_bitrate_kbps, vmaf_mean, vmaf_min, vmaf_p5, psnr_mean, ssim_mean, loudness_input_i, loudness_input_tp, loudness_input_lra, loudness_output_i, loudness_output_tp, loudness_output_lra, created_at FROM renditions
 WHERE id = $1
 `
 
@@ -95,13 +375,33 @@ func (q *Queries) GetRendition(ctx context.Context, id pgtype.UUID) (Rendition,
 		&i.JobID,
 		&i.Resolution,
 		&i.OutputKey,
+		&i.HlsPlaylistKey,
+		&i.DashRepresentationID,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.OutputSizeBytes,
+		&i.DurationSeconds,
+		&i.TargetBitrateKbps,
+		&i.VmafMean,
+		&i.VmafMin,
+		&i.VmafP5,
+		&i.PsnrMean,
+		&i.SsimMean,
+		&i.LoudnessInputI,
+		&i.LoudnessInputTp,
+		&i.LoudnessInputLra,
+		&i.LoudnessOutputI,
+		&i.LoudnessOutputTp,
+		&i.LoudnessOutputLra,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
 const getRenditionsByJobID = `-- name: GetRenditionsByJobID :many
-SELECT id, job_id, resolution, output_key, created_at FROM renditions
+SELECT id, job_id, resolution, output_key, hls_playlist_key, dash_representation_id, status, error_message, started_at, finished_at, output_size_bytes, duration_seconds, target_bitrate_kbps, vmaf_mean, vmaf_min, vmaf_p5, psnr_mean, ssim_mean, loudness_input_i, loudness_input_tp, loudness_input_lra, loudness_output_i, loudness_output_tp, loudness_output_lra, created_at FROM renditions
 WHERE job_id = $1
 ORDER BY resolution
 `
@@ -120,6 +420,26 @@ func (q *Queries) GetRenditionsByJobID(ctx context.Context, jobID pgtype.UUID) (
 			&i.JobID,
 			&i.Resolution,
 			&i.OutputKey,
+			&i.HlsPlaylistKey,
+			&i.DashRepresentationID,
+			&i.Status,
+			&i.ErrorMessage,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.OutputSizeBytes,
+			&i.DurationSeconds,
+			&i.TargetBitrateKbps,
+			&i.VmafMean,
+			&i.VmafMin,
+			&i.VmafP5,
+			&i.PsnrMean,
+			&i.SsimMean,
+			&i.LoudnessInputI,
+			&i.LoudnessInputTp,
+			&i.LoudnessInputLra,
+			&i.LoudnessOutputI,
+			&i.LoudnessOutputTp,
+			&i.LoudnessOutputLra,
 			&i.CreatedAt,
 		); err != nil {
 			return nil, err
@@ -133,7 +453,7 @@ func (q *Queries) GetRenditionsByJobID(ctx context.Context, jobID pgtype.UUID) (
 }
 
 const listJobs = `-- name: ListJobs :many
-SELECT id, input_key, status, error_message, retry_count, max_retries, started_at, worker_id, created_at, updated_at FROM jobs
+SELECT id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at FROM jobs
 ORDER BY created_at DESC
 LIMIT $1 OFFSET $2
 `
@@ -157,10 +477,15 @@ func (q *Queries) ListJobs(ctx context.Context, arg ListJobsParams) ([]Job, erro
 			&i.InputKey,
 			&i.Status,
 			&i.ErrorMessage,
+			&i.ErrorCode,
 			&i.RetryCount,
 			&i.MaxRetries,
 			&i.StartedAt,
 			&i.WorkerID,
+			&i.Options,
+			&i.HlsMasterKey,
+			&i.DashManifestKey,
+			&i.Ladder,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -175,7 +500,7 @@ func (q *Queries) ListJobs(ctx context.Context, arg ListJobsParams) ([]Job, erro
 }
 
 const listJobsByStatus = `-- name: ListJobsByStatus :many
-SELECT id, input_key, status, error_message, retry_count, max_retries, started_at, worker_id, created_at, updated_at FROM jobs
+SELECT id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at FROM jobs
 WHERE status = $1
 ORDER BY created_at DESC
 `
@@ -194,10 +519,66 @@ func (q *Queries) ListJobsByStatus(ctx context.Context, status JobStatus) ([]Job
 			&i.InputKey,
 			&i.Status,
 			&i.ErrorMessage,
+			&i.ErrorCode,
 			&i.RetryCount,
 			&i.MaxRetries,
 			&i.StartedAt,
 			&i.WorkerID,
+			&i.Options,
+			&i.HlsMasterKey,
+			&i.DashManifestKey,
+			&i.Ladder,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProfiles = `-- name: ListProfiles :many
+SELECT name, scale, video_codec, audio_codec, preset, rate_control, crf, video_bitrate, max_rate, buf_size, keyframe_interval, audio_bitrate, max_fps, container, audio_only, audio_sample_rate, audio_channels, min_vmaf, watermark, loudness_target, true_peak_limit, deinterlace, tone_map, created_at, updated_at FROM profiles
+ORDER BY name
+`
+
+func (q *Queries) ListProfiles(ctx context.Context) ([]Profile, error) {
+	rows, err := q.db.Query(ctx, listProfiles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Profile{}
+	for rows.Next() {
+		var i Profile
+		if err := rows.Scan(
+			&i.Name,
+			&i.Scale,
+			&i.VideoCodec,
+			&i.AudioCodec,
+			&i.Preset,
+			&i.RateControl,
+			&i.Crf,
+			&i.VideoBitrate,
+			&i.MaxRate,
+			&i.BufSize,
+			&i.KeyframeInterval,
+			&i.AudioBitrate,
+			&i.MaxFps,
+			&i.Container,
+			&i.AudioOnly,
+			&i.AudioSampleRate,
+			&i.AudioChannels,
+			&i.MinVmaf,
+			&i.Watermark,
+			&i.LoudnessTarget,
+			&i.TruePeakLimit,
+			&i.Deinterlace,
+			&i.ToneMap,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -215,7 +596,7 @@ const updateJobStatus = `-- name: UpdateJobStatus :one
 UPDATE jobs
 SET status = $2, error_message = $3
 WHERE id = $1
-RETURNING id, input_key, status, error_message, retry_count, max_retries, started_at, worker_id, created_at, updated_at
+RETURNING id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at
 `
 
 type UpdateJobStatusParams struct {
@@ -232,10 +613,102 @@ func (q *Queries) UpdateJobStatus(ctx context.Context, arg UpdateJobStatusParams
 		&i.InputKey,
 		&i.Status,
 		&i.ErrorMessage,
+		&i.ErrorCode,
 		&i.RetryCount,
 		&i.MaxRetries,
 		&i.StartedAt,
 		&i.WorkerID,
+		&i.Options,
+		&i.HlsMasterKey,
+		&i.DashManifestKey,
+		&i.Ladder,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const updateProfile = `-- name: UpdateProfile :one
+UPDATE profiles
+SET scale = $2,
+    video_codec = $3,
+    audio_codec = $4,
+    preset = $5,
+    crf = $6,
+    video_bitrate = $7,
+    audio_bitrate = $8,
+    max_fps = $9,
+    container = $10,
+    audio_only = $11,
+    audio_sample_rate = $12,
+    audio_channels = $13,
+    rate_control = $14,
+    max_rate = $15,
+    buf_size = $16,
+    keyframe_interval = $17,
+    min_vmaf = $18,
+    watermark = $19,
+    loudness_target = $20,
+    true_peak_limit = $21,
+    deinterlace = $22,
+    tone_map = $23
+WHERE name = $1
+RETURNING name, scale, video_codec, audio_codec, preset, rate_control, crf, video_bitrate, max_rate, buf_size, keyframe_interval, audio_bitrate, max_fps, container, audio_only, audio_sample_rate, audio_channels, min_vmaf, watermark, loudness_target, true_peak_limit, deinterlace, tone_map, created_at, updated_at
+`
+
+type UpdateProfileParams struct {
+	Name             string   `json:"name"`
+	Scale            string   `json:"scale"`
+	VideoCodec       string   `json:"video_codec"`
+	AudioCodec       string   `json:"audio_codec"`
+	Preset           string   `json:"preset"`
+	Crf              *int32   `json:"crf"`
+	VideoBitrate     *string  `json:"video_bitrate"`
+	AudioBitrate     *string  `json:"audio_bitrate"`
+	MaxFps           *int32   `json:"max_fps"`
+	Container        string   `json:"container"`
+	AudioOnly        bool     `json:"audio_only"`
+	AudioSampleRate  *int32   `json:"audio_sample_rate"`
+	AudioChannels    *int32   `json:"audio_channels"`
+	RateControl      string   `json:"rate_control"`
+	MaxRate          *string  `json:"max_rate"`
+	BufSize          *string  `json:"buf_size"`
+	KeyframeInterval *float64 `json:"keyframe_interval"`
+	MinVmaf          *float64 `json:"min_vmaf"`
+	Watermark        []byte   `json:"watermark"`
+	LoudnessTarget   *float64 `json:"loudness_target"`
+	TruePeakLimit    *float64 `json:"true_peak_limit"`
+	Deinterlace      string   `json:"deinterlace"`
+	ToneMap          string   `json:"tone_map"`
+}
+
+func (q *Queries) UpdateProfile(ctx context.Context, arg UpdateProfileParams) (Profile, error) {
+	row := q.db.QueryRow(ctx, updateProfile, arg.Name, arg.Scale, arg.VideoCodec, arg.AudioCodec, arg.Preset, arg.Crf, arg.VideoBitrate, arg.AudioBitrate, arg.MaxFps, arg.Container, arg.AudioOnly, arg.AudioSampleRate, arg.AudioChannels, arg.RateControl, arg.MaxRate, arg.BufSize, arg.KeyframeInterval, arg.MinVmaf, arg.Watermark, arg.LoudnessTarget, arg.TruePeakLimit, arg.Deinterlace, arg.ToneMap)
+	var i Profile
+	err := row.Scan(
+		&i.Name,
+		&i.Scale,
+		&i.VideoCodec,
+		&i.AudioCodec,
+		&i.Preset,
+		&i.RateControl,
+		&i.Crf,
+		&i.VideoBitrate,
+		&i.MaxRate,
+		&i.BufSize,
+		&i.KeyframeInterval,
+		&i.AudioBitrate,
+		&i.MaxFps,
+		&i.Container,
+		&i.AudioOnly,
+		&i.AudioSampleRate,
+		&i.AudioChannels,
+		&i.MinVmaf,
+		&i.Watermark,
+		&i.LoudnessTarget,
+		&i.TruePeakLimit,
+		&i.Deinterlace,
+		&i.ToneMap,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -246,7 +719,7 @@ const updateRenditionOutputKey = `-- name: UpdateRenditionOutputKey :one
 UPDATE renditions
 SET output_key = $2
 WHERE id = $1
-RETURNING id, job_id, resolution, output_key, created_at
+RETURNING id, job_id, resolution, output_key, hls_playlist_key, dash_representation_id, status, error_message, started_at, finished_at, output_size_bytes, duration_seconds, target_bitrate_kbps, vmaf_mean, vmaf_min, vmaf_p5, psnr_mean, ssim_mean, loudness_input_i, loudness_input_tp, loudness_input_lra, loudness_output_i, loudness_output_tp, loudness_output_lra, created_at
 `
 
 type UpdateRenditionOutputKeyParams struct {
@@ -262,6 +735,26 @@ func (q *Queries) UpdateRenditionOutputKey(ctx context.Context, arg UpdateRendit
 		&i.JobID,
 		&i.Resolution,
 		&i.OutputKey,
+		&i.HlsPlaylistKey,
+		&i.DashRepresentationID,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.OutputSizeBytes,
+		&i.DurationSeconds,
+		&i.TargetBitrateKbps,
+		&i.VmafMean,
+		&i.VmafMin,
+		&i.VmafP5,
+		&i.PsnrMean,
+		&i.SsimMean,
+		&i.LoudnessInputI,
+		&i.LoudnessInputTp,
+		&i.LoudnessInputLra,
+		&i.LoudnessOutputI,
+		&i.LoudnessOutputTp,
+		&i.LoudnessOutputLra,
 		&i.CreatedAt,
 	)
 	return i, err
@@ -2,11 +2,19 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/api/internal/db"
@@ -28,28 +36,194 @@ func NewJobHandler(queries *db.Queries, producer *queue.Producer) *JobHandler {
 	}
 }
 
+// supportedPackaging lists the packaging formats a job can request
+// in addition to the progressive MP4 renditions
+var supportedPackaging = map[string]bool{
+	"hls":  true,
+	"dash": true,
+}
+
+const (
+	// maxSubtitleTracks limits the sidecar subtitle files one job can supply
+	maxSubtitleTracks = 16
+	// maxConcatInputs limits the uploads one concat job can join
+	maxConcatInputs = 20
+	// minChunkSeconds and maxChunkSeconds bound the chunk length of a chunked job
+	minChunkSeconds = 10
+	maxChunkSeconds = 600
+)
+
+// languageTagRegex matches BCP 47 style language tags such as "en", "eng" or "pt-BR"
+var languageTagRegex = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{1,8})*$`)
+
 // CreateJobRequest represents the request body for creating a job
 type CreateJobRequest struct {
-	InputKey    string   `json:"input_key"`
-	Resolutions []string `json:"resolutions"`
+	InputKey        string             `json:"input_key"`
+	InputKeys       []string           `json:"input_keys,omitempty"`        // Concat job: uploads joined in order, instead of input_key
+	Trim            *TrimRequest       `json:"trim,omitempty"`              // Optional: keep only part of the input
+	Resolutions     []string           `json:"resolutions"`                 // Profile names, e.g. "720p"
+	Packaging       []string           `json:"packaging,omitempty"`         // Optional: "hls", "dash"
+	Thumbnails      *ThumbnailsOptions `json:"thumbnails,omitempty"`        // Optional: sprite sheets + WebVTT scrub previews
+	PerTitle        bool               `json:"per_title,omitempty"`         // Optional: fit bitrates and rungs to the source's complexity
+	QualityMetrics  bool               `json:"quality_metrics,omitempty"`   // Optional: score renditions against the source (VMAF, PSNR, SSIM)
+	Subtitles       []SubtitleInput    `json:"subtitles,omitempty"`         // Optional: SRT/VTT/ASS files uploaded alongside the input
+	BurnInSubtitles string             `json:"burn_in_subtitles,omitempty"` // Optional: language of the track to render into the picture
+	Watermark       *WatermarkSpec     `json:"watermark,omitempty"`         // Optional: logo/text overlay, replaces the profiles' watermarks
+	Chunked         *ChunkedOptions    `json:"chunked,omitempty"`           // Optional: split the encode across workers
+}
+
+// ChunkedOptions splits a job's encode across workers: the input is cut at keyframes into
+// chunks of about ChunkSeconds (default 60), encoded in parallel and stitched losslessly
+type ChunkedOptions struct {
+	ChunkSeconds float64 `json:"chunk_seconds,omitempty"`
+}
+
+// TrimRequest selects the part of the input to transcode. Timestamps are seconds ("90.5")
+// or [HH:]MM:SS[.fff] ("01:30.5"); an omitted end keeps everything after start.
+type TrimRequest struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+// TrimOptions is a validated trim range in seconds; zero EndSeconds means the end of the input
+type TrimOptions struct {
+	StartSeconds float64 `json:"start_seconds,omitempty"`
+	EndSeconds   float64 `json:"end_seconds,omitempty"`
+}
+
+// SubtitleInput is a subtitle file uploaded via /upload-url to accompany the input
+type SubtitleInput struct {
+	Key      string `json:"key"`
+	Language string `json:"language"` // e.g. "en", "es", "pt-BR"
+}
+
+// ThumbnailsOptions configures scrub preview generation
+// Omitted fields default to a frame every 10s, 160px wide tiles, 10x10 tiles per sprite sheet
+type ThumbnailsOptions struct {
+	IntervalSeconds float64 `json:"interval_seconds,omitempty"`
+	Width           int     `json:"width,omitempty"`
+	Columns         int     `json:"columns,omitempty"`
+	Rows            int     `json:"rows,omitempty"`
+}
+
+// JobOptions holds the optional processing settings stored in jobs.options
+// The worker decodes the same document before processing the job
+type JobOptions struct {
+	Packaging       []string           `json:"packaging,omitempty"`
+	Thumbnails      *ThumbnailsOptions `json:"thumbnails,omitempty"`
+	PerTitle        bool               `json:"per_title,omitempty"`
+	QualityMetrics  bool               `json:"quality_metrics,omitempty"`
+	Subtitles       []SubtitleInput    `json:"subtitles,omitempty"`
+	BurnInSubtitles string             `json:"burn_in_subtitles,omitempty"`
+	Trim            *TrimOptions       `json:"trim,omitempty"`
+	ConcatKeys      []string           `json:"concat_keys,omitempty"`
+	Watermark       *WatermarkSpec     `json:"watermark,omitempty"`
+	Chunked         *ChunkedOptions    `json:"chunked,omitempty"`
 }
 
 // JobResponse represents a job in API responses
 type JobResponse struct {
-	ID           string              `json:"id"`
-	InputKey     string              `json:"input_key"`
-	Status       string              `json:"status"`
-	ErrorMessage *string             `json:"error_message,omitempty"`
-	CreatedAt    string              `json:"created_at"`
-	UpdatedAt    string              `json:"updated_at"`
-	Renditions   []RenditionResponse `json:"renditions,omitempty"`
+	ID              string              `json:"id"`
+	InputKey        string              `json:"input_key"`
+	Status          string              `json:"status"`
+	ErrorMessage    *string             `json:"error_message,omitempty"`
+	ErrorCode       *string             `json:"error_code,omitempty"` // Failure kind, e.g. "invalid_input", when status is failed
+	HLSMasterKey    *string             `json:"hls_master_key,omitempty"`
+	DASHManifestKey *string             `json:"dash_manifest_key,omitempty"`
+	CreatedAt       string              `json:"created_at"`
+	UpdatedAt       string              `json:"updated_at"`
+	Source          *SourceResponse     `json:"source,omitempty"`
+	Renditions      []RenditionResponse `json:"renditions,omitempty"`
+	Artifacts       []ArtifactResponse  `json:"artifacts,omitempty"`
+	Ladder          json.RawMessage     `json:"ladder,omitempty"` // Per-title ladder chosen by the worker
+	Chunks          []ChunkResponse     `json:"chunks,omitempty"` // Chunked jobs only
+}
+
+// ChunkResponse represents one chunk of a chunked job in API responses
+type ChunkResponse struct {
+	Index           int32   `json:"index"`
+	StartSeconds    float64 `json:"start_seconds"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Status          string  `json:"status"`
+	Attempts        int32   `json:"attempts"`
+	WorkerID        *string `json:"worker_id,omitempty"`
+	ErrorMessage    *string `json:"error_message,omitempty"`
+}
+
+// ArtifactResponse represents an auxiliary job output such as a thumbnail sprite sheet
+type ArtifactResponse struct {
+	Kind        string  `json:"kind"` // "sprite", "thumbnails_vtt", "subtitles" or "captions"
+	OutputKey   string  `json:"output_key"`
+	ContentType string  `json:"content_type"`
+	Language    *string `json:"language,omitempty"` // Subtitle track language
+}
+
+// SourceResponse represents the probed input media metadata in API responses
+type SourceResponse struct {
+	DurationSeconds    *float64 `json:"duration_seconds,omitempty"`
+	Container          *string  `json:"container,omitempty"`
+	VideoCodec         *string  `json:"video_codec,omitempty"`
+	AudioCodec         *string  `json:"audio_codec,omitempty"`
+	Width              *int32   `json:"width,omitempty"`
+	Height             *int32   `json:"height,omitempty"`
+	FrameRate          *float64 `json:"frame_rate,omitempty"`
+	BitRate            *int64   `json:"bit_rate,omitempty"`
+	Rotation           int32    `json:"rotation"`
+	AudioChannels      *int32   `json:"audio_channels,omitempty"`
+	AudioChannelLayout *string  `json:"audio_channel_layout,omitempty"`
+	FieldOrder         *string  `json:"field_order,omitempty"` // Detected scan type: progressive, tff or bff
+	ColorTransfer      *string  `json:"color_transfer,omitempty"`
+	ColorPrimaries     *string  `json:"color_primaries,omitempty"`
+	HDRFormat          *string  `json:"hdr_format,omitempty"` // "pq" or "hlg" for HDR sources
 }
 
 // RenditionResponse represents a rendition in API responses
 type RenditionResponse struct {
-	ID         string  `json:"id"`
-	Resolution string  `json:"resolution"`
-	OutputKey  *string `json:"output_key,omitempty"`
+	ID                   string            `json:"id"`
+	Resolution           string            `json:"resolution"`
+	OutputKey            *string           `json:"output_key,omitempty"`
+	HLSPlaylistKey       *string           `json:"hls_playlist_key,omitempty"`
+	DASHRepresentationID *string           `json:"dash_representation_id,omitempty"` // Representation inside the job's DASH manifest
+	Status               string            `json:"status"`
+	ErrorMessage         *string           `json:"error_message,omitempty"`
+	StartedAt            *string           `json:"started_at,omitempty"`
+	FinishedAt           *string           `json:"finished_at,omitempty"`
+	OutputSizeBytes      *int64            `json:"output_size_bytes,omitempty"`
+	DurationSeconds      *float64          `json:"duration_seconds,omitempty"`    // Duration of the encoded output
+	TargetBitrateKbps    *int32            `json:"target_bitrate_kbps,omitempty"` // Set by per-title analysis
+	Quality              *QualityResponse  `json:"quality,omitempty"`
+	Loudness             *LoudnessResponse `json:"loudness,omitempty"` // Set when the profile normalizes loudness
+	Progress             *ProgressResponse `json:"progress,omitempty"`
+}
+
+// QualityResponse holds a rendition's objective quality scores against the source
+type QualityResponse struct {
+	VMAFMean float64 `json:"vmaf_mean"`
+	VMAFMin  float64 `json:"vmaf_min"`
+	VMAFP5   float64 `json:"vmaf_p5"` // 5th percentile frame
+	PSNRMean float64 `json:"psnr_mean"`
+	SSIMMean float64 `json:"ssim_mean"`
+}
+
+// LoudnessResponse holds a rendition's EBU R128 loudness before and after normalization
+type LoudnessResponse struct {
+	Input  LoudnessMeasurement `json:"input"`
+	Output LoudnessMeasurement `json:"output"`
+}
+
+// LoudnessMeasurement is one EBU R128 measurement
+type LoudnessMeasurement struct {
+	IntegratedLUFS float64 `json:"integrated_lufs"`
+	TruePeakDBTP   float64 `json:"true_peak_dbtp"`
+	RangeLU        float64 `json:"range_lu"`
+}
+
+// ProgressResponse represents the live encode progress of a rendition in API responses
+type ProgressResponse struct {
+	Percent    float64 `json:"percent"`
+	Speed      float64 `json:"speed"`
+	ETASeconds int64   `json:"eta_seconds"`
+	UpdatedAt  string  `json:"updated_at"`
 }
 
 // CreateJob handles POST /jobs
@@ -60,13 +234,151 @@ func (h *JobHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A concat job lists its inputs in input_keys; the first one is stored as the job's input_key
+	if len(req.InputKeys) > 0 {
+		if req.InputKey != "" {
+			http.Error(w, "use either input_key or input_keys, not both", http.StatusBadRequest)
+			return
+		}
+		if len(req.InputKeys) < 2 || len(req.InputKeys) > maxConcatInputs {
+			http.Error(w, fmt.Sprintf("input_keys must list between 2 and %d uploads", maxConcatInputs), http.StatusBadRequest)
+			return
+		}
+		for _, key := range req.InputKeys {
+			if !isUploadKey(key) {
+				http.Error(w, fmt.Sprintf("invalid input key: %s", key), http.StatusBadRequest)
+				return
+			}
+		}
+		req.InputKey = req.InputKeys[0]
+	}
+
 	if req.InputKey == "" {
 		http.Error(w, "input_key is required", http.StatusBadRequest)
 		return
 	}
 
+	var trim *TrimOptions
+	if req.Trim != nil {
+		var err error
+		trim, err = parseTrim(*req.Trim)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	for _, p := range req.Packaging {
+		if !supportedPackaging[p] {
+			http.Error(w, fmt.Sprintf("unsupported packaging: %s", p), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Every requested resolution must name a profile in the registry
+	resolutions := req.Resolutions
+	if len(resolutions) == 0 {
+		resolutions = []string{"480p", "720p", "1080p"} // Default fallback
+	}
+	for _, res := range resolutions {
+		profile, err := h.queries.GetProfile(r.Context(), res)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				http.Error(w, fmt.Sprintf("unknown profile: %s", res), http.StatusBadRequest)
+				return
+			}
+			log.Printf("Failed to look up profile %s: %v", res, err)
+			http.Error(w, "Failed to create job", http.StatusInternalServerError)
+			return
+		}
+		// Reject packaging the worker would only fail after encoding every rendition
+		for _, p := range req.Packaging {
+			if err := packagingError(profile, p); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	if t := req.Thumbnails; t != nil {
+		if t.IntervalSeconds < 0 || t.IntervalSeconds > 3600 {
+			http.Error(w, "thumbnails.interval_seconds must be between 0 and 3600", http.StatusBadRequest)
+			return
+		}
+		if t.Width < 0 || t.Width > 1920 {
+			http.Error(w, "thumbnails.width must be between 0 and 1920", http.StatusBadRequest)
+			return
+		}
+		if t.Columns < 0 || t.Columns > 20 || t.Rows < 0 || t.Rows > 20 {
+			http.Error(w, "thumbnails.columns and thumbnails.rows must be between 0 and 20", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if len(req.Subtitles) > maxSubtitleTracks {
+		http.Error(w, fmt.Sprintf("at most %d subtitle files are allowed", maxSubtitleTracks), http.StatusBadRequest)
+		return
+	}
+	for _, s := range req.Subtitles {
+		if s.Key == "" || !subtitleExtensions[strings.ToLower(filepath.Ext(s.Key))] {
+			http.Error(w, "subtitles[].key must name an .srt, .vtt, .ass or .ssa file", http.StatusBadRequest)
+			return
+		}
+		if !isUploadKey(s.Key) {
+			http.Error(w, fmt.Sprintf("invalid subtitle key: %s", s.Key), http.StatusBadRequest)
+			return
+		}
+		if !languageTagRegex.MatchString(s.Language) {
+			http.Error(w, fmt.Sprintf("invalid subtitle language: %q", s.Language), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.BurnInSubtitles != "" && !languageTagRegex.MatchString(req.BurnInSubtitles) {
+		http.Error(w, fmt.Sprintf("invalid burn_in_subtitles language: %q", req.BurnInSubtitles), http.StatusBadRequest)
+		return
+	}
+
+	if req.Watermark != nil {
+		if err := req.Watermark.validate("watermark"); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if c := req.Chunked; c != nil {
+		if c.ChunkSeconds != 0 && (c.ChunkSeconds < minChunkSeconds || c.ChunkSeconds > maxChunkSeconds) {
+			http.Error(w, fmt.Sprintf("chunked.chunk_seconds must be between %d and %d", minChunkSeconds, maxChunkSeconds), http.StatusBadRequest)
+			return
+		}
+		// Chunks restart their timestamps at zero, which a subtitle track cannot follow
+		if req.BurnInSubtitles != "" {
+			http.Error(w, "burn_in_subtitles is not supported with chunked encoding", http.StatusBadRequest)
+			return
+		}
+	}
+
+	options, err := json.Marshal(JobOptions{
+		Packaging:       req.Packaging,
+		Thumbnails:      req.Thumbnails,
+		PerTitle:        req.PerTitle,
+		QualityMetrics:  req.QualityMetrics,
+		Subtitles:       req.Subtitles,
+		BurnInSubtitles: req.BurnInSubtitles,
+		Trim:            trim,
+		ConcatKeys:      req.InputKeys,
+		Watermark:       req.Watermark,
+		Chunked:         req.Chunked,
+	})
+	if err != nil {
+		http.Error(w, "Invalid job options", http.StatusBadRequest)
+		return
+	}
+
 	// Create job in database
-	job, err := h.queries.CreateJob(r.Context(), req.InputKey)
+	job, err := h.queries.CreateJob(r.Context(), db.CreateJobParams{
+		InputKey: req.InputKey,
+		Options:  options,
+	})
 	if err != nil {
 		log.Printf("Failed to create job: %v", err)
 		http.Error(w, "Failed to create job", http.StatusInternalServerError)
@@ -80,10 +392,6 @@ func (h *JobHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
 	jobID := uuidToString(job.ID)
 
 	// Create rendition records for each resolution
-	resolutions := req.Resolutions
-	if len(resolutions) == 0 {
-		resolutions = []string{"480p", "720p", "1080p"} // Default fallback
-	}
 	for _, res := range resolutions {
 		_, err := h.queries.CreateRendition(r.Context(), db.CreateRenditionParams{
 			JobID:      job.ID,
@@ -113,7 +421,7 @@ func (h *JobHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
 // GetJob handles GET /jobs/{id}
 func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
 	idParam := chi.URLParam(r, "id")
-	
+
 	jobUUID, err := uuid.Parse(idParam)
 	if err != nil {
 		http.Error(w, "Invalid job ID", http.StatusBadRequest)
@@ -131,6 +439,105 @@ func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	renditions, _ := h.queries.GetRenditionsByJobID(r.Context(), job.ID)
+	resp := jobToResponse(job, renditions)
+
+	// Source metadata is only present once a worker has probed the input
+	probe, err := h.queries.GetMediaProbe(r.Context(), job.ID)
+	if err == nil {
+		resp.Source = probeToResponse(probe)
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("Failed to get media probe for job %s: %v", idParam, err)
+	}
+
+	artifacts, err := h.queries.GetJobArtifacts(r.Context(), job.ID)
+	if err != nil {
+		log.Printf("Failed to get artifacts for job %s: %v", idParam, err)
+	}
+	for _, a := range artifacts {
+		resp.Artifacts = append(resp.Artifacts, ArtifactResponse{
+			Kind:        a.Kind,
+			OutputKey:   a.OutputKey,
+			ContentType: a.ContentType,
+			Language:    a.Language,
+		})
+	}
+
+	chunks, err := h.queries.GetJobChunks(r.Context(), job.ID)
+	if err != nil {
+		log.Printf("Failed to get chunks for job %s: %v", idParam, err)
+	}
+	for _, c := range chunks {
+		resp.Chunks = append(resp.Chunks, ChunkResponse{
+			Index:           c.ChunkIndex,
+			StartSeconds:    c.StartSeconds,
+			DurationSeconds: c.DurationSeconds,
+			Status:          string(c.Status),
+			Attempts:        c.Attempts,
+			WorkerID:        c.WorkerID,
+			ErrorMessage:    c.ErrorMessage,
+		})
+	}
+
+	// Live encode progress is published to Redis by the worker while renditions are transcoding
+	progress, err := h.producer.Progress(r.Context(), idParam)
+	if err != nil {
+		log.Printf("Failed to get progress for job %s: %v", idParam, err)
+	}
+	for i, rend := range resp.Renditions {
+		if p, ok := progress[rend.Resolution]; ok {
+			resp.Renditions[i].Progress = &ProgressResponse{
+				Percent:    p.Percent,
+				Speed:      p.Speed,
+				ETASeconds: p.ETASeconds,
+				UpdatedAt:  p.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// CancelJob handles DELETE /jobs/{id} and POST /jobs/{id}/cancel
+func (h *JobHandler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	idParam := chi.URLParam(r, "id")
+
+	jobUUID, err := uuid.Parse(idParam)
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	pgUUID := pgtype.UUID{
+		Bytes: jobUUID,
+		Valid: true,
+	}
+
+	job, err := h.queries.CancelJob(r.Context(), pgUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Either the job doesn't exist or it already finished
+		existing, getErr := h.queries.GetJob(r.Context(), pgUUID)
+		if getErr != nil {
+			http.Error(w, "Job not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Job is already %s", existing.Status), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to cancel job %s: %v", idParam, err)
+		http.Error(w, "Failed to cancel job", http.StatusInternalServerError)
+		return
+	}
+
+	// Tell the worker holding the job to stop, and drop queued copies
+	if err := h.producer.Cancel(r.Context(), idParam); err != nil {
+		log.Printf("Failed to signal cancellation for job %s: %v", idParam, err)
+		http.Error(w, "Failed to cancel job", http.StatusInternalServerError)
+		return
+	}
+
 	renditions, _ := h.queries.GetRenditionsByJobID(r.Context(), job.ID)
 
 	w.Header().Set("Content-Type", "application/json")
@@ -168,25 +575,164 @@ func uuidToString(u pgtype.UUID) string {
 	return uuid.UUID(u.Bytes).String()
 }
 
+func timestampToString(t pgtype.Timestamptz) *string {
+	if !t.Valid {
+		return nil
+	}
+	s := t.Time.Format("2006-01-02T15:04:05Z07:00")
+	return &s
+}
+
 func jobToResponse(job db.Job, renditions []db.Rendition) JobResponse {
 	resp := JobResponse{
-		ID:           uuidToString(job.ID),
-		InputKey:     job.InputKey,
-		Status:       string(job.Status),
-		ErrorMessage: job.ErrorMessage,
-		CreatedAt:    job.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:    job.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
-		Renditions:   make([]RenditionResponse, 0, len(renditions)),
+		ID:              uuidToString(job.ID),
+		InputKey:        job.InputKey,
+		Status:          string(job.Status),
+		ErrorMessage:    job.ErrorMessage,
+		ErrorCode:       job.ErrorCode,
+		HLSMasterKey:    job.HlsMasterKey,
+		DASHManifestKey: job.DashManifestKey,
+		CreatedAt:       job.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:       job.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		Renditions:      make([]RenditionResponse, 0, len(renditions)),
+	}
+	if len(job.Ladder) > 0 {
+		resp.Ladder = json.RawMessage(job.Ladder)
 	}
 
 	for _, r := range renditions {
 		resp.Renditions = append(resp.Renditions, RenditionResponse{
-			ID:         uuidToString(r.ID),
-			Resolution: r.Resolution,
-			OutputKey:  r.OutputKey,
+			ID:                   uuidToString(r.ID),
+			Resolution:           r.Resolution,
+			OutputKey:            r.OutputKey,
+			HLSPlaylistKey:       r.HlsPlaylistKey,
+			DASHRepresentationID: r.DashRepresentationID,
+			Status:               string(r.Status),
+			ErrorMessage:         r.ErrorMessage,
+			StartedAt:            timestampToString(r.StartedAt),
+			FinishedAt:           timestampToString(r.FinishedAt),
+			OutputSizeBytes:      r.OutputSizeBytes,
+			DurationSeconds:      r.DurationSeconds,
+			TargetBitrateKbps:    r.TargetBitrateKbps,
+			Quality:              qualityToResponse(r),
+			Loudness:             loudnessToResponse(r),
 		})
 	}
 
 	return resp
 }
 
+// qualityToResponse returns nil for renditions that were not scored
+func qualityToResponse(r db.Rendition) *QualityResponse {
+	if r.VmafMean == nil {
+		return nil
+	}
+	q := &QualityResponse{VMAFMean: *r.VmafMean}
+	if r.VmafMin != nil {
+		q.VMAFMin = *r.VmafMin
+	}
+	if r.VmafP5 != nil {
+		q.VMAFP5 = *r.VmafP5
+	}
+	if r.PsnrMean != nil {
+		q.PSNRMean = *r.PsnrMean
+	}
+	if r.SsimMean != nil {
+		q.SSIMMean = *r.SsimMean
+	}
+	return q
+}
+
+// loudnessToResponse returns nil for renditions that were not normalized
+func loudnessToResponse(r db.Rendition) *LoudnessResponse {
+	if r.LoudnessInputI == nil || r.LoudnessOutputI == nil {
+		return nil
+	}
+	l := &LoudnessResponse{
+		Input:  LoudnessMeasurement{IntegratedLUFS: *r.LoudnessInputI},
+		Output: LoudnessMeasurement{IntegratedLUFS: *r.LoudnessOutputI},
+	}
+	if r.LoudnessInputTp != nil {
+		l.Input.TruePeakDBTP = *r.LoudnessInputTp
+	}
+	if r.LoudnessInputLra != nil {
+		l.Input.RangeLU = *r.LoudnessInputLra
+	}
+	if r.LoudnessOutputTp != nil {
+		l.Output.TruePeakDBTP = *r.LoudnessOutputTp
+	}
+	if r.LoudnessOutputLra != nil {
+		l.Output.RangeLU = *r.LoudnessOutputLra
+	}
+	return l
+}
+
+func probeToResponse(p db.MediaProbe) *SourceResponse {
+	return &SourceResponse{
+		DurationSeconds:    p.DurationSeconds,
+		Container:          p.Container,
+		VideoCodec:         p.VideoCodec,
+		AudioCodec:         p.AudioCodec,
+		Width:              p.Width,
+		Height:             p.Height,
+		FrameRate:          p.FrameRate,
+		BitRate:            p.BitRate,
+		Rotation:           p.Rotation,
+		AudioChannels:      p.AudioChannels,
+		AudioChannelLayout: p.AudioChannelLayout,
+		FieldOrder:         p.FieldOrder,
+		ColorTransfer:      p.ColorTransfer,
+		ColorPrimaries:     p.ColorPrimaries,
+		HDRFormat:          p.HdrFormat,
+	}
+}
+
+// isUploadKey reports whether key names an object under uploads/, where clients put their
+// files, so a job can't make the worker read other objects in the bucket
+func isUploadKey(key string) bool {
+	return strings.HasPrefix(key, "uploads/") && !strings.Contains(key, "..")
+}
+
+// parseTrim validates a trim request and converts its timestamps to seconds
+func parseTrim(t TrimRequest) (*TrimOptions, error) {
+	var opts TrimOptions
+	var err error
+	if t.Start != "" {
+		if opts.StartSeconds, err = parseTimestamp(t.Start); err != nil {
+			return nil, fmt.Errorf("invalid trim.start: %w", err)
+		}
+	}
+	if t.End != "" {
+		if opts.EndSeconds, err = parseTimestamp(t.End); err != nil {
+			return nil, fmt.Errorf("invalid trim.end: %w", err)
+		}
+		if opts.EndSeconds <= opts.StartSeconds {
+			return nil, errors.New("trim.end must be after trim.start")
+		}
+	}
+	if opts.StartSeconds == 0 && opts.EndSeconds == 0 {
+		return nil, errors.New("trim needs a start or an end")
+	}
+	return &opts, nil
+}
+
+// parseTimestamp parses "90.5", "01:30.5" or "00:01:30.5" into seconds
+func parseTimestamp(value string) (float64, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("%q is not a timestamp", value)
+	}
+	var seconds float64
+	for i, part := range parts {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 || math.IsInf(n, 0) || math.IsNaN(n) {
+			return 0, fmt.Errorf("%q is not a timestamp", value)
+		}
+		// Only the seconds field may be fractional, and fields after the first stay below 60
+		if (i < len(parts)-1 && n != math.Trunc(n)) || (i > 0 && n >= 60) {
+			return 0, fmt.Errorf("%q is not a timestamp", value)
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds, nil
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/api/internal/db"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{value: "90.5", want: 90.5},
+		{value: "0", want: 0},
+		{value: "01:30.5", want: 90.5},
+		{value: "00:01:30.5", want: 90.5},
+		{value: "1:00:00", want: 3600},
+		{value: "100:00", want: 6000},
+		{value: "", wantErr: true},
+		{value: "abc", wantErr: true},
+		{value: "-5", wantErr: true},
+		{value: "1:60", wantErr: true},
+		{value: "1:00:60", wantErr: true},
+		{value: "1.5:30", wantErr: true},
+		{value: "1:2:3:4", wantErr: true},
+		{value: "NaN", wantErr: true},
+		{value: "Inf", wantErr: true},
+		{value: "01::30", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseTimestamp(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseTimestamp(%q) = %g, want an error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseTimestamp(%q) = %g, %v, want %g", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseTrim(t *testing.T) {
+	tests := []struct {
+		name    string
+		trim    TrimRequest
+		want    TrimOptions
+		wantErr bool
+	}{
+		{name: "start and end", trim: TrimRequest{Start: "00:10", End: "01:00"}, want: TrimOptions{StartSeconds: 10, EndSeconds: 60}},
+		{name: "start only", trim: TrimRequest{Start: "5"}, want: TrimOptions{StartSeconds: 5}},
+		{name: "end only", trim: TrimRequest{End: "30"}, want: TrimOptions{EndSeconds: 30}},
+		{name: "empty", trim: TrimRequest{}, wantErr: true},
+		{name: "zero start only", trim: TrimRequest{Start: "0"}, wantErr: true},
+		{name: "end before start", trim: TrimRequest{Start: "30", End: "10"}, wantErr: true},
+		{name: "end equals start", trim: TrimRequest{Start: "10", End: "10"}, wantErr: true},
+		{name: "invalid start", trim: TrimRequest{Start: "x"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTrim(tt.trim)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseTrim() = %+v, want an error", *got)
+				}
+				return
+			}
+			if err != nil || *got != tt.want {
+				t.Errorf("parseTrim() = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsUploadKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{key: "uploads/abc/movie.en.srt", want: true},
+		{key: "uploads/abc/input.mp4", want: true},
+		{key: "outputs/abc/720p.mp4", want: false},
+		{key: "/uploads/abc/movie.srt", want: false},
+		{key: "uploads/../outputs/abc/movie.srt", want: false},
+		{key: "uploads/abc/..srt", want: false},
+		{key: "movie.srt", want: false},
+		{key: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := isUploadKey(tt.key); got != tt.want {
+			t.Errorf("isUploadKey(%q) = %t, want %t", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestJobToResponseRenditionStatus(t *testing.T) {
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	finished := started.Add(90 * time.Second)
+	size := int64(1 << 20)
+	duration := 12.5
+	errMsg := "ffmpeg: exit status 1"
+
+	renditions := []db.Rendition{
+		{
+			Resolution:      "720p",
+			Status:          db.RenditionStatusCompleted,
+			StartedAt:       pgtype.Timestamptz{Time: started, Valid: true},
+			FinishedAt:      pgtype.Timestamptz{Time: finished, Valid: true},
+			OutputSizeBytes: &size,
+			DurationSeconds: &duration,
+		},
+		{
+			Resolution:   "1080p",
+			Status:       db.RenditionStatusFailed,
+			ErrorMessage: &errMsg,
+			StartedAt:    pgtype.Timestamptz{Time: started, Valid: true},
+			FinishedAt:   pgtype.Timestamptz{Time: finished, Valid: true},
+		},
+		{Resolution: "480p", Status: db.RenditionStatusPending},
+	}
+	resp := jobToResponse(db.Job{Status: db.JobStatusPartial}, renditions)
+	if resp.Status != "partial" {
+		t.Errorf("job status = %s, want partial", resp.Status)
+	}
+	if len(resp.Renditions) != len(renditions) {
+		t.Fatalf("got %d renditions, want %d", len(resp.Renditions), len(renditions))
+	}
+
+	done := resp.Renditions[0]
+	if done.Status != "completed" || done.ErrorMessage != nil {
+		t.Errorf("720p status = %s, error %v, want completed without an error", done.Status, done.ErrorMessage)
+	}
+	if done.StartedAt == nil || *done.StartedAt != "2026-01-02T03:04:05Z" {
+		t.Errorf("720p started_at = %v, want 2026-01-02T03:04:05Z", done.StartedAt)
+	}
+	if done.FinishedAt == nil || *done.FinishedAt != "2026-01-02T03:05:35Z" {
+		t.Errorf("720p finished_at = %v, want 2026-01-02T03:05:35Z", done.FinishedAt)
+	}
+	if done.OutputSizeBytes == nil || *done.OutputSizeBytes != size || done.DurationSeconds == nil || *done.DurationSeconds != duration {
+		t.Errorf("720p output size, duration = %v, %v, want %d, %g", done.OutputSizeBytes, done.DurationSeconds, size, duration)
+	}
+
+	if failed := resp.Renditions[1]; failed.Status != "failed" || failed.ErrorMessage == nil || *failed.ErrorMessage != errMsg {
+		t.Errorf("1080p status = %s, error %v, want failed with %q", failed.Status, failed.ErrorMessage, errMsg)
+	}
+
+	pending := resp.Renditions[2]
+	if pending.Status != "pending" || pending.StartedAt != nil || pending.FinishedAt != nil {
+		t.Errorf("480p status = %s, started %v, finished %v, want pending without timestamps", pending.Status, pending.StartedAt, pending.FinishedAt)
+	}
+}
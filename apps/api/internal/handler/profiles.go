@@ -0,0 +1,492 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/api/internal/db"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/packages/codecs"
+)
+
+var (
+	// Profile names end up in S3 keys and file names, so keep them to safe characters
+	profileNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+	// FFmpeg scale filter arguments, e.g. "-2:720" or "1280:720"
+	scaleRegex = regexp.MustCompile(`^-?\d+:-?\d+$`)
+
+	// Bitrates in FFmpeg notation, e.g. "2500k" or "4M"
+	bitrateRegex = regexp.MustCompile(`^\d+[kKmM]?$`)
+
+	// Output containers the worker can write
+	supportedContainers = map[string]bool{
+		"mp4":  true,
+		"mov":  true,
+		"mkv":  true,
+		"webm": true,
+		"m4a":  true,
+		"mp3":  true,
+		"opus": true,
+	}
+
+	// Containers that cannot carry video, and the audio codec each defaults to
+	audioContainers = map[string]string{
+		"m4a":  "aac",
+		"mp3":  "libmp3lame",
+		"opus": "libopus",
+	}
+
+	// Rate control modes: constant quality, single-pass target bitrate, two-pass average bitrate
+	rateControlModes = map[string]bool{"crf": true, "vbr": true, "abr_2pass": true}
+
+	// Deinterlace and tone map modes: apply when the source needs it, always, or never
+	correctionModes = map[string]bool{"auto": true, "force": true, "off": true}
+
+	// Audio encoders whose output fits in HLS and DASH segments (MPEG-TS or fragmented MP4).
+	// "copy" passes because the source's codec is only known once the worker probes it.
+	packagingAudioCodecs = map[string]bool{
+		"aac": true, "libfdk_aac": true, "libmp3lame": true, "libopus": true,
+		"ac3": true, "eac3": true, "flac": true, "copy": true,
+	}
+)
+
+// packagingError reports why renditions of a profile can't be packaged as format, or nil.
+// Packaging remuxes the rendition's streams into segments, so the profile's own container
+// doesn't matter, only its codecs do. Audio-only renditions are never packaged.
+func packagingError(p db.Profile, format string) error {
+	if p.AudioOnly {
+		return nil
+	}
+	if _, ok := codecs.VideoCodecs[p.VideoCodec]; !ok {
+		return fmt.Errorf("profile %s: %s video can't be packaged as %s", p.Name, p.VideoCodec, format)
+	}
+	if !packagingAudioCodecs[p.AudioCodec] {
+		return fmt.Errorf("profile %s: %s audio can't be packaged as %s", p.Name, p.AudioCodec, format)
+	}
+	return nil
+}
+
+// ProfileHandler handles transcoding profile HTTP requests
+type ProfileHandler struct {
+	queries *db.Queries
+}
+
+// NewProfileHandler creates a new profile handler
+func NewProfileHandler(queries *db.Queries) *ProfileHandler {
+	return &ProfileHandler{queries: queries}
+}
+
+// ProfileRequest represents the request body for creating or updating a profile
+// Omitted codecs, preset and container fall back to libx264/aac/fast/mp4
+type ProfileRequest struct {
+	Name         string  `json:"name"` // Ignored on update; the name comes from the URL
+	Scale        string  `json:"scale"`
+	VideoCodec   string  `json:"video_codec"`
+	AudioCodec   string  `json:"audio_codec"`
+	Preset       string  `json:"preset"`
+	RateControl  string  `json:"rate_control"` // "crf", "vbr" or "abr_2pass"; defaults to vbr with a video_bitrate, crf otherwise
+	CRF          *int32  `json:"crf,omitempty"`
+	VideoBitrate *string `json:"video_bitrate,omitempty"`
+	MaxRate      *string `json:"max_rate,omitempty"`
+	BufSize      *string `json:"buf_size,omitempty"`
+	AudioBitrate *string `json:"audio_bitrate,omitempty"`
+	MaxFPS       *int32  `json:"max_fps,omitempty"`
+	Container    string  `json:"container"`
+
+	// Seconds between forced keyframes; omitted defaults to 2, 0 leaves the GOP to the encoder
+	KeyframeInterval *float64 `json:"keyframe_interval,omitempty"`
+
+	// Renditions whose mean VMAF against the source is lower fail; omitted disables the check
+	MinVMAF *float64 `json:"min_vmaf,omitempty"`
+
+	// Logo/text burned into this profile's renditions unless the job sets its own
+	Watermark *WatermarkSpec `json:"watermark,omitempty"`
+
+	// EBU R128 normalization with two-pass loudnorm: integrated loudness in LUFS (e.g. -23)
+	// and true peak ceiling in dBTP (default -1); omitted leaves the audio level untouched
+	LoudnessTarget *float64 `json:"loudness_target,omitempty"`
+	TruePeakLimit  *float64 `json:"true_peak_limit,omitempty"`
+
+	// "auto" (default), "force" or "off": deinterlace sources idet finds interlaced,
+	// and tone map HDR (PQ/HLG) sources to BT.709 SDR
+	Deinterlace string `json:"deinterlace"`
+	ToneMap     string `json:"tone_map"`
+
+	// Audio-only profiles drop video; scale and the video settings are ignored
+	AudioOnly       bool   `json:"audio_only"`
+	AudioSampleRate *int32 `json:"audio_sample_rate,omitempty"`
+	AudioChannels   *int32 `json:"audio_channels,omitempty"`
+}
+
+// ProfileResponse represents a profile in API responses
+type ProfileResponse struct {
+	Name         string  `json:"name"`
+	Scale        string  `json:"scale"`
+	VideoCodec   string  `json:"video_codec"`
+	AudioCodec   string  `json:"audio_codec"`
+	Preset       string  `json:"preset"`
+	RateControl  string  `json:"rate_control"`
+	CRF          *int32  `json:"crf,omitempty"`
+	VideoBitrate *string `json:"video_bitrate,omitempty"`
+	MaxRate      *string `json:"max_rate,omitempty"`
+	BufSize      *string `json:"buf_size,omitempty"`
+	AudioBitrate *string `json:"audio_bitrate,omitempty"`
+	MaxFPS       *int32  `json:"max_fps,omitempty"`
+	Container    string  `json:"container"`
+
+	KeyframeInterval *float64 `json:"keyframe_interval,omitempty"`
+	MinVMAF          *float64 `json:"min_vmaf,omitempty"`
+
+	Watermark json.RawMessage `json:"watermark,omitempty"`
+
+	LoudnessTarget *float64 `json:"loudness_target,omitempty"`
+	TruePeakLimit  *float64 `json:"true_peak_limit,omitempty"`
+
+	Deinterlace string `json:"deinterlace"`
+	ToneMap     string `json:"tone_map"`
+
+	AudioOnly       bool   `json:"audio_only"`
+	AudioSampleRate *int32 `json:"audio_sample_rate,omitempty"`
+	AudioChannels   *int32 `json:"audio_channels,omitempty"`
+
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// ListProfiles handles GET /profiles
+func (h *ProfileHandler) ListProfiles(w http.ResponseWriter, r *http.Request) {
+	profiles, err := h.queries.ListProfiles(r.Context())
+	if err != nil {
+		log.Printf("Failed to list profiles: %v", err)
+		http.Error(w, "Failed to list profiles", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]ProfileResponse, 0, len(profiles))
+	for _, p := range profiles {
+		response = append(response, profileToResponse(p))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetProfile handles GET /profiles/{name}
+func (h *ProfileHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	profile, err := h.queries.GetProfile(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		http.Error(w, "Profile not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profileToResponse(profile))
+}
+
+// CreateProfile handles POST /profiles
+func (h *ProfileHandler) CreateProfile(w http.ResponseWriter, r *http.Request) {
+	var req ProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !profileNameRegex.MatchString(req.Name) {
+		http.Error(w, "name is required and may only contain letters, digits, '-' and '_'", http.StatusBadRequest)
+		return
+	}
+	if err := req.normalize(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	watermark, err := watermarkToJSON(req.Watermark)
+	if err != nil {
+		http.Error(w, "Invalid watermark", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := h.queries.CreateProfile(r.Context(), db.CreateProfileParams{
+		Name:             req.Name,
+		Scale:            req.Scale,
+		VideoCodec:       req.VideoCodec,
+		AudioCodec:       req.AudioCodec,
+		Preset:           req.Preset,
+		Crf:              req.CRF,
+		VideoBitrate:     req.VideoBitrate,
+		AudioBitrate:     req.AudioBitrate,
+		MaxFps:           req.MaxFPS,
+		Container:        req.Container,
+		AudioOnly:        req.AudioOnly,
+		AudioSampleRate:  req.AudioSampleRate,
+		AudioChannels:    req.AudioChannels,
+		RateControl:      req.RateControl,
+		MaxRate:          req.MaxRate,
+		BufSize:          req.BufSize,
+		KeyframeInterval: req.KeyframeInterval,
+		MinVmaf:          req.MinVMAF,
+		Watermark:        watermark,
+		LoudnessTarget:   req.LoudnessTarget,
+		TruePeakLimit:    req.TruePeakLimit,
+		Deinterlace:      req.Deinterlace,
+		ToneMap:          req.ToneMap,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			http.Error(w, fmt.Sprintf("profile %s already exists", req.Name), http.StatusConflict)
+			return
+		}
+		log.Printf("Failed to create profile: %v", err)
+		http.Error(w, "Failed to create profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(profileToResponse(profile))
+}
+
+// UpdateProfile handles PUT /profiles/{name}
+// The request replaces every setting of the profile
+func (h *ProfileHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	var req ProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.normalize(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	watermark, err := watermarkToJSON(req.Watermark)
+	if err != nil {
+		http.Error(w, "Invalid watermark", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := h.queries.UpdateProfile(r.Context(), db.UpdateProfileParams{
+		Name:             chi.URLParam(r, "name"),
+		Scale:            req.Scale,
+		VideoCodec:       req.VideoCodec,
+		AudioCodec:       req.AudioCodec,
+		Preset:           req.Preset,
+		Crf:              req.CRF,
+		VideoBitrate:     req.VideoBitrate,
+		AudioBitrate:     req.AudioBitrate,
+		MaxFps:           req.MaxFPS,
+		Container:        req.Container,
+		AudioOnly:        req.AudioOnly,
+		AudioSampleRate:  req.AudioSampleRate,
+		AudioChannels:    req.AudioChannels,
+		RateControl:      req.RateControl,
+		MaxRate:          req.MaxRate,
+		BufSize:          req.BufSize,
+		KeyframeInterval: req.KeyframeInterval,
+		MinVmaf:          req.MinVMAF,
+		Watermark:        watermark,
+		LoudnessTarget:   req.LoudnessTarget,
+		TruePeakLimit:    req.TruePeakLimit,
+		Deinterlace:      req.Deinterlace,
+		ToneMap:          req.ToneMap,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Profile not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to update profile: %v", err)
+		http.Error(w, "Failed to update profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profileToResponse(profile))
+}
+
+// normalize fills in defaults and validates the encoding settings
+// Audio-only profiles default to an AAC .m4a output, or the codec matching an .mp3/.opus container
+func (req *ProfileRequest) normalize() error {
+	if req.VideoCodec == "" {
+		req.VideoCodec = "libx264"
+	}
+	if req.Preset == "" {
+		req.Preset = "fast"
+	}
+	if req.Container == "" {
+		req.Container = "mp4"
+		if req.AudioOnly {
+			req.Container = "m4a"
+		}
+	}
+	if req.AudioCodec == "" {
+		req.AudioCodec = "aac"
+		if codec, ok := audioContainers[req.Container]; ok {
+			req.AudioCodec = codec
+		} else if req.Container == "webm" {
+			req.AudioCodec = "libopus"
+		}
+	}
+
+	if req.Deinterlace == "" {
+		req.Deinterlace = "auto"
+	}
+	if req.ToneMap == "" {
+		req.ToneMap = "auto"
+	}
+	if req.RateControl == "" {
+		req.RateControl = "crf"
+		if req.VideoBitrate != nil {
+			req.RateControl = "vbr"
+		}
+	}
+	if req.KeyframeInterval == nil {
+		interval := 2.0
+		req.KeyframeInterval = &interval
+	} else if *req.KeyframeInterval == 0 {
+		req.KeyframeInterval = nil
+	}
+
+	if !correctionModes[req.Deinterlace] {
+		return fmt.Errorf("deinterlace must be one of auto, force, off")
+	}
+	if !correctionModes[req.ToneMap] {
+		return fmt.Errorf("tone_map must be one of auto, force, off")
+	}
+	if !rateControlModes[req.RateControl] {
+		return fmt.Errorf("rate_control must be one of crf, vbr, abr_2pass")
+	}
+	if req.RateControl != "crf" && req.VideoBitrate == nil && !req.AudioOnly {
+		return fmt.Errorf("rate_control %s requires video_bitrate", req.RateControl)
+	}
+
+	if req.AudioOnly {
+		// The scale is meaningless without video; store it empty rather than a misleading value
+		req.Scale = ""
+	} else {
+		if !scaleRegex.MatchString(req.Scale) {
+			return fmt.Errorf("scale must be an FFmpeg scale like \"-2:720\"")
+		}
+		if _, ok := audioContainers[req.Container]; ok {
+			return fmt.Errorf("container %s requires audio_only", req.Container)
+		}
+
+		codec, ok := codecs.VideoCodecs[req.VideoCodec]
+		if !ok {
+			return fmt.Errorf("unsupported video_codec: %s", req.VideoCodec)
+		}
+		if supportedContainers[req.Container] && !codec.Containers[req.Container] {
+			return fmt.Errorf("video_codec %s cannot be written to a %s container", req.VideoCodec, req.Container)
+		}
+		if _, ok := codecs.PresetSpeeds[req.Preset]; !ok {
+			return fmt.Errorf("preset must be an x264-style speed such as \"fast\" or \"slow\"")
+		}
+		if req.CRF != nil && (*req.CRF < 0 || int(*req.CRF) > codec.MaxCRF) {
+			return fmt.Errorf("crf for %s must be between 0 and %d", req.VideoCodec, codec.MaxCRF)
+		}
+		if req.RateControl == "abr_2pass" && !codec.TwoPass {
+			return fmt.Errorf("video_codec %s does not support two-pass encoding", req.VideoCodec)
+		}
+	}
+	if req.Container == "webm" && !codecs.WebMAudioCodecs[req.AudioCodec] {
+		return fmt.Errorf("webm requires libopus or libvorbis audio")
+	}
+	if want, ok := audioContainers[req.Container]; ok && req.Container != "m4a" && req.AudioCodec != want {
+		return fmt.Errorf("container %s requires %s audio", req.Container, want)
+	}
+	if req.VideoBitrate != nil && !bitrateRegex.MatchString(*req.VideoBitrate) {
+		return fmt.Errorf("video_bitrate must look like \"2500k\"")
+	}
+	if req.MaxRate != nil && !bitrateRegex.MatchString(*req.MaxRate) {
+		return fmt.Errorf("max_rate must look like \"3000k\"")
+	}
+	if req.BufSize != nil {
+		if req.MaxRate == nil {
+			return fmt.Errorf("buf_size requires max_rate")
+		}
+		if !bitrateRegex.MatchString(*req.BufSize) {
+			return fmt.Errorf("buf_size must look like \"6000k\"")
+		}
+	}
+	if req.KeyframeInterval != nil && (*req.KeyframeInterval < 0 || *req.KeyframeInterval > 20) {
+		return fmt.Errorf("keyframe_interval must be between 0 and 20 seconds")
+	}
+	if req.MinVMAF != nil && (*req.MinVMAF <= 0 || *req.MinVMAF > 100) {
+		return fmt.Errorf("min_vmaf must be between 0 and 100")
+	}
+	if req.LoudnessTarget != nil && (*req.LoudnessTarget < -70 || *req.LoudnessTarget > -5) {
+		return fmt.Errorf("loudness_target must be between -70 and -5 LUFS")
+	}
+	if req.LoudnessTarget != nil && req.AudioCodec == "copy" {
+		return fmt.Errorf("loudness_target requires re-encoding the audio, not audio_codec copy")
+	}
+	if req.TruePeakLimit != nil {
+		if req.LoudnessTarget == nil {
+			return fmt.Errorf("true_peak_limit requires loudness_target")
+		}
+		if *req.TruePeakLimit < -9 || *req.TruePeakLimit > 0 {
+			return fmt.Errorf("true_peak_limit must be between -9 and 0 dBTP")
+		}
+	}
+	if req.Watermark != nil {
+		if req.AudioOnly {
+			return fmt.Errorf("audio-only profiles cannot have a watermark")
+		}
+		if err := req.Watermark.validate("watermark"); err != nil {
+			return err
+		}
+	}
+	if req.AudioBitrate != nil && !bitrateRegex.MatchString(*req.AudioBitrate) {
+		return fmt.Errorf("audio_bitrate must look like \"128k\"")
+	}
+	if req.MaxFPS != nil && *req.MaxFPS <= 0 {
+		return fmt.Errorf("max_fps must be positive")
+	}
+	if req.AudioSampleRate != nil && (*req.AudioSampleRate < 8000 || *req.AudioSampleRate > 192000) {
+		return fmt.Errorf("audio_sample_rate must be between 8000 and 192000")
+	}
+	if req.AudioChannels != nil && (*req.AudioChannels < 1 || *req.AudioChannels > 8) {
+		return fmt.Errorf("audio_channels must be between 1 and 8")
+	}
+	if !supportedContainers[req.Container] {
+		return fmt.Errorf("unsupported container: %s", req.Container)
+	}
+	return nil
+}
+
+func profileToResponse(p db.Profile) ProfileResponse {
+	return ProfileResponse{
+		Name:             p.Name,
+		Scale:            p.Scale,
+		VideoCodec:       p.VideoCodec,
+		AudioCodec:       p.AudioCodec,
+		Preset:           p.Preset,
+		CRF:              p.Crf,
+		VideoBitrate:     p.VideoBitrate,
+		AudioBitrate:     p.AudioBitrate,
+		MaxFPS:           p.MaxFps,
+		Container:        p.Container,
+		AudioOnly:        p.AudioOnly,
+		AudioSampleRate:  p.AudioSampleRate,
+		AudioChannels:    p.AudioChannels,
+		RateControl:      p.RateControl,
+		MaxRate:          p.MaxRate,
+		BufSize:          p.BufSize,
+		KeyframeInterval: p.KeyframeInterval,
+		MinVMAF:          p.MinVmaf,
+		Watermark:        p.Watermark,
+		LoudnessTarget:   p.LoudnessTarget,
+		TruePeakLimit:    p.TruePeakLimit,
+		Deinterlace:      p.Deinterlace,
+		ToneMap:          p.ToneMap,
+		CreatedAt:        p.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:        p.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/api/internal/db"
+)
+
+func TestPackagingError(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile db.Profile
+		wantErr bool
+	}{
+		{name: "h264 with aac", profile: db.Profile{Name: "720p", VideoCodec: "libx264", AudioCodec: "aac"}},
+		{name: "vp9 with opus", profile: db.Profile{Name: "vp9", VideoCodec: "libvpx-vp9", AudioCodec: "libopus"}},
+		{name: "av1 with copied audio", profile: db.Profile{Name: "av1", VideoCodec: "libsvtav1", AudioCodec: "copy"}},
+		{name: "vp9 with vorbis", profile: db.Profile{Name: "webm", VideoCodec: "libvpx-vp9", AudioCodec: "libvorbis"}, wantErr: true},
+		{name: "unknown video codec", profile: db.Profile{Name: "old", VideoCodec: "mpeg2video", AudioCodec: "aac"}, wantErr: true},
+		{name: "audio only is never packaged", profile: db.Profile{Name: "ogg", AudioCodec: "libvorbis", AudioOnly: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := packagingError(tt.profile, "hls")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("packagingError() = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProfileNameRegex(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "720p", want: true},
+		{name: "hls_1080p-high", want: true},
+		{name: "", want: false},
+		{name: "720p/../x", want: false},
+		{name: "with space", want: false},
+		{name: "a.mp4", want: false},
+		{name: strings.Repeat("a", 64), want: true},
+		{name: strings.Repeat("a", 65), want: false},
+	}
+	for _, tt := range tests {
+		if got := profileNameRegex.MatchString(tt.name); got != tt.want {
+			t.Errorf("profileNameRegex.MatchString(%q) = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestProfileRequestDefaults(t *testing.T) {
+	req := ProfileRequest{Name: "720p", Scale: "-2:720"}
+	if err := req.normalize(); err != nil {
+		t.Fatalf("normalize() = %v", err)
+	}
+	if req.VideoCodec != "libx264" || req.AudioCodec != "aac" || req.Preset != "fast" || req.Container != "mp4" {
+		t.Errorf("codecs = %s/%s/%s/%s, want libx264/aac/fast/mp4", req.VideoCodec, req.AudioCodec, req.Preset, req.Container)
+	}
+	if req.RateControl != "crf" {
+		t.Errorf("RateControl = %s, want crf", req.RateControl)
+	}
+	if req.KeyframeInterval == nil || *req.KeyframeInterval != 2 {
+		t.Errorf("KeyframeInterval = %v, want 2", req.KeyframeInterval)
+	}
+	if req.Deinterlace != "auto" || req.ToneMap != "auto" {
+		t.Errorf("Deinterlace, ToneMap = %s, %s, want auto, auto", req.Deinterlace, req.ToneMap)
+	}
+
+	bitrate := "2500k"
+	req = ProfileRequest{Scale: "-2:720", VideoBitrate: &bitrate}
+	if err := req.normalize(); err != nil || req.RateControl != "vbr" {
+		t.Errorf("with video_bitrate: RateControl = %s, %v, want vbr", req.RateControl, err)
+	}
+
+	req = ProfileRequest{Scale: "-2:720", Container: "webm", VideoCodec: "libvpx-vp9"}
+	if err := req.normalize(); err != nil || req.AudioCodec != "libopus" {
+		t.Errorf("webm: AudioCodec = %s, %v, want libopus", req.AudioCodec, err)
+	}
+
+	zero := 0.0
+	req = ProfileRequest{Scale: "-2:720", KeyframeInterval: &zero}
+	if err := req.normalize(); err != nil || req.KeyframeInterval != nil {
+		t.Errorf("keyframe_interval 0: KeyframeInterval = %v, %v, want nil", req.KeyframeInterval, err)
+	}
+}
+
+func TestProfileRequestValidation(t *testing.T) {
+	int32p := func(v int32) *int32 { return &v }
+	stringp := func(v string) *string { return &v }
+	float64p := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name    string
+		req     ProfileRequest
+		wantErr bool
+	}{
+		{name: "minimal", req: ProfileRequest{Scale: "-2:720"}},
+		{name: "fixed size", req: ProfileRequest{Scale: "1280:720"}},
+		{name: "vp9 webm", req: ProfileRequest{Scale: "-2:720", VideoCodec: "libvpx-vp9", Container: "webm", CRF: int32p(63)}},
+		{name: "two-pass h264", req: ProfileRequest{Scale: "-2:720", RateControl: "abr_2pass", VideoBitrate: stringp("2500k")}},
+		{name: "capped crf", req: ProfileRequest{Scale: "-2:720", CRF: int32p(23), MaxRate: stringp("3M"), BufSize: stringp("6M")}},
+		{name: "corrections forced", req: ProfileRequest{Scale: "-2:720", Deinterlace: "force", ToneMap: "force"}},
+		{name: "corrections off", req: ProfileRequest{Scale: "-2:720", Deinterlace: "off", ToneMap: "off"}},
+
+		{name: "missing scale", req: ProfileRequest{}, wantErr: true},
+		{name: "bad scale", req: ProfileRequest{Scale: "720p"}, wantErr: true},
+		{name: "unknown video codec", req: ProfileRequest{Scale: "-2:720", VideoCodec: "mpeg2video"}, wantErr: true},
+		{name: "codec not allowed in container", req: ProfileRequest{Scale: "-2:720", Container: "webm"}, wantErr: true},
+		{name: "unknown container", req: ProfileRequest{Scale: "-2:720", Container: "avi"}, wantErr: true},
+		{name: "unknown preset", req: ProfileRequest{Scale: "-2:720", Preset: "turbo"}, wantErr: true},
+		{name: "crf too high for h264", req: ProfileRequest{Scale: "-2:720", CRF: int32p(52)}, wantErr: true},
+		{name: "negative crf", req: ProfileRequest{Scale: "-2:720", CRF: int32p(-1)}, wantErr: true},
+		{name: "two-pass without two-pass support", req: ProfileRequest{Scale: "-2:720", VideoCodec: "libsvtav1", RateControl: "abr_2pass", VideoBitrate: stringp("2M")}, wantErr: true},
+		{name: "unknown rate control", req: ProfileRequest{Scale: "-2:720", RateControl: "cbr"}, wantErr: true},
+		{name: "vbr without bitrate", req: ProfileRequest{Scale: "-2:720", RateControl: "vbr"}, wantErr: true},
+		{name: "webm with aac", req: ProfileRequest{Scale: "-2:720", VideoCodec: "libvpx-vp9", Container: "webm", AudioCodec: "aac"}, wantErr: true},
+		{name: "bad video bitrate", req: ProfileRequest{Scale: "-2:720", VideoBitrate: stringp("fast")}, wantErr: true},
+		{name: "bad max rate", req: ProfileRequest{Scale: "-2:720", MaxRate: stringp("3 Mbps")}, wantErr: true},
+		{name: "buf size without max rate", req: ProfileRequest{Scale: "-2:720", BufSize: stringp("6M")}, wantErr: true},
+		{name: "bad audio bitrate", req: ProfileRequest{Scale: "-2:720", AudioBitrate: stringp("128 kbps")}, wantErr: true},
+		{name: "zero max fps", req: ProfileRequest{Scale: "-2:720", MaxFPS: int32p(0)}, wantErr: true},
+		{name: "keyframe interval too long", req: ProfileRequest{Scale: "-2:720", KeyframeInterval: float64p(30)}, wantErr: true},
+		{name: "negative keyframe interval", req: ProfileRequest{Scale: "-2:720", KeyframeInterval: float64p(-1)}, wantErr: true},
+		{name: "min vmaf zero", req: ProfileRequest{Scale: "-2:720", MinVMAF: float64p(0)}, wantErr: true},
+		{name: "min vmaf over 100", req: ProfileRequest{Scale: "-2:720", MinVMAF: float64p(101)}, wantErr: true},
+		{name: "unknown deinterlace mode", req: ProfileRequest{Scale: "-2:720", Deinterlace: "yadif"}, wantErr: true},
+		{name: "unknown tone map mode", req: ProfileRequest{Scale: "-2:720", ToneMap: "hable"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.normalize()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("normalize() = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProfileRequestAudioOnly(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           ProfileRequest
+		wantContainer string
+		wantCodec     string
+	}{
+		{name: "defaults to aac in m4a", req: ProfileRequest{AudioOnly: true}, wantContainer: "m4a", wantCodec: "aac"},
+		{name: "mp3 container", req: ProfileRequest{AudioOnly: true, Container: "mp3"}, wantContainer: "mp3", wantCodec: "libmp3lame"},
+		{name: "opus container", req: ProfileRequest{AudioOnly: true, Container: "opus"}, wantContainer: "opus", wantCodec: "libopus"},
+		{name: "webm container", req: ProfileRequest{AudioOnly: true, Container: "webm"}, wantContainer: "webm", wantCodec: "libopus"},
+		{name: "m4a with another codec", req: ProfileRequest{AudioOnly: true, Container: "m4a", AudioCodec: "alac"}, wantContainer: "m4a", wantCodec: "alac"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			req.Scale = "-2:720"
+			if err := req.normalize(); err != nil {
+				t.Fatalf("normalize() = %v", err)
+			}
+			if req.Container != tt.wantContainer || req.AudioCodec != tt.wantCodec {
+				t.Errorf("container, audio codec = %s, %s, want %s, %s", req.Container, req.AudioCodec, tt.wantContainer, tt.wantCodec)
+			}
+			if req.Scale != "" {
+				t.Errorf("Scale = %q, want it cleared", req.Scale)
+			}
+		})
+	}
+}
+
+func TestProfileRequestAudioOnlyValidation(t *testing.T) {
+	int32p := func(v int32) *int32 { return &v }
+
+	tests := []struct {
+		name    string
+		req     ProfileRequest
+		wantErr bool
+	}{
+		{name: "no scale needed", req: ProfileRequest{AudioOnly: true}},
+		{name: "video settings ignored", req: ProfileRequest{AudioOnly: true, VideoCodec: "mpeg2video", Preset: "turbo", RateControl: "vbr"}},
+		{name: "sample rate and channels", req: ProfileRequest{AudioOnly: true, AudioSampleRate: int32p(48000), AudioChannels: int32p(1)}},
+
+		{name: "audio container without audio_only", req: ProfileRequest{Scale: "-2:720", Container: "m4a"}, wantErr: true},
+		{name: "mp3 with aac", req: ProfileRequest{AudioOnly: true, Container: "mp3", AudioCodec: "aac"}, wantErr: true},
+		{name: "opus with vorbis", req: ProfileRequest{AudioOnly: true, Container: "opus", AudioCodec: "libvorbis"}, wantErr: true},
+		{name: "webm with aac", req: ProfileRequest{AudioOnly: true, Container: "webm", AudioCodec: "aac"}, wantErr: true},
+		{name: "watermark", req: ProfileRequest{AudioOnly: true, Watermark: &WatermarkSpec{}}, wantErr: true},
+		{name: "sample rate too low", req: ProfileRequest{AudioOnly: true, AudioSampleRate: int32p(4000)}, wantErr: true},
+		{name: "sample rate too high", req: ProfileRequest{AudioOnly: true, AudioSampleRate: int32p(384000)}, wantErr: true},
+		{name: "no channels", req: ProfileRequest{AudioOnly: true, AudioChannels: int32p(0)}, wantErr: true},
+		{name: "too many channels", req: ProfileRequest{AudioOnly: true, AudioChannels: int32p(9)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.normalize()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("normalize() = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProfileRequestLoudness(t *testing.T) {
+	float64p := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name    string
+		req     ProfileRequest
+		wantErr bool
+	}{
+		{name: "broadcast target", req: ProfileRequest{LoudnessTarget: float64p(-23)}},
+		{name: "streaming target with peak limit", req: ProfileRequest{LoudnessTarget: float64p(-14), TruePeakLimit: float64p(-2)}},
+		{name: "audio only", req: ProfileRequest{AudioOnly: true, LoudnessTarget: float64p(-16)}},
+
+		{name: "target too quiet", req: ProfileRequest{LoudnessTarget: float64p(-71)}, wantErr: true},
+		{name: "target too loud", req: ProfileRequest{LoudnessTarget: float64p(-4)}, wantErr: true},
+		{name: "copied audio", req: ProfileRequest{AudioCodec: "copy", LoudnessTarget: float64p(-23)}, wantErr: true},
+		{name: "peak limit without target", req: ProfileRequest{TruePeakLimit: float64p(-1)}, wantErr: true},
+		{name: "peak limit above 0", req: ProfileRequest{LoudnessTarget: float64p(-23), TruePeakLimit: float64p(1)}, wantErr: true},
+		{name: "peak limit below -9", req: ProfileRequest{LoudnessTarget: float64p(-23), TruePeakLimit: float64p(-10)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if !req.AudioOnly {
+				req.Scale = "-2:720"
+			}
+			err := req.normalize()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("normalize() = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -35,6 +35,22 @@ var (
 		".flv":  true,
 	}
 
+	// Subtitle files accepted as sidecars for a job
+	subtitleExtensions = map[string]bool{
+		".srt": true,
+		".vtt": true,
+		".ass": true,
+		".ssa": true,
+	}
+
+	// Images accepted as watermark logos
+	imageExtensions = map[string]bool{
+		".png":  true,
+		".jpg":  true,
+		".jpeg": true,
+		".webp": true,
+	}
+
 	// Regex for safe filename characters (alphanumeric, dash, underscore, dot)
 	safeFilenameRegex = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
 )
@@ -90,7 +106,7 @@ func (h *StorageHandler) GetUploadURL(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate extension is allowed
-	if !allowedExtensions[ext] {
+	if !allowedExtensions[ext] && !subtitleExtensions[ext] && !imageExtensions[ext] {
 		http.Error(w, "file type not allowed", http.StatusBadRequest)
 		return
 	}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	// Corners (or the center) a watermark's logo or text can be placed in
+	watermarkPositions = map[string]bool{
+		"top-left":     true,
+		"top-right":    true,
+		"bottom-left":  true,
+		"bottom-right": true,
+		"center":       true,
+	}
+
+	// Colors FFmpeg understands by name ("white") or as hex ("#ffcc00")
+	colorRegex = regexp.MustCompile(`^(#[0-9a-fA-F]{6}|[a-zA-Z]{3,20})$`)
+)
+
+const (
+	// maxWatermarkText limits the length of a text watermark
+	maxWatermarkText = 200
+	// maxWatermarkMargin limits how far from the edges a watermark can sit, in pixels
+	maxWatermarkMargin = 500
+)
+
+// WatermarkSpec is a logo and/or text overlay burned into video renditions, set on a job or a profile.
+// Omitted fields default to the top-right corner, a 16px margin, full opacity and a logo
+// 10% of the output height.
+type WatermarkSpec struct {
+	ImageKey string         `json:"image_key,omitempty"` // Logo image in the bucket, e.g. uploaded via /upload-url
+	Position string         `json:"position,omitempty"`  // top-left, top-right, bottom-left, bottom-right or center
+	Margin   *int           `json:"margin,omitempty"`    // Pixels from the frame edges
+	Opacity  float64        `json:"opacity,omitempty"`   // 0-1
+	Scale    float64        `json:"scale,omitempty"`     // Logo height relative to the output height
+	Text     *WatermarkText `json:"text,omitempty"`
+}
+
+// WatermarkText is an optional line of text drawn with the watermark.
+// Omitted fields default to white text in the bottom-left corner, 4% of the output height.
+type WatermarkText struct {
+	Text     string  `json:"text"`
+	Position string  `json:"position,omitempty"`
+	Size     float64 `json:"size,omitempty"` // Font size relative to the output height
+	Color    string  `json:"color,omitempty"`
+}
+
+// validate checks a watermark spec; field names in errors are prefixed with field
+func (w *WatermarkSpec) validate(field string) error {
+	if w.ImageKey == "" && w.Text == nil {
+		return fmt.Errorf("%s needs an image_key or text", field)
+	}
+	if w.ImageKey != "" {
+		if strings.Contains(w.ImageKey, "..") || strings.HasPrefix(w.ImageKey, "/") {
+			return fmt.Errorf("%s.image_key is invalid", field)
+		}
+		if !imageExtensions[strings.ToLower(filepath.Ext(w.ImageKey))] {
+			return fmt.Errorf("%s.image_key must be a .png, .jpg or .webp image", field)
+		}
+	}
+	if w.Position != "" && !watermarkPositions[w.Position] {
+		return fmt.Errorf("%s.position must be top-left, top-right, bottom-left, bottom-right or center", field)
+	}
+	if w.Margin != nil && (*w.Margin < 0 || *w.Margin > maxWatermarkMargin) {
+		return fmt.Errorf("%s.margin must be between 0 and %d", field, maxWatermarkMargin)
+	}
+	if w.Opacity < 0 || w.Opacity > 1 {
+		return fmt.Errorf("%s.opacity must be between 0 and 1", field)
+	}
+	if w.Scale < 0 || w.Scale > 1 {
+		return fmt.Errorf("%s.scale must be between 0 and 1", field)
+	}
+
+	if t := w.Text; t != nil {
+		if t.Text == "" || len(t.Text) > maxWatermarkText {
+			return fmt.Errorf("%s.text.text must be 1 to %d characters", field, maxWatermarkText)
+		}
+		if t.Position != "" && !watermarkPositions[t.Position] {
+			return fmt.Errorf("%s.text.position must be top-left, top-right, bottom-left, bottom-right or center", field)
+		}
+		if t.Size < 0 || t.Size > 0.5 {
+			return fmt.Errorf("%s.text.size must be between 0 and 0.5", field)
+		}
+		if t.Color != "" && !colorRegex.MatchString(t.Color) {
+			return fmt.Errorf("%s.text.color must be a color name or #RRGGBB", field)
+		}
+	}
+	return nil
+}
+
+// watermarkToJSON encodes a watermark for a JSONB column; nil stays NULL
+func watermarkToJSON(w *WatermarkSpec) ([]byte, error) {
+	if w == nil {
+		return nil, nil
+	}
+	return json.Marshal(w)
+}
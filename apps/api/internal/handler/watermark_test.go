@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWatermarkSpecValidate(t *testing.T) {
+	intp := func(v int) *int { return &v }
+
+	tests := []struct {
+		name    string
+		spec    WatermarkSpec
+		wantErr bool
+	}{
+		{name: "logo", spec: WatermarkSpec{ImageKey: "logos/acme.png"}},
+		{name: "uppercase extension", spec: WatermarkSpec{ImageKey: "logos/ACME.PNG"}},
+		{name: "text", spec: WatermarkSpec{Text: &WatermarkText{Text: "draft"}}},
+		{
+			name: "everything set",
+			spec: WatermarkSpec{
+				ImageKey: "logos/acme.webp", Position: "center", Margin: intp(0), Opacity: 0.5, Scale: 0.2,
+				Text: &WatermarkText{Text: "draft", Position: "top-left", Size: 0.1, Color: "#ffcc00"},
+			},
+		},
+		{name: "named color", spec: WatermarkSpec{Text: &WatermarkText{Text: "draft", Color: "white"}}},
+
+		{name: "empty", spec: WatermarkSpec{}, wantErr: true},
+		{name: "path traversal", spec: WatermarkSpec{ImageKey: "../secrets.png"}, wantErr: true},
+		{name: "absolute key", spec: WatermarkSpec{ImageKey: "/logos/acme.png"}, wantErr: true},
+		{name: "not an image", spec: WatermarkSpec{ImageKey: "uploads/video.mp4"}, wantErr: true},
+		{name: "unknown position", spec: WatermarkSpec{ImageKey: "logo.png", Position: "middle"}, wantErr: true},
+		{name: "negative margin", spec: WatermarkSpec{ImageKey: "logo.png", Margin: intp(-1)}, wantErr: true},
+		{name: "margin too large", spec: WatermarkSpec{ImageKey: "logo.png", Margin: intp(501)}, wantErr: true},
+		{name: "opacity above 1", spec: WatermarkSpec{ImageKey: "logo.png", Opacity: 1.5}, wantErr: true},
+		{name: "scale above 1", spec: WatermarkSpec{ImageKey: "logo.png", Scale: 2}, wantErr: true},
+		{name: "empty text", spec: WatermarkSpec{Text: &WatermarkText{}}, wantErr: true},
+		{name: "text too long", spec: WatermarkSpec{Text: &WatermarkText{Text: strings.Repeat("a", 201)}}, wantErr: true},
+		{name: "unknown text position", spec: WatermarkSpec{Text: &WatermarkText{Text: "draft", Position: "left"}}, wantErr: true},
+		{name: "text too big", spec: WatermarkSpec{Text: &WatermarkText{Text: "draft", Size: 0.6}}, wantErr: true},
+		{name: "color with filter syntax", spec: WatermarkSpec{Text: &WatermarkText{Text: "draft", Color: "white:x=0"}}, wantErr: true},
+		{name: "short hex color", spec: WatermarkSpec{Text: &WatermarkText{Text: "draft", Color: "#fff"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.validate("watermark")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,16 +2,23 @@ package queue
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/redis/go-redis/v9"
-)
 
-const (
-	// JobQueueKey is the Redis key for the pending jobs queue
-	JobQueueKey = "jobs:pending"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/packages/jobqueue"
 )
 
+// RenditionProgress is the live encode progress a worker publishes for one rendition
+type RenditionProgress struct {
+	Percent    float64   `json:"percent"`
+	Speed      float64   `json:"speed"`
+	ETASeconds int64     `json:"eta_seconds"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
 // Producer handles pushing jobs to the Redis queue
 type Producer struct {
 	client *redis.Client
@@ -35,13 +42,38 @@ func NewProducer(redisAddr string) (*Producer, error) {
 // Push adds a job ID to the queue
 func (p *Producer) Push(ctx context.Context, jobID string) error {
 	// LPUSH adds to the left (head) of the list
-	// Workers use BRPOP to pop from the right (tail) - FIFO order
-	return p.client.LPush(ctx, JobQueueKey, jobID).Err()
+	// Workers use BLMOVE to pop from the right (tail) - FIFO order
+	return p.client.LPush(ctx, jobqueue.JobQueueKey, jobID).Err()
 }
 
 // QueueLength returns the current number of jobs in the queue
 func (p *Producer) QueueLength(ctx context.Context) (int64, error) {
-	return p.client.LLen(ctx, JobQueueKey).Result()
+	return p.client.LLen(ctx, jobqueue.JobQueueKey).Result()
+}
+
+// Progress returns the latest published progress for each rendition of a job, keyed by resolution
+// Renditions that have not started encoding are absent from the map
+func (p *Producer) Progress(ctx context.Context, jobID string) (map[string]RenditionProgress, error) {
+	fields, err := p.client.HGetAll(ctx, jobqueue.ProgressKeyPrefix+jobID).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	progress := make(map[string]RenditionProgress, len(fields))
+	for resolution, value := range fields {
+		var rp RenditionProgress
+		if err := json.Unmarshal([]byte(value), &rp); err != nil {
+			return nil, fmt.Errorf("invalid progress for rendition %s: %w", resolution, err)
+		}
+		progress[resolution] = rp
+	}
+	return progress, nil
+}
+
+// Cancel flags a job as cancelled for the worker processing it and removes
+// any copies of the job ID still waiting in the queue or for a retry
+func (p *Producer) Cancel(ctx context.Context, jobID string) error {
+	return jobqueue.Cancel(ctx, p.client, jobID)
 }
 
 // Close closes the Redis connection
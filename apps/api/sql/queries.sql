@@ -1,6 +1,6 @@
 -- name: CreateJob :one
-INSERT INTO jobs (input_key, status)
-VALUES ($1, 'queued')
+INSERT INTO jobs (input_key, status, options)
+VALUES ($1, 'queued', $2)
 RETURNING *;
 
 -- name: GetJob :one
@@ -13,6 +13,13 @@ SET status = $2, error_message = $3
 WHERE id = $1
 RETURNING *;
 
+-- name: CancelJob :one
+-- Cancel a job that has not finished yet; returns no rows if it already reached a final state
+UPDATE jobs
+SET status = 'cancelled'
+WHERE id = $1 AND status IN ('queued', 'processing')
+RETURNING *;
+
 -- name: ListJobs :many
 SELECT * FROM jobs
 ORDER BY created_at DESC
@@ -43,3 +50,62 @@ ORDER BY resolution;
 SELECT * FROM renditions
 WHERE id = $1;
 
+-- name: GetMediaProbe :one
+SELECT * FROM media_probes
+WHERE job_id = $1;
+
+-- name: ListProfiles :many
+SELECT * FROM profiles
+ORDER BY name;
+
+-- name: GetProfile :one
+SELECT * FROM profiles
+WHERE name = $1;
+
+-- name: CreateProfile :one
+INSERT INTO profiles (
+    name, scale, video_codec, audio_codec, preset, crf,
+    video_bitrate, audio_bitrate, max_fps, container,
+    audio_only, audio_sample_rate, audio_channels,
+    rate_control, max_rate, buf_size, keyframe_interval, min_vmaf,
+    watermark, loudness_target, true_peak_limit, deinterlace, tone_map
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
+RETURNING *;
+
+-- name: UpdateProfile :one
+UPDATE profiles
+SET scale = $2,
+    video_codec = $3,
+    audio_codec = $4,
+    preset = $5,
+    crf = $6,
+    video_bitrate = $7,
+    audio_bitrate = $8,
+    max_fps = $9,
+    container = $10,
+    audio_only = $11,
+    audio_sample_rate = $12,
+    audio_channels = $13,
+    rate_control = $14,
+    max_rate = $15,
+    buf_size = $16,
+    keyframe_interval = $17,
+    min_vmaf = $18,
+    watermark = $19,
+    loudness_target = $20,
+    true_peak_limit = $21,
+    deinterlace = $22,
+    tone_map = $23
+WHERE name = $1
+RETURNING *;
+
+-- name: GetJobArtifacts :many
+SELECT * FROM job_artifacts
+WHERE job_id = $1
+ORDER BY output_key;
+
+-- name: GetJobChunks :many
+SELECT * FROM job_chunks
+WHERE job_id = $1
+ORDER BY chunk_index;
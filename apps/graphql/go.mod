@@ -4,6 +4,7 @@ go 1.23
 
 require (
 	github.com/99designs/gqlgen v0.17.45
+	github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/packages/jobqueue v0.0.0
 	github.com/go-chi/chi/v5 v5.2.0
 	github.com/google/uuid v1.6.0
 	github.com/jackc/pgx/v5 v5.7.2
@@ -43,3 +44,5 @@ require (
 )
 
 replace golang.org/x/tools => golang.org/x/tools v0.26.0
+
+replace github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/packages/jobqueue => ../../packages/jobqueue
@@ -11,13 +11,59 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ChunkStatus string
+
+const (
+	ChunkStatusPending    ChunkStatus = "pending"
+	ChunkStatusProcessing ChunkStatus = "processing"
+	ChunkStatusCompleted  ChunkStatus = "completed"
+	ChunkStatusFailed     ChunkStatus = "failed"
+)
+
+func (e *ChunkStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ChunkStatus(s)
+	case string:
+		*e = ChunkStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ChunkStatus: %T", src)
+	}
+	return nil
+}
+
+type NullChunkStatus struct {
+	ChunkStatus ChunkStatus `json:"chunk_status"`
+	Valid       bool        `json:"valid"` // Valid is true if ChunkStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullChunkStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ChunkStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ChunkStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullChunkStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ChunkStatus), nil
+}
+
 type JobStatus string
 
 const (
 	JobStatusQueued     JobStatus = "queued"
 	JobStatusProcessing JobStatus = "processing"
 	JobStatusCompleted  JobStatus = "completed"
+	JobStatusPartial    JobStatus = "partial"
 	JobStatusFailed     JobStatus = "failed"
+	JobStatusCancelled  JobStatus = "cancelled"
 )
 
 func (e *JobStatus) Scan(src interface{}) error {
@@ -55,23 +101,167 @@ func (ns NullJobStatus) Value() (driver.Value, error) {
 	return string(ns.JobStatus), nil
 }
 
+type RenditionStatus string
+
+const (
+	RenditionStatusPending    RenditionStatus = "pending"
+	RenditionStatusProcessing RenditionStatus = "processing"
+	RenditionStatusCompleted  RenditionStatus = "completed"
+	RenditionStatusFailed     RenditionStatus = "failed"
+)
+
+func (e *RenditionStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = RenditionStatus(s)
+	case string:
+		*e = RenditionStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for RenditionStatus: %T", src)
+	}
+	return nil
+}
+
+type NullRenditionStatus struct {
+	RenditionStatus RenditionStatus `json:"rendition_status"`
+	Valid           bool            `json:"valid"` // Valid is true if RenditionStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullRenditionStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.RenditionStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.RenditionStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullRenditionStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.RenditionStatus), nil
+}
+
+type JobArtifact struct {
+	ID          pgtype.UUID        `json:"id"`
+	JobID       pgtype.UUID        `json:"job_id"`
+	Kind        string             `json:"kind"`
+	OutputKey   string             `json:"output_key"`
+	ContentType string             `json:"content_type"`
+	Language    pgtype.Text        `json:"language"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+type JobChunk struct {
+	ID              pgtype.UUID        `json:"id"`
+	JobID           pgtype.UUID        `json:"job_id"`
+	ChunkIndex      int32              `json:"chunk_index"`
+	SourceKey       string             `json:"source_key"`
+	StartSeconds    float64            `json:"start_seconds"`
+	DurationSeconds float64            `json:"duration_seconds"`
+	Status          ChunkStatus        `json:"status"`
+	Attempts        int32              `json:"attempts"`
+	WorkerID        pgtype.Text        `json:"worker_id"`
+	ErrorMessage    pgtype.Text        `json:"error_message"`
+	StartedAt       pgtype.Timestamptz `json:"started_at"`
+	FinishedAt      pgtype.Timestamptz `json:"finished_at"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+}
+
 type Job struct {
-	ID           pgtype.UUID        `json:"id"`
-	InputKey     string             `json:"input_key"`
-	Status       JobStatus          `json:"status"`
-	ErrorMessage pgtype.Text        `json:"error_message"`
-	RetryCount   int32              `json:"retry_count"`
-	MaxRetries   int32              `json:"max_retries"`
-	StartedAt    pgtype.Timestamptz `json:"started_at"`
-	WorkerID     pgtype.Text        `json:"worker_id"`
-	CreatedAt    pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	ID              pgtype.UUID        `json:"id"`
+	InputKey        string             `json:"input_key"`
+	Status          JobStatus          `json:"status"`
+	ErrorMessage    pgtype.Text        `json:"error_message"`
+	ErrorCode       pgtype.Text        `json:"error_code"`
+	RetryCount      int32              `json:"retry_count"`
+	MaxRetries      int32              `json:"max_retries"`
+	StartedAt       pgtype.Timestamptz `json:"started_at"`
+	WorkerID        pgtype.Text        `json:"worker_id"`
+	Options         []byte             `json:"options"`
+	HlsMasterKey    pgtype.Text        `json:"hls_master_key"`
+	DashManifestKey pgtype.Text        `json:"dash_manifest_key"`
+	Ladder          []byte             `json:"ladder"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+}
+
+type MediaProbe struct {
+	JobID              pgtype.UUID        `json:"job_id"`
+	DurationSeconds    pgtype.Float8      `json:"duration_seconds"`
+	Container          pgtype.Text        `json:"container"`
+	VideoCodec         pgtype.Text        `json:"video_codec"`
+	AudioCodec         pgtype.Text        `json:"audio_codec"`
+	Width              pgtype.Int4        `json:"width"`
+	Height             pgtype.Int4        `json:"height"`
+	FrameRate          pgtype.Float8      `json:"frame_rate"`
+	BitRate            pgtype.Int8        `json:"bit_rate"`
+	Rotation           int32              `json:"rotation"`
+	AudioChannels      pgtype.Int4        `json:"audio_channels"`
+	AudioChannelLayout pgtype.Text        `json:"audio_channel_layout"`
+	FieldOrder         pgtype.Text        `json:"field_order"`
+	ColorTransfer      pgtype.Text        `json:"color_transfer"`
+	ColorPrimaries     pgtype.Text        `json:"color_primaries"`
+	HdrFormat          pgtype.Text        `json:"hdr_format"`
+	CreatedAt          pgtype.Timestamptz `json:"created_at"`
+}
+
+type Profile struct {
+	Name             string             `json:"name"`
+	Scale            string             `json:"scale"`
+	VideoCodec       string             `json:"video_codec"`
+	AudioCodec       string             `json:"audio_codec"`
+	Preset           string             `json:"preset"`
+	RateControl      string             `json:"rate_control"`
+	Crf              pgtype.Int4        `json:"crf"`
+	VideoBitrate     pgtype.Text        `json:"video_bitrate"`
+	MaxRate          pgtype.Text        `json:"max_rate"`
+	BufSize          pgtype.Text        `json:"buf_size"`
+	KeyframeInterval pgtype.Float8      `json:"keyframe_interval"`
+	AudioBitrate     pgtype.Text        `json:"audio_bitrate"`
+	MaxFps           pgtype.Int4        `json:"max_fps"`
+	Container        string             `json:"container"`
+	AudioOnly        bool               `json:"audio_only"`
+	AudioSampleRate  pgtype.Int4        `json:"audio_sample_rate"`
+	AudioChannels    pgtype.Int4        `json:"audio_channels"`
+	MinVmaf          pgtype.Float8      `json:"min_vmaf"`
+	Watermark        []byte             `json:"watermark"`
+	LoudnessTarget   pgtype.Float8      `json:"loudness_target"`
+	TruePeakLimit    pgtype.Float8      `json:"true_peak_limit"`
+	Deinterlace      string             `json:"deinterlace"`
+	ToneMap          string             `json:"tone_map"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
 }
 
 type Rendition struct {
-	ID         pgtype.UUID        `json:"id"`
-	JobID      pgtype.UUID        `json:"job_id"`
-	Resolution string             `json:"resolution"`
-	OutputKey  pgtype.Text        `json:"output_key"`
-	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	ID                   pgtype.UUID        `json:"id"`
+	JobID                pgtype.UUID        `json:"job_id"`
+	Resolution           string             `json:"resolution"`
+	OutputKey            pgtype.Text        `json:"output_key"`
+	HlsPlaylistKey       pgtype.Text        `json:"hls_playlist_key"`
+	DashRepresentationID pgtype.Text        `json:"dash_representation_id"`
+	Status               RenditionStatus    `json:"status"`
+	ErrorMessage         pgtype.Text        `json:"error_message"`
+	StartedAt            pgtype.Timestamptz `json:"started_at"`
+	FinishedAt           pgtype.Timestamptz `json:"finished_at"`
+	OutputSizeBytes      pgtype.Int8        `json:"output_size_bytes"`
+	DurationSeconds      pgtype.Float8      `json:"duration_seconds"`
+	TargetBitrateKbps    pgtype.Int4        `json:"target_bitrate_kbps"`
+	VmafMean             pgtype.Float8      `json:"vmaf_mean"`
+	VmafMin              pgtype.Float8      `json:"vmaf_min"`
+	VmafP5               pgtype.Float8      `json:"vmaf_p5"`
+	PsnrMean             pgtype.Float8      `json:"psnr_mean"`
+	SsimMean             pgtype.Float8      `json:"ssim_mean"`
+	LoudnessInputI       pgtype.Float8      `json:"loudness_input_i"`
+	LoudnessInputTp      pgtype.Float8      `json:"loudness_input_tp"`
+	LoudnessInputLra     pgtype.Float8      `json:"loudness_input_lra"`
+	LoudnessOutputI      pgtype.Float8      `json:"loudness_output_i"`
+	LoudnessOutputTp     pgtype.Float8      `json:"loudness_output_tp"`
+	LoudnessOutputLra    pgtype.Float8      `json:"loudness_output_lra"`
+	CreatedAt            pgtype.Timestamptz `json:"created_at"`
 }
@@ -11,11 +11,43 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const cancelJob = `-- name: CancelJob :one
+UPDATE jobs
+SET status = 'cancelled'
+WHERE id = $1 AND status IN ('queued', 'processing')
+RETURNING id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at
+`
+
+// Cancel a job that has not finished yet; returns no rows if it already reached a final state
+func (q *Queries) CancelJob(ctx context.Context, id pgtype.UUID) (Job, error) {
+	row := q.db.QueryRow(ctx, cancelJob, id)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.InputKey,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.ErrorCode,
+		&i.RetryCount,
+		&i.MaxRetries,
+		&i.StartedAt,
+		&i.WorkerID,
+		&i.Options,
+		&i.HlsMasterKey,
+		&i.DashManifestKey,
+		&i.Ladder,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const countJobsByStatus = `-- name: CountJobsByStatus :one
 SELECT 
     COUNT(*) FILTER (WHERE status = 'queued') AS queued,
     COUNT(*) FILTER (WHERE status = 'processing') AS processing,
     COUNT(*) FILTER (WHERE status = 'completed') AS completed,
+    COUNT(*) FILTER (WHERE status = 'partial') AS partial,
     COUNT(*) FILTER (WHERE status = 'failed') AS failed,
     COUNT(*) AS total
 FROM jobs
@@ -25,6 +57,7 @@ type CountJobsByStatusRow struct {
 	Queued     int64 `json:"queued"`
 	Processing int64 `json:"processing"`
 	Completed  int64 `json:"completed"`
+	Partial    int64 `json:"partial"`
 	Failed     int64 `json:"failed"`
 	Total      int64 `json:"total"`
 }
@@ -36,6 +69,7 @@ func (q *Queries) CountJobsByStatus(ctx context.Context) (CountJobsByStatusRow,
 		&i.Queued,
 		&i.Processing,
 		&i.Completed,
+		&i.Partial,
 		&i.Failed,
 		&i.Total,
 	)
@@ -44,7 +78,7 @@ func (q *Queries) CountJobsByStatus(ctx context.Context) (CountJobsByStatusRow,
 
 const getJob = `-- name: GetJob :one
 
-SELECT id, input_key, status, error_message, retry_count, max_retries, started_at, worker_id, created_at, updated_at FROM jobs
+SELECT id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at FROM jobs
 WHERE id = $1
 `
 
@@ -57,18 +91,128 @@ func (q *Queries) GetJob(ctx context.Context, id pgtype.UUID) (Job, error) {
 		&i.InputKey,
 		&i.Status,
 		&i.ErrorMessage,
+		&i.ErrorCode,
 		&i.RetryCount,
 		&i.MaxRetries,
 		&i.StartedAt,
 		&i.WorkerID,
+		&i.Options,
+		&i.HlsMasterKey,
+		&i.DashManifestKey,
+		&i.Ladder,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
+const getJobArtifacts = `-- name: GetJobArtifacts :many
+SELECT id, job_id, kind, output_key, content_type, language, created_at FROM job_artifacts
+WHERE job_id = $1
+ORDER BY output_key
+`
+
+func (q *Queries) GetJobArtifacts(ctx context.Context, jobID pgtype.UUID) ([]JobArtifact, error) {
+	rows, err := q.db.Query(ctx, getJobArtifacts, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []JobArtifact{}
+	for rows.Next() {
+		var i JobArtifact
+		if err := rows.Scan(
+			&i.ID,
+			&i.JobID,
+			&i.Kind,
+			&i.OutputKey,
+			&i.ContentType,
+			&i.Language,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getJobChunks = `-- name: GetJobChunks :many
+SELECT id, job_id, chunk_index, source_key, start_seconds, duration_seconds, status, attempts, worker_id, error_message, started_at, finished_at, created_at, updated_at FROM job_chunks
+WHERE job_id = $1
+ORDER BY chunk_index
+`
+
+func (q *Queries) GetJobChunks(ctx context.Context, jobID pgtype.UUID) ([]JobChunk, error) {
+	rows, err := q.db.Query(ctx, getJobChunks, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []JobChunk{}
+	for rows.Next() {
+		var i JobChunk
+		if err := rows.Scan(
+			&i.ID,
+			&i.JobID,
+			&i.ChunkIndex,
+			&i.SourceKey,
+			&i.StartSeconds,
+			&i.DurationSeconds,
+			&i.Status,
+			&i.Attempts,
+			&i.WorkerID,
+			&i.ErrorMessage,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMediaProbe = `-- name: GetMediaProbe :one
+SELECT job_id, duration_seconds, container, video_codec, audio_codec, width, height, frame_rate, bit_rate, rotation, audio_channels, audio_channel_layout, field_order, color_transfer, color_primaries, hdr_format, created_at FROM media_probes
+WHERE job_id = $1
+`
+
+func (q *Queries) GetMediaProbe(ctx context.Context, jobID pgtype.UUID) (MediaProbe, error) {
+	row := q.db.QueryRow(ctx, getMediaProbe, jobID)
+	var i MediaProbe
+	err := row.Scan(
+		&i.JobID,
+		&i.DurationSeconds,
+		&i.Container,
+		&i.VideoCodec,
+		&i.AudioCodec,
+		&i.Width,
+		&i.Height,
+		&i.FrameRate,
+		&i.BitRate,
+		&i.Rotation,
+		&i.AudioChannels,
+		&i.AudioChannelLayout,
+		&i.FieldOrder,
+		&i.ColorTransfer,
+		&i.ColorPrimaries,
+		&i.HdrFormat,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const getRenditionsByJobID = `-- name: GetRenditionsByJobID :many
-SELECT id, job_id, resolution, output_key, created_at FROM renditions
+SELECT id, job_id, resolution, output_key, hls_playlist_key, dash_representation_id, status, error_message, started_at, finished_at, output_size_bytes, duration_seconds, target_bitrate_kbps, vmaf_mean, vmaf_min, vmaf_p5, psnr_mean, ssim_mean, loudness_input_i, loudness_input_tp, loudness_input_lra, loudness_output_i, loudness_output_tp, loudness_output_lra, created_at FROM renditions
 WHERE job_id = $1
 ORDER BY resolution
 `
@@ -87,6 +231,26 @@ func (q *Queries) GetRenditionsByJobID(ctx context.Context, jobID pgtype.UUID) (
 			&i.JobID,
 			&i.Resolution,
 			&i.OutputKey,
+			&i.HlsPlaylistKey,
+			&i.DashRepresentationID,
+			&i.Status,
+			&i.ErrorMessage,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.OutputSizeBytes,
+			&i.DurationSeconds,
+			&i.TargetBitrateKbps,
+			&i.VmafMean,
+			&i.VmafMin,
+			&i.VmafP5,
+			&i.PsnrMean,
+			&i.SsimMean,
+			&i.LoudnessInputI,
+			&i.LoudnessInputTp,
+			&i.LoudnessInputLra,
+			&i.LoudnessOutputI,
+			&i.LoudnessOutputTp,
+			&i.LoudnessOutputLra,
 			&i.CreatedAt,
 		); err != nil {
 			return nil, err
@@ -100,7 +264,7 @@ func (q *Queries) GetRenditionsByJobID(ctx context.Context, jobID pgtype.UUID) (
 }
 
 const listJobs = `-- name: ListJobs :many
-SELECT id, input_key, status, error_message, retry_count, max_retries, started_at, worker_id, created_at, updated_at FROM jobs
+SELECT id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at FROM jobs
 ORDER BY created_at DESC
 LIMIT $1 OFFSET $2
 `
@@ -124,10 +288,15 @@ func (q *Queries) ListJobs(ctx context.Context, arg ListJobsParams) ([]Job, erro
 			&i.InputKey,
 			&i.Status,
 			&i.ErrorMessage,
+			&i.ErrorCode,
 			&i.RetryCount,
 			&i.MaxRetries,
 			&i.StartedAt,
 			&i.WorkerID,
+			&i.Options,
+			&i.HlsMasterKey,
+			&i.DashManifestKey,
+			&i.Ladder,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -142,7 +311,7 @@ func (q *Queries) ListJobs(ctx context.Context, arg ListJobsParams) ([]Job, erro
 }
 
 const listJobsByStatus = `-- name: ListJobsByStatus :many
-SELECT id, input_key, status, error_message, retry_count, max_retries, started_at, worker_id, created_at, updated_at FROM jobs
+SELECT id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at FROM jobs
 WHERE status = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3
@@ -168,10 +337,15 @@ func (q *Queries) ListJobsByStatus(ctx context.Context, arg ListJobsByStatusPara
 			&i.InputKey,
 			&i.Status,
 			&i.ErrorMessage,
+			&i.ErrorCode,
 			&i.RetryCount,
 			&i.MaxRetries,
 			&i.StartedAt,
 			&i.WorkerID,
+			&i.Options,
+			&i.HlsMasterKey,
+			&i.DashManifestKey,
+			&i.Ladder,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
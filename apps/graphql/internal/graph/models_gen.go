@@ -11,13 +11,90 @@ import (
 
 // Represents a transcoding job
 type Job struct {
-	ID           string       `json:"id"`
-	Status       JobStatus    `json:"status"`
-	InputKey     string       `json:"inputKey"`
-	ErrorMessage *string      `json:"errorMessage,omitempty"`
-	CreatedAt    time.Time    `json:"createdAt"`
-	UpdatedAt    time.Time    `json:"updatedAt"`
-	Renditions   []*Rendition `json:"renditions"`
+	ID           string    `json:"id"`
+	Status       JobStatus `json:"status"`
+	InputKey     string    `json:"inputKey"`
+	ErrorMessage *string   `json:"errorMessage,omitempty"`
+	// Machine-readable failure kind when status is FAILED, e.g. invalid_input, object_not_found
+	// or storage_unavailable. Permanent kinds fail the job without retries.
+	ErrorCode *string `json:"errorCode,omitempty"`
+	// S3 key of the HLS master playlist, when HLS packaging was requested
+	HlsMasterKey *string `json:"hlsMasterKey,omitempty"`
+	// S3 key of the DASH MPD manifest, when DASH packaging was requested
+	DashManifestKey *string `json:"dashManifestKey,omitempty"`
+	// Metadata of the input file, available once a worker has probed it
+	Source     *SourceMetadata `json:"source,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	UpdatedAt  time.Time       `json:"updatedAt"`
+	Renditions []*Rendition    `json:"renditions"`
+	// Auxiliary outputs such as thumbnail sprite sheets and their WebVTT track
+	Artifacts []*JobArtifact `json:"artifacts"`
+	// Ladder chosen by per-title analysis, lowest rung first; null unless per-title encoding was requested
+	Ladder []*LadderRung `json:"ladder,omitempty"`
+	// Chunks of a job split across workers, in source order; empty unless chunked encoding was requested
+	Chunks []*JobChunk `json:"chunks"`
+}
+
+// An auxiliary output file produced for a job
+type JobArtifact struct {
+	// Artifact kind, e.g. "sprite", "thumbnails_vtt", "subtitles" or "captions"
+	Kind        string `json:"kind"`
+	OutputKey   string `json:"outputKey"`
+	ContentType string `json:"contentType"`
+	// Language of a subtitle track, e.g. "en"
+	Language *string `json:"language,omitempty"`
+}
+
+// A keyframe-aligned piece of a chunked job's input, encoded by any worker and retried on its own
+type JobChunk struct {
+	Index           int         `json:"index"`
+	StartSeconds    float64     `json:"startSeconds"`
+	DurationSeconds float64     `json:"durationSeconds"`
+	Status          ChunkStatus `json:"status"`
+	// Encode attempts so far
+	Attempts     int     `json:"attempts"`
+	WorkerID     *string `json:"workerId,omitempty"`
+	ErrorMessage *string `json:"errorMessage,omitempty"`
+}
+
+// The per-title decision for one rung of a job's ladder
+type LadderRung struct {
+	Resolution string `json:"resolution"`
+	Height     int    `json:"height"`
+	// Bitrate in kbit/s the complexity probe needed at this rung
+	ProbeBitrateKbps *int `json:"probeBitrateKbps,omitempty"`
+	// Video bitrate in kbit/s chosen for this rung
+	TargetBitrateKbps *int `json:"targetBitrateKbps,omitempty"`
+	Dropped           bool `json:"dropped"`
+	// Why the rung was dropped
+	Reason *string `json:"reason,omitempty"`
+}
+
+// One EBU R128 loudness measurement
+type LoudnessMeasurement struct {
+	IntegratedLufs float64 `json:"integratedLufs"`
+	TruePeakDbtp   float64 `json:"truePeakDbtp"`
+	RangeLu        float64 `json:"rangeLu"`
+}
+
+// A rendition's loudness measured on the source and on the normalized output
+type LoudnessReport struct {
+	Input  *LoudnessMeasurement `json:"input"`
+	Output *LoudnessMeasurement `json:"output"`
+}
+
+type Mutation struct {
+}
+
+// Objective quality scores of a rendition, measured against the source at the source's size
+type QualityScores struct {
+	VmafMean float64 `json:"vmafMean"`
+	VmafMin  float64 `json:"vmafMin"`
+	// 5th percentile frame VMAF
+	VmafP5 float64 `json:"vmafP5"`
+	// Mean luma PSNR in dB
+	PsnrMean float64 `json:"psnrMean"`
+	SsimMean float64 `json:"ssimMean"`
 }
 
 type Query struct {
@@ -28,37 +105,148 @@ type Rendition struct {
 	ID         string  `json:"id"`
 	Resolution string  `json:"resolution"`
 	OutputKey  *string `json:"outputKey,omitempty"`
+	// S3 key of this rendition's HLS media playlist, when HLS packaging was requested
+	HlsPlaylistKey *string `json:"hlsPlaylistKey,omitempty"`
+	// Representation ID of this rendition inside the job's DASH manifest
+	DashRepresentationID *string `json:"dashRepresentationId,omitempty"`
+	// Output formats this rendition is available in
+	Formats []OutputFormat  `json:"formats"`
+	Status  RenditionStatus `json:"status"`
+	// Error details if status is failed
+	ErrorMessage *string    `json:"errorMessage,omitempty"`
+	StartedAt    *time.Time `json:"startedAt,omitempty"`
+	FinishedAt   *time.Time `json:"finishedAt,omitempty"`
+	// Size of the encoded output file in bytes
+	OutputSizeBytes *int `json:"outputSizeBytes,omitempty"`
+	// Duration of the encoded output in seconds
+	DurationSeconds *float64 `json:"durationSeconds,omitempty"`
+	// Video bitrate in kbit/s chosen by per-title analysis
+	TargetBitrateKbps *int `json:"targetBitrateKbps,omitempty"`
+	// Objective quality against the source, when the rendition was scored
+	Quality *QualityScores `json:"quality,omitempty"`
+	// EBU R128 loudness before and after normalization, when the profile normalizes loudness
+	Loudness *LoudnessReport `json:"loudness,omitempty"`
+	// Live encode progress, published by the worker while this rendition is transcoding
+	Progress *RenditionProgress `json:"progress,omitempty"`
+}
+
+// Encode progress of a single rendition
+type RenditionProgress struct {
+	// Percent complete, 0-100
+	Percent float64 `json:"percent"`
+	// Encode speed as a multiple of realtime
+	Speed float64 `json:"speed"`
+	// Estimated seconds remaining
+	EtaSeconds int       `json:"etaSeconds"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Properties of a job's input file as reported by ffprobe
+type SourceMetadata struct {
+	DurationSeconds *float64 `json:"durationSeconds,omitempty"`
+	Container       *string  `json:"container,omitempty"`
+	VideoCodec      *string  `json:"videoCodec,omitempty"`
+	AudioCodec      *string  `json:"audioCodec,omitempty"`
+	Width           *int     `json:"width,omitempty"`
+	Height          *int     `json:"height,omitempty"`
+	FrameRate       *float64 `json:"frameRate,omitempty"`
+	// Overall bitrate in bits per second
+	BitRate *int `json:"bitRate,omitempty"`
+	// Display rotation in degrees (0, 90, 180 or 270)
+	Rotation           int     `json:"rotation"`
+	AudioChannels      *int    `json:"audioChannels,omitempty"`
+	AudioChannelLayout *string `json:"audioChannelLayout,omitempty"`
+	// Scan type detected with idet: "progressive", "tff" or "bff"
+	FieldOrder *string `json:"fieldOrder,omitempty"`
+	// Transfer characteristics, e.g. "bt709", "smpte2084" (PQ) or "arib-std-b67" (HLG)
+	ColorTransfer  *string `json:"colorTransfer,omitempty"`
+	ColorPrimaries *string `json:"colorPrimaries,omitempty"`
+	// "pq" or "hlg" for HDR sources, null for SDR
+	HdrFormat *string `json:"hdrFormat,omitempty"`
 }
 
 // System-wide metrics for monitoring
 type SystemMetrics struct {
-	QueueDepth     int `json:"queueDepth"`
+	QueueDepth int `json:"queueDepth"`
+	// Retries waiting for their backoff before re-entering the queue
+	DelayedJobs    int `json:"delayedJobs"`
 	TotalJobs      int `json:"totalJobs"`
 	CompletedJobs  int `json:"completedJobs"`
+	PartialJobs    int `json:"partialJobs"`
 	FailedJobs     int `json:"failedJobs"`
 	ProcessingJobs int `json:"processingJobs"`
 }
 
-// Status of a transcoding job
+// Status of a single chunk within a chunked job
+type ChunkStatus string
+
+const (
+	ChunkStatusPending    ChunkStatus = "pending"
+	ChunkStatusProcessing ChunkStatus = "processing"
+	ChunkStatusCompleted  ChunkStatus = "completed"
+	ChunkStatusFailed     ChunkStatus = "failed"
+)
+
+var AllChunkStatus = []ChunkStatus{
+	ChunkStatusPending,
+	ChunkStatusProcessing,
+	ChunkStatusCompleted,
+	ChunkStatusFailed,
+}
+
+func (e ChunkStatus) IsValid() bool {
+	switch e {
+	case ChunkStatusPending, ChunkStatusProcessing, ChunkStatusCompleted, ChunkStatusFailed:
+		return true
+	}
+	return false
+}
+
+func (e ChunkStatus) String() string {
+	return string(e)
+}
+
+func (e *ChunkStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ChunkStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ChunkStatus", str)
+	}
+	return nil
+}
+
+func (e ChunkStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Status of a transcoding job. A partial job has at least one completed and one failed rendition.
 type JobStatus string
 
 const (
 	JobStatusPending    JobStatus = "pending"
 	JobStatusProcessing JobStatus = "processing"
 	JobStatusCompleted  JobStatus = "completed"
+	JobStatusPartial    JobStatus = "partial"
 	JobStatusFailed     JobStatus = "failed"
+	JobStatusCancelled  JobStatus = "cancelled"
 )
 
 var AllJobStatus = []JobStatus{
 	JobStatusPending,
 	JobStatusProcessing,
 	JobStatusCompleted,
+	JobStatusPartial,
 	JobStatusFailed,
+	JobStatusCancelled,
 }
 
 func (e JobStatus) IsValid() bool {
 	switch e {
-	case JobStatusPending, JobStatusProcessing, JobStatusCompleted, JobStatusFailed:
+	case JobStatusPending, JobStatusProcessing, JobStatusCompleted, JobStatusPartial, JobStatusFailed, JobStatusCancelled:
 		return true
 	}
 	return false
@@ -84,3 +272,93 @@ func (e *JobStatus) UnmarshalGQL(v interface{}) error {
 func (e JobStatus) MarshalGQL(w io.Writer) {
 	fmt.Fprint(w, strconv.Quote(e.String()))
 }
+
+// Delivery format of a rendition
+type OutputFormat string
+
+const (
+	OutputFormatMp4  OutputFormat = "mp4"
+	OutputFormatHls  OutputFormat = "hls"
+	OutputFormatDash OutputFormat = "dash"
+)
+
+var AllOutputFormat = []OutputFormat{
+	OutputFormatMp4,
+	OutputFormatHls,
+	OutputFormatDash,
+}
+
+func (e OutputFormat) IsValid() bool {
+	switch e {
+	case OutputFormatMp4, OutputFormatHls, OutputFormatDash:
+		return true
+	}
+	return false
+}
+
+func (e OutputFormat) String() string {
+	return string(e)
+}
+
+func (e *OutputFormat) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OutputFormat(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OutputFormat", str)
+	}
+	return nil
+}
+
+func (e OutputFormat) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Status of a single rendition within a job
+type RenditionStatus string
+
+const (
+	RenditionStatusPending    RenditionStatus = "pending"
+	RenditionStatusProcessing RenditionStatus = "processing"
+	RenditionStatusCompleted  RenditionStatus = "completed"
+	RenditionStatusFailed     RenditionStatus = "failed"
+)
+
+var AllRenditionStatus = []RenditionStatus{
+	RenditionStatusPending,
+	RenditionStatusProcessing,
+	RenditionStatusCompleted,
+	RenditionStatusFailed,
+}
+
+func (e RenditionStatus) IsValid() bool {
+	switch e {
+	case RenditionStatusPending, RenditionStatusProcessing, RenditionStatusCompleted, RenditionStatusFailed:
+		return true
+	}
+	return false
+}
+
+func (e RenditionStatus) String() string {
+	return string(e)
+}
+
+func (e *RenditionStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = RenditionStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid RenditionStatus", str)
+	}
+	return nil
+}
+
+func (e RenditionStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
@@ -6,13 +6,52 @@ package graph
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"time"
 
 	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/graphql/internal/db"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/packages/jobqueue"
 	"github.com/google/uuid"
+	pgx "github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+// CancelJob is the resolver for the cancelJob field.
+func (r *mutationResolver) CancelJob(ctx context.Context, id string) (*Job, error) {
+	jobUUID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+
+	pgUUID := pgtype.UUID{
+		Bytes: jobUUID,
+		Valid: true,
+	}
+
+	dbJob, err := r.DB.CancelJob(ctx, pgUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Either the job doesn't exist or it already finished
+		existing, getErr := r.DB.GetJob(ctx, pgUUID)
+		if getErr != nil {
+			return nil, getErr
+		}
+		return nil, fmt.Errorf("job is already %s", existing.Status)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Tell the worker holding the job to stop, and drop queued copies and pending retries
+	if err := jobqueue.Cancel(ctx, r.RedisClient, id); err != nil {
+		return nil, err
+	}
+
+	return r.convertJob(ctx, dbJob)
+}
+
 // Jobs is the resolver for the jobs field.
 func (r *queryResolver) Jobs(ctx context.Context, limit *int, offset *int, status *JobStatus) ([]*Job, error) {
 	// Set defaults
@@ -88,24 +127,39 @@ func (r *queryResolver) SystemMetrics(ctx context.Context) (*SystemMetrics, erro
 	}
 
 	// Get queue depth from Redis
-	queueDepth, err := r.RedisClient.LLen(ctx, "jobs:pending").Result()
+	queueDepth, err := r.RedisClient.LLen(ctx, jobqueue.JobQueueKey).Result()
 	if err != nil {
 		// Log error but don't fail - queue depth is supplementary
+		log.Printf("Failed to get queue depth: %v", err)
 		queueDepth = 0
 	}
 
+	// Retries waiting out their backoff are not in the queue yet
+	delayedJobs, err := r.RedisClient.ZCard(ctx, jobqueue.DelayedQueueKey).Result()
+	if err != nil {
+		// Supplementary as well
+		log.Printf("Failed to count delayed retries: %v", err)
+		delayedJobs = 0
+	}
+
 	return &SystemMetrics{
 		QueueDepth:     int(queueDepth),
+		DelayedJobs:    int(delayedJobs),
 		TotalJobs:      int(counts.Total),
 		CompletedJobs:  int(counts.Completed),
+		PartialJobs:    int(counts.Partial),
 		FailedJobs:     int(counts.Failed),
 		ProcessingJobs: int(counts.Processing),
 	}, nil
 }
 
+// Mutation returns MutationResolver implementation.
+func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
+
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
 
 // !!! WARNING !!!
@@ -114,30 +168,98 @@ type queryResolver struct{ *Resolver }
 //   - When renaming or deleting a resolver the old code will be put in here. You can safely delete
 //     it when you're done.
 //   - You have helper methods in this file. Move them out to keep these resolver files clean.
-func (r *queryResolver) convertJob(ctx context.Context, dbJob db.Job) (*Job, error) {
+func (r *Resolver) convertJob(ctx context.Context, dbJob db.Job) (*Job, error) {
 	// Get renditions for this job
 	dbRenditions, err := r.DB.GetRenditionsByJobID(ctx, dbJob.ID)
 	if err != nil {
 		return nil, err
 	}
 
+	// Live progress is supplementary, so a Redis failure just omits it
+	progress := r.renditionProgress(ctx, uuidToString(dbJob.ID))
+
 	renditions := make([]*Rendition, len(dbRenditions))
 	for i, dbRend := range dbRenditions {
 		renditions[i] = &Rendition{
-			ID:         uuidToString(dbRend.ID),
-			Resolution: dbRend.Resolution,
-			OutputKey:  pgtextToStringPtr(dbRend.OutputKey),
+			ID:                   uuidToString(dbRend.ID),
+			Resolution:           dbRend.Resolution,
+			OutputKey:            pgtextToStringPtr(dbRend.OutputKey),
+			HlsPlaylistKey:       pgtextToStringPtr(dbRend.HlsPlaylistKey),
+			DashRepresentationID: pgtextToStringPtr(dbRend.DashRepresentationID),
+			Formats:              renditionFormats(dbRend),
+			Status:               RenditionStatus(dbRend.Status),
+			ErrorMessage:         pgtextToStringPtr(dbRend.ErrorMessage),
+			StartedAt:            pgtimestampToTimePtr(dbRend.StartedAt),
+			FinishedAt:           pgtimestampToTimePtr(dbRend.FinishedAt),
+			OutputSizeBytes:      pgint8ToIntPtr(dbRend.OutputSizeBytes),
+			DurationSeconds:      pgfloat8ToFloatPtr(dbRend.DurationSeconds),
+			TargetBitrateKbps:    pgint4ToIntPtr(dbRend.TargetBitrateKbps),
+			Quality:              convertQuality(dbRend),
+			Loudness:             convertLoudness(dbRend),
+			Progress:             progress[dbRend.Resolution],
+		}
+	}
+
+	// Source metadata only exists once a worker has probed the input
+	var source *SourceMetadata
+	probe, err := r.DB.GetMediaProbe(ctx, dbJob.ID)
+	if err == nil {
+		source = convertMediaProbe(probe)
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	dbArtifacts, err := r.DB.GetJobArtifacts(ctx, dbJob.ID)
+	if err != nil {
+		return nil, err
+	}
+	artifacts := make([]*JobArtifact, len(dbArtifacts))
+	for i, a := range dbArtifacts {
+		artifacts[i] = &JobArtifact{
+			Kind:        a.Kind,
+			OutputKey:   a.OutputKey,
+			ContentType: a.ContentType,
+			Language:    pgtextToStringPtr(a.Language),
+		}
+	}
+
+	ladder, err := convertLadder(dbJob.Ladder)
+	if err != nil {
+		return nil, err
+	}
+
+	dbChunks, err := r.DB.GetJobChunks(ctx, dbJob.ID)
+	if err != nil {
+		return nil, err
+	}
+	chunks := make([]*JobChunk, len(dbChunks))
+	for i, c := range dbChunks {
+		chunks[i] = &JobChunk{
+			Index:           int(c.ChunkIndex),
+			StartSeconds:    c.StartSeconds,
+			DurationSeconds: c.DurationSeconds,
+			Status:          ChunkStatus(c.Status),
+			Attempts:        int(c.Attempts),
+			WorkerID:        pgtextToStringPtr(c.WorkerID),
+			ErrorMessage:    pgtextToStringPtr(c.ErrorMessage),
 		}
 	}
 
 	return &Job{
-		ID:           uuidToString(dbJob.ID),
-		Status:       mapDBStatusToGraphQL(dbJob.Status),
-		InputKey:     dbJob.InputKey,
-		ErrorMessage: pgtextToStringPtr(dbJob.ErrorMessage),
-		CreatedAt:    dbJob.CreatedAt.Time,
-		UpdatedAt:    dbJob.UpdatedAt.Time,
-		Renditions:   renditions,
+		ID:              uuidToString(dbJob.ID),
+		Status:          mapDBStatusToGraphQL(dbJob.Status),
+		InputKey:        dbJob.InputKey,
+		ErrorMessage:    pgtextToStringPtr(dbJob.ErrorMessage),
+		ErrorCode:       pgtextToStringPtr(dbJob.ErrorCode),
+		HlsMasterKey:    pgtextToStringPtr(dbJob.HlsMasterKey),
+		DashManifestKey: pgtextToStringPtr(dbJob.DashManifestKey),
+		Source:          source,
+		CreatedAt:       dbJob.CreatedAt.Time,
+		UpdatedAt:       dbJob.UpdatedAt.Time,
+		Renditions:      renditions,
+		Artifacts:       artifacts,
+		Ladder:          ladder,
+		Chunks:          chunks,
 	}, nil
 }
 func uuidToString(u pgtype.UUID) string {
@@ -152,6 +274,148 @@ func pgtextToStringPtr(t pgtype.Text) *string {
 	}
 	return &t.String
 }
+func convertQuality(r db.Rendition) *QualityScores {
+	if !r.VmafMean.Valid {
+		return nil
+	}
+	return &QualityScores{
+		VmafMean: r.VmafMean.Float64,
+		VmafMin:  r.VmafMin.Float64,
+		VmafP5:   r.VmafP5.Float64,
+		PsnrMean: r.PsnrMean.Float64,
+		SsimMean: r.SsimMean.Float64,
+	}
+}
+func convertLoudness(r db.Rendition) *LoudnessReport {
+	if !r.LoudnessInputI.Valid || !r.LoudnessOutputI.Valid {
+		return nil
+	}
+	return &LoudnessReport{
+		Input: &LoudnessMeasurement{
+			IntegratedLufs: r.LoudnessInputI.Float64,
+			TruePeakDbtp:   r.LoudnessInputTp.Float64,
+			RangeLu:        r.LoudnessInputLra.Float64,
+		},
+		Output: &LoudnessMeasurement{
+			IntegratedLufs: r.LoudnessOutputI.Float64,
+			TruePeakDbtp:   r.LoudnessOutputTp.Float64,
+			RangeLu:        r.LoudnessOutputLra.Float64,
+		},
+	}
+}
+func convertLadder(raw []byte) ([]*LadderRung, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	// Decode with the worker's field names; the generated model uses GraphQL casing
+	var rungs []struct {
+		Resolution        string  `json:"resolution"`
+		Height            int     `json:"height"`
+		ProbeBitrateKbps  *int    `json:"probe_bitrate_kbps"`
+		TargetBitrateKbps *int    `json:"target_bitrate_kbps"`
+		Dropped           bool    `json:"dropped"`
+		Reason            *string `json:"reason"`
+	}
+	if err := json.Unmarshal(raw, &rungs); err != nil {
+		return nil, fmt.Errorf("invalid ladder: %w", err)
+	}
+	ladder := make([]*LadderRung, len(rungs))
+	for i, r := range rungs {
+		ladder[i] = &LadderRung{
+			Resolution:        r.Resolution,
+			Height:            r.Height,
+			ProbeBitrateKbps:  r.ProbeBitrateKbps,
+			TargetBitrateKbps: r.TargetBitrateKbps,
+			Dropped:           r.Dropped,
+			Reason:            r.Reason,
+		}
+	}
+	return ladder, nil
+}
+func convertMediaProbe(p db.MediaProbe) *SourceMetadata {
+	return &SourceMetadata{
+		DurationSeconds:    pgfloat8ToFloatPtr(p.DurationSeconds),
+		Container:          pgtextToStringPtr(p.Container),
+		VideoCodec:         pgtextToStringPtr(p.VideoCodec),
+		AudioCodec:         pgtextToStringPtr(p.AudioCodec),
+		Width:              pgint4ToIntPtr(p.Width),
+		Height:             pgint4ToIntPtr(p.Height),
+		FrameRate:          pgfloat8ToFloatPtr(p.FrameRate),
+		BitRate:            pgint8ToIntPtr(p.BitRate),
+		Rotation:           int(p.Rotation),
+		AudioChannels:      pgint4ToIntPtr(p.AudioChannels),
+		AudioChannelLayout: pgtextToStringPtr(p.AudioChannelLayout),
+		FieldOrder:         pgtextToStringPtr(p.FieldOrder),
+		ColorTransfer:      pgtextToStringPtr(p.ColorTransfer),
+		ColorPrimaries:     pgtextToStringPtr(p.ColorPrimaries),
+		HdrFormat:          pgtextToStringPtr(p.HdrFormat),
+	}
+}
+func pgtimestampToTimePtr(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+func pgint4ToIntPtr(i pgtype.Int4) *int {
+	if !i.Valid {
+		return nil
+	}
+	v := int(i.Int32)
+	return &v
+}
+func pgint8ToIntPtr(i pgtype.Int8) *int {
+	if !i.Valid {
+		return nil
+	}
+	v := int(i.Int64)
+	return &v
+}
+func pgfloat8ToFloatPtr(f pgtype.Float8) *float64 {
+	if !f.Valid {
+		return nil
+	}
+	return &f.Float64
+}
+func (r *Resolver) renditionProgress(ctx context.Context, jobID string) map[string]*RenditionProgress {
+	fields, err := r.RedisClient.HGetAll(ctx, jobqueue.ProgressKeyPrefix+jobID).Result()
+	if err != nil {
+		return nil
+	}
+
+	progress := make(map[string]*RenditionProgress, len(fields))
+	for resolution, value := range fields {
+		var p struct {
+			Percent    float64   `json:"percent"`
+			Speed      float64   `json:"speed"`
+			ETASeconds int64     `json:"eta_seconds"`
+			UpdatedAt  time.Time `json:"updated_at"`
+		}
+		if err := json.Unmarshal([]byte(value), &p); err != nil {
+			continue
+		}
+		progress[resolution] = &RenditionProgress{
+			Percent:    p.Percent,
+			Speed:      p.Speed,
+			EtaSeconds: int(p.ETASeconds),
+			UpdatedAt:  p.UpdatedAt,
+		}
+	}
+	return progress
+}
+func renditionFormats(r db.Rendition) []OutputFormat {
+	formats := []OutputFormat{}
+	if r.OutputKey.Valid {
+		formats = append(formats, OutputFormatMp4)
+	}
+	if r.HlsPlaylistKey.Valid {
+		formats = append(formats, OutputFormatHls)
+	}
+	if r.DashRepresentationID.Valid {
+		formats = append(formats, OutputFormatDash)
+	}
+	return formats
+}
 func mapGraphQLStatusToDB(status JobStatus) db.JobStatus {
 	switch status {
 	case JobStatusPending:
@@ -160,8 +424,12 @@ func mapGraphQLStatusToDB(status JobStatus) db.JobStatus {
 		return db.JobStatusProcessing
 	case JobStatusCompleted:
 		return db.JobStatusCompleted
+	case JobStatusPartial:
+		return db.JobStatusPartial
 	case JobStatusFailed:
 		return db.JobStatusFailed
+	case JobStatusCancelled:
+		return db.JobStatusCancelled
 	default:
 		return db.JobStatusQueued
 	}
@@ -174,8 +442,12 @@ func mapDBStatusToGraphQL(status db.JobStatus) JobStatus {
 		return JobStatusProcessing
 	case db.JobStatusCompleted:
 		return JobStatusCompleted
+	case db.JobStatusPartial:
+		return JobStatusPartial
 	case db.JobStatusFailed:
 		return JobStatusFailed
+	case db.JobStatusCancelled:
+		return JobStatusCancelled
 	default:
 		return JobStatusPending
 	}
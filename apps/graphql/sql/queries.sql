@@ -4,6 +4,13 @@
 SELECT * FROM jobs
 WHERE id = $1;
 
+-- name: CancelJob :one
+-- Cancel a job that has not finished yet; returns no rows if it already reached a final state
+UPDATE jobs
+SET status = 'cancelled'
+WHERE id = $1 AND status IN ('queued', 'processing')
+RETURNING *;
+
 -- name: ListJobs :many
 SELECT * FROM jobs
 ORDER BY created_at DESC
@@ -20,11 +27,26 @@ SELECT * FROM renditions
 WHERE job_id = $1
 ORDER BY resolution;
 
+-- name: GetMediaProbe :one
+SELECT * FROM media_probes
+WHERE job_id = $1;
+
 -- name: CountJobsByStatus :one
 SELECT 
     COUNT(*) FILTER (WHERE status = 'queued') AS queued,
     COUNT(*) FILTER (WHERE status = 'processing') AS processing,
     COUNT(*) FILTER (WHERE status = 'completed') AS completed,
+    COUNT(*) FILTER (WHERE status = 'partial') AS partial,
     COUNT(*) FILTER (WHERE status = 'failed') AS failed,
     COUNT(*) AS total
 FROM jobs;
+
+-- name: GetJobArtifacts :many
+SELECT * FROM job_artifacts
+WHERE job_id = $1
+ORDER BY output_key;
+
+-- name: GetJobChunks :many
+SELECT * FROM job_chunks
+WHERE job_id = $1
+ORDER BY chunk_index;
@@ -6,11 +6,13 @@ interface JobStatusPillProps {
   showDot?: boolean;
 }
 
-const statusConfig: Record<JobStatus, { label: string; variant: "pending" | "processing" | "completed" | "failed" }> = {
+const statusConfig: Record<JobStatus, { label: string; variant: "default" | "pending" | "processing" | "completed" | "failed" }> = {
   pending: { label: "Queued", variant: "pending" },
   processing: { label: "Processing", variant: "processing" },
   completed: { label: "Completed", variant: "completed" },
+  partial: { label: "Partial", variant: "failed" },
   failed: { label: "Failed", variant: "failed" },
+  cancelled: { label: "Cancelled", variant: "default" },
 };
 
 export function JobStatusPill({ status, showDot = true }: JobStatusPillProps) {
@@ -31,6 +33,8 @@ export function JobStatusPill({ status, showDot = true }: JobStatusPillProps) {
                 ? "var(--status-processing)"
                 : status === "completed"
                 ? "var(--status-completed)"
+                : status === "cancelled"
+                ? "var(--text-secondary)"
                 : "var(--status-failed)",
           }}
         />
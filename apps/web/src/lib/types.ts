@@ -1,5 +1,5 @@
 // Job status enum matching backend
-export type JobStatus = "pending" | "processing" | "completed" | "failed";
+export type JobStatus = "pending" | "processing" | "completed" | "partial" | "failed" | "cancelled";
 
 // Job from GraphQL API
 export interface Job {
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/queue"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/storage"
+)
+
+const (
+	// cancelPollInterval is how often a running job checks whether it has been cancelled
+	cancelPollInterval = 5 * time.Second
+	// lockExtendInterval is how often a running task's lock is extended (it lasts queue.DefaultLockTTL)
+	lockExtendInterval = 2 * time.Minute
+)
+
+// errJobCancelled is the cause attached to a job's context when the API cancels it.
+// Cancelled jobs are cleaned up but never retried or moved to the dead letter queue.
+var errJobCancelled = errors.New("job cancelled")
+
+// watchTask runs alongside a task until jobCtx is done. It extends the task's lock every
+// lockInterval and, checking every pollInterval, cancels jobCtx with errJobCancelled once the
+// API flags the job as cancelled, which kills any running FFmpeg process.
+func watchTask(ctx, jobCtx context.Context, cancel context.CancelCauseFunc, consumer *queue.Consumer, t task, lockInterval, pollInterval time.Duration) {
+	ticker := time.NewTicker(lockInterval)
+	defer ticker.Stop()
+	cancelTicker := time.NewTicker(pollInterval)
+	defer cancelTicker.Stop()
+
+	for {
+		select {
+		case <-jobCtx.Done():
+			return
+		case <-ticker.C:
+			if err := consumer.ExtendLock(ctx, t.message, queue.DefaultLockTTL); err != nil {
+				log.Printf("Warning: failed to extend lock for %s: %v", t.message, err)
+			} else {
+				log.Printf("%s: lock extended", t.message)
+			}
+		case <-cancelTicker.C:
+			cancelled, err := consumer.IsCancelled(ctx, t.jobID)
+			if err != nil {
+				log.Printf("Warning: failed to check cancellation for job %s: %v", t.jobID, err)
+			} else if cancelled {
+				log.Printf("Job %s: cancellation requested, stopping", t.jobID)
+				cancel(errJobCancelled)
+				return
+			}
+		}
+	}
+}
+
+// cleanupCancelledJob removes any outputs, and the chunks of a chunked job, a cancelled job had already uploaded.
+// Multipart uploads still open under them, such as another worker's chunk, are aborted first.
+func cleanupCancelledJob(ctx context.Context, store *storage.Storage, jobID string) {
+	for _, prefix := range []string{fmt.Sprintf("outputs/%s/", jobID), chunksPrefix(jobID)} {
+		abortIncompleteUploads(ctx, store, jobID, prefix)
+		if err := store.DeletePrefix(ctx, prefix); err != nil {
+			log.Printf("Job %s: failed to clean up partial outputs under %s: %v", jobID, prefix, err)
+			continue
+		}
+		log.Printf("Job %s: removed partial outputs under %s", jobID, prefix)
+	}
+}
+
+// abortIncompleteUploads aborts the multipart uploads left open under prefix. Upload aborts its
+// own upload when it fails; this catches uploads of a worker that died or lost its connection.
+func abortIncompleteUploads(ctx context.Context, store *storage.Storage, jobID, prefix string) {
+	if err := store.AbortUploads(ctx, prefix); err != nil {
+		log.Printf("Job %s: failed to abort incomplete uploads under %s: %v", jobID, prefix, err)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/queue"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/packages/jobqueue"
+)
+
+// newTestConsumer returns a consumer backed by an in-memory Redis
+func newTestConsumer(t *testing.T) (*queue.Consumer, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	consumer, err := queue.NewConsumer(mr.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { consumer.Close() })
+	return consumer, mr
+}
+
+func TestWatchTaskCancels(t *testing.T) {
+	ctx := context.Background()
+	consumer, _ := newTestConsumer(t)
+	running := task{kind: taskJob, jobID: "job-1", message: "job-1"}
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	done := make(chan struct{})
+	go func() {
+		watchTask(ctx, jobCtx, cancel, consumer, running, time.Hour, 10*time.Millisecond)
+		close(done)
+	}()
+
+	// Another job's cancellation is ignored
+	if err := jobqueue.Cancel(ctx, consumer.Client(), "job-2"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if jobCtx.Err() != nil {
+		t.Fatal("job context cancelled by another job's cancellation")
+	}
+
+	if err := jobqueue.Cancel(ctx, consumer.Client(), "job-1"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watchTask did not stop after the job was cancelled")
+	}
+	if cause := context.Cause(jobCtx); !errors.Is(cause, errJobCancelled) {
+		t.Errorf("job context cause = %v, want %v", cause, errJobCancelled)
+	}
+}
+
+func TestWatchTaskExtendsLock(t *testing.T) {
+	ctx := context.Background()
+	consumer, mr := newTestConsumer(t)
+	running := task{kind: taskChunk, jobID: "job-1", chunk: 3, message: queue.ChunkTask("job-1", 3)}
+
+	if ok, err := consumer.Lock(ctx, running.message); err != nil || !ok {
+		t.Fatalf("Lock() = %t, %v", ok, err)
+	}
+	mr.FastForward(queue.DefaultLockTTL - time.Minute)
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	go func() {
+		watchTask(ctx, jobCtx, cancel, consumer, running, 10*time.Millisecond, time.Hour)
+		close(done)
+	}()
+	deadline := time.Now().Add(time.Second)
+	for mr.TTL(queue.LockKeyPrefix+running.message) != queue.DefaultLockTTL && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if ttl := mr.TTL(queue.LockKeyPrefix + running.message); ttl != queue.DefaultLockTTL {
+		t.Errorf("lock TTL = %v, want it extended to %v", ttl, queue.DefaultLockTTL)
+	}
+
+	// The watcher stops with the task, without a cancellation cause of its own
+	cancel(nil)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watchTask did not stop with the task")
+	}
+	if errors.Is(context.Cause(jobCtx), errJobCancelled) {
+		t.Error("task finishing recorded as cancelled")
+	}
+}
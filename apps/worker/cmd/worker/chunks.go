@@ -0,0 +1,455 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/db"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/failure"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/queue"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/storage"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/transcoder"
+)
+
+// defaultChunkSeconds is the chunk length used when a chunked job does not set one
+const defaultChunkSeconds = 60
+
+// Kinds of work carried on the job queue
+const (
+	taskJob    = "job"    // A whole job, or the split of a chunked job
+	taskChunk  = "chunk"  // Encode one chunk of a chunked job into every video rendition
+	taskStitch = "stitch" // Join a chunked job's encoded chunks and finish the job
+)
+
+// errJobChunked is returned once a chunked job's source is split and its chunks are queued.
+// The job stays processing until its chunks are encoded and stitched.
+var errJobChunked = errors.New("job split into chunks")
+
+// task is an entry popped from the job queue
+type task struct {
+	message string // The queue entry, also the name of the task's lock
+	kind    string
+	jobID   string
+	chunk   int // Chunk index of a taskChunk
+}
+
+// parseTask decodes a queue entry: a job ID, "chunk:{job}:{index}" or "stitch:{job}"
+func parseTask(message string) (task, error) {
+	t := task{message: message, kind: taskJob, jobID: message}
+	switch {
+	case strings.HasPrefix(message, queue.ChunkTaskPrefix):
+		jobID, index, ok := strings.Cut(strings.TrimPrefix(message, queue.ChunkTaskPrefix), ":")
+		n, err := strconv.Atoi(index)
+		if !ok || err != nil || n < 0 {
+			return t, fmt.Errorf("invalid chunk task %q", message)
+		}
+		t.kind, t.jobID, t.chunk = taskChunk, jobID, n
+	case strings.HasPrefix(message, queue.StitchTaskPrefix):
+		t.kind, t.jobID = taskStitch, strings.TrimPrefix(message, queue.StitchTaskPrefix)
+	}
+	if _, err := uuid.Parse(t.jobID); err != nil {
+		return t, fmt.Errorf("invalid job ID in %q", message)
+	}
+	return t, nil
+}
+
+// chunksPrefix returns the S3 prefix holding a chunked job's intermediate files.
+// It is removed once the job has been stitched.
+func chunksPrefix(jobID string) string {
+	return fmt.Sprintf("chunks/%s/", jobID)
+}
+
+// sourceChunkKey returns the S3 key of a stream-copied source chunk
+func sourceChunkKey(jobID string, index int) string {
+	return fmt.Sprintf("%ssource/chunk_%04d.mkv", chunksPrefix(jobID), index)
+}
+
+// encodedChunkKey returns the S3 key of a chunk encoded for one rendition
+func encodedChunkKey(jobID, resolution string, index int) string {
+	return fmt.Sprintf("%s%s/chunk_%04d.mkv", chunksPrefix(jobID), resolution, index)
+}
+
+// chunkedInputKey returns where the stitch finds a chunked job's input: the upload itself, or
+// the edited input the split uploads when the job trims or joins its inputs
+func chunkedInputKey(jobID, inputKey string, opts jobOptions) string {
+	if opts.Trim == nil && len(opts.ConcatKeys) == 0 {
+		return inputKey
+	}
+	return chunksPrefix(jobID) + "input.mkv"
+}
+
+// splitJob cuts a chunked job's input into keyframe-aligned chunks, uploads them, records them
+// and queues a task per chunk. Chunks are queued only once all of them are recorded, so the
+// last chunk to finish always sees the full set.
+func splitJob(ctx context.Context, queries *db.Queries, store *storage.Storage, consumer *queue.Consumer, jobID pgtype.UUID,
+	jobIDStr, tempDir, inputPath, inputKey string, opts jobOptions) error {
+
+	chunkSeconds := opts.Chunked.ChunkSeconds
+	if chunkSeconds <= 0 {
+		chunkSeconds = defaultChunkSeconds
+	}
+	chunkDir := filepath.Join(tempDir, "chunks")
+	if err := os.MkdirAll(chunkDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create chunks dir: %w", err)
+	}
+
+	log.Printf("Job %s: splitting input into %gs chunks", jobIDStr, chunkSeconds)
+	chunks, err := transcoder.SplitChunks(ctx, inputPath, chunkDir, chunkSeconds)
+	if err != nil {
+		return fmt.Errorf("failed to split input: %w", err)
+	}
+
+	// The stitch takes its audio from the input, so an edited input is kept for it
+	if key := chunkedInputKey(jobIDStr, inputKey, opts); key != inputKey {
+		if err := store.Upload(ctx, inputPath, key); err != nil {
+			return fmt.Errorf("failed to upload edited input: %w", err)
+		}
+	}
+	for i, c := range chunks {
+		if err := store.Upload(ctx, c.Path, sourceChunkKey(jobIDStr, i)); err != nil {
+			return fmt.Errorf("failed to upload chunk %d: %w", i, err)
+		}
+	}
+
+	// A retried split replaces the chunks of the earlier attempt
+	if err := queries.DeleteJobChunks(ctx, jobID); err != nil {
+		return fmt.Errorf("failed to clear previous chunks: %w", err)
+	}
+	for i, c := range chunks {
+		if _, err := queries.CreateJobChunk(ctx, db.CreateJobChunkParams{
+			JobID:           jobID,
+			ChunkIndex:      int32(i),
+			SourceKey:       sourceChunkKey(jobIDStr, i),
+			StartSeconds:    c.StartSeconds,
+			DurationSeconds: c.DurationSeconds,
+		}); err != nil {
+			return fmt.Errorf("failed to record chunk %d: %w", i, err)
+		}
+	}
+	for i := range chunks {
+		if err := consumer.Push(ctx, queue.ChunkTask(jobIDStr, i)); err != nil {
+			return fmt.Errorf("failed to queue chunk %d: %w", i, err)
+		}
+	}
+
+	log.Printf("Job %s: queued %d chunks", jobIDStr, len(chunks))
+	return nil
+}
+
+// processChunk encodes one chunk of a chunked job into every video rendition and uploads the
+// results. The worker that completes the job's last chunk queues the stitch.
+func processChunk(ctx context.Context, queries *db.Queries, store *storage.Storage, consumer *queue.Consumer, budget encodeBudget, t task) error {
+	workerID := consumer.WorkerID()
+	jobUUID, err := uuid.Parse(t.jobID)
+	if err != nil {
+		return fmt.Errorf("invalid job ID: %w", err)
+	}
+	pgUUID := pgtype.UUID{Bytes: jobUUID, Valid: true}
+
+	job, err := queries.GetJob(ctx, pgUUID)
+	if err != nil {
+		return fmt.Errorf("failed to get job: %w", err)
+	}
+	if job.Status != db.JobStatusProcessing {
+		log.Printf("Job %s: job is %s, skipping chunk %d", t.jobID, job.Status, t.chunk)
+		return nil
+	}
+
+	chunk, err := queries.StartJobChunk(ctx, db.StartJobChunkParams{
+		JobID:      pgUUID,
+		ChunkIndex: int32(t.chunk),
+		WorkerID:   &workerID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Already encoded, being encoded elsewhere, or dropped by a newer split
+		log.Printf("Job %s: chunk %d is not pending, skipping", t.jobID, t.chunk)
+		return queueStitchWhenDone(ctx, queries, consumer, pgUUID, t.jobID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to claim chunk %d: %w", t.chunk, err)
+	}
+	log.Printf("Job %s: encoding chunk %d (%.1fs at %.1fs, attempt %d, worker: %s)",
+		t.jobID, chunk.ChunkIndex, chunk.DurationSeconds, chunk.StartSeconds, chunk.Attempts, workerID)
+
+	if err := encodeChunk(ctx, queries, store, consumer, budget, t.jobID, job, chunk); err != nil {
+		errMsg := err.Error()
+		if _, dbErr := queries.FailJobChunk(ctx, db.FailJobChunkParams{
+			ID:           chunk.ID,
+			ErrorMessage: &errMsg,
+		}); dbErr != nil {
+			log.Printf("Job %s: failed to mark chunk %d as failed: %v", t.jobID, chunk.ChunkIndex, dbErr)
+		}
+		return err
+	}
+	if _, err := queries.CompleteJobChunk(ctx, chunk.ID); err != nil {
+		return fmt.Errorf("failed to mark chunk %d as completed: %w", chunk.ChunkIndex, err)
+	}
+	log.Printf("Job %s: chunk %d completed", t.jobID, chunk.ChunkIndex)
+
+	return queueStitchWhenDone(ctx, queries, consumer, pgUUID, t.jobID)
+}
+
+// queueStitchWhenDone queues the job's stitch once none of its chunks is left to encode.
+// Chunks finishing at the same time may both queue it; the stitch runs only once.
+func queueStitchWhenDone(ctx context.Context, queries *db.Queries, consumer *queue.Consumer, jobID pgtype.UUID, jobIDStr string) error {
+	remaining, err := queries.CountUnfinishedJobChunks(ctx, jobID)
+	if err != nil {
+		return fmt.Errorf("failed to count remaining chunks: %w", err)
+	}
+	if remaining > 0 {
+		return nil
+	}
+	if err := consumer.Push(ctx, queue.StitchTask(jobIDStr)); err != nil {
+		return fmt.Errorf("failed to queue stitch: %w", err)
+	}
+	log.Printf("Job %s: all chunks encoded, stitch queued", jobIDStr)
+	return nil
+}
+
+// encodeChunk does the work behind processChunk: the chunk is encoded into each video rendition,
+// video only, with the same profile settings, corrections and watermark a whole-file encode uses.
+// Renditions run in parallel within the worker's CPU budget; any failure fails the chunk.
+func encodeChunk(ctx context.Context, queries *db.Queries, store *storage.Storage, consumer *queue.Consumer, budget encodeBudget,
+	jobIDStr string, job db.Job, chunk db.JobChunk) error {
+	opts, err := parseJobOptions(job.Options)
+	if err != nil {
+		return err
+	}
+
+	tempDir, err := os.MkdirTemp("", fmt.Sprintf("chunk-%s-%d", jobIDStr, chunk.ChunkIndex))
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	chunkPath := filepath.Join(tempDir, "source.mkv")
+	if err := store.Download(ctx, chunk.SourceKey, chunkPath); err != nil {
+		return fmt.Errorf("failed to download chunk: %w", err)
+	}
+	source, err := reprobeInput(ctx, queries, job.ID, chunkPath)
+	if err != nil {
+		return err
+	}
+
+	renditions, err := queries.GetRenditionsByJobID(ctx, job.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get renditions: %w", err)
+	}
+	watermarks, err := prepareWatermarks(ctx, queries, store, tempDir, renditions, opts)
+	if err != nil {
+		return err
+	}
+
+	// Progress weighs this chunk by its share of the job's duration
+	chunks, err := queries.GetJobChunks(ctx, job.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get chunks: %w", err)
+	}
+	var totalSeconds float64
+	for _, c := range chunks {
+		totalSeconds += c.DurationSeconds
+	}
+
+	errs := make([]error, len(renditions))
+	sem := make(chan struct{}, budget.Concurrency)
+	var wg sync.WaitGroup
+	for i, r := range renditions {
+		wg.Add(1)
+		go func(i int, r db.Rendition) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			profile, err := resolveProfile(ctx, queries, r.Resolution)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			if profile.AudioOnly {
+				return // Encoded from the whole input when the job is stitched
+			}
+			if r.TargetBitrateKbps != nil {
+				profile = applyTargetBitrate(profile, int(*r.TargetBitrateKbps))
+			}
+			deinterlace, toneMap := profile.Corrections(source)
+
+			// Chunks are Matroska whatever the profile's container; the stitch muxes them into it
+			profile.Container = "mkv"
+			outputPath := filepath.Join(tempDir, r.Resolution+".mkv")
+			start := time.Now()
+			if err := transcoder.TranscodeWithProfile(ctx, chunkPath, outputPath, profile, transcoder.Options{
+				Duration:       time.Duration(chunk.DurationSeconds * float64(time.Second)),
+				OnProgress:     chunkProgressReporter(ctx, consumer, jobIDStr, r.Resolution, int(chunk.ChunkIndex), chunk.DurationSeconds, totalSeconds),
+				Threads:        budget.Threads,
+				FrameRate:      source.FrameRate,
+				Watermark:      watermarks[r.Resolution],
+				Deinterlace:    deinterlace,
+				ToneMap:        toneMap,
+				VideoOnly:      true,
+				KeyframeOffset: chunk.StartSeconds,
+			}); err != nil {
+				errs[i] = fmt.Errorf("failed to encode rendition %s: %w", r.Resolution, err)
+				return
+			}
+			log.Printf("Job %s: chunk %d encoded to %s in %v", jobIDStr, chunk.ChunkIndex, r.Resolution, time.Since(start).Round(time.Millisecond))
+
+			if err := store.Upload(ctx, outputPath, encodedChunkKey(jobIDStr, r.Resolution, int(chunk.ChunkIndex))); err != nil {
+				errs[i] = fmt.Errorf("failed to upload rendition %s: %w", r.Resolution, err)
+			}
+		}(i, r)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// processStitch finishes a chunked job once every chunk is encoded. Video renditions are stitched
+// from their chunks with the input's audio, audio-only renditions are encoded from the input,
+// and the job is then packaged and completed like any other. Its chunks are deleted afterwards.
+func processStitch(ctx context.Context, queries *db.Queries, store *storage.Storage, consumer *queue.Consumer, budget encodeBudget, t task) error {
+	workerID := consumer.WorkerID()
+	jobUUID, err := uuid.Parse(t.jobID)
+	if err != nil {
+		return fmt.Errorf("invalid job ID: %w", err)
+	}
+	pgUUID := pgtype.UUID{Bytes: jobUUID, Valid: true}
+
+	job, err := queries.StartJobStitch(ctx, db.StartJobStitchParams{
+		ID:       pgUUID,
+		WorkerID: &workerID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("Job %s: not ready to stitch or already finished, skipping", t.jobID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to claim job for stitching: %w", err)
+	}
+	log.Printf("Job %s: stitching (worker: %s)", t.jobID, workerID)
+
+	opts, err := parseJobOptions(job.Options)
+	if err != nil {
+		return markJobFailed(ctx, queries, pgUUID, err)
+	}
+	chunks, err := queries.GetJobChunks(ctx, pgUUID)
+	if err != nil {
+		return markJobFailed(ctx, queries, pgUUID, fmt.Errorf("failed to get chunks: %w", err))
+	}
+
+	tempDir, err := os.MkdirTemp("", "stitch-"+t.jobID)
+	if err != nil {
+		return markJobFailed(ctx, queries, pgUUID, fmt.Errorf("failed to create temp dir: %w", err))
+	}
+	defer os.RemoveAll(tempDir)
+
+	inputPath, err := downloadInput(ctx, store, t.jobID, tempDir, "input", chunkedInputKey(t.jobID, job.InputKey, opts))
+	if err != nil {
+		return markJobFailed(ctx, queries, pgUUID, err)
+	}
+	source, err := reprobeInput(ctx, queries, pgUUID, inputPath)
+	if err != nil {
+		return markJobFailed(ctx, queries, pgUUID, err)
+	}
+
+	renditions, err := queries.GetRenditionsByJobID(ctx, pgUUID)
+	if err != nil {
+		return markJobFailed(ctx, queries, pgUUID, fmt.Errorf("failed to get renditions: %w", err))
+	}
+
+	// The split already published the WebVTT tracks; renditions still carry the text streams
+	var subtitles subtitlePlan
+	for _, s := range source.Subtitles {
+		if s.IsText() {
+			subtitles.carry = append(subtitles.carry, s.Index)
+		}
+	}
+
+	inputBase := filepath.Base(job.InputKey)
+	encoder := &renditionEncoder{
+		queries:        queries,
+		store:          store,
+		consumer:       consumer,
+		jobID:          t.jobID,
+		tempDir:        tempDir,
+		inputPath:      inputPath,
+		inputName:      strings.TrimSuffix(inputBase, filepath.Ext(inputBase)),
+		opts:           opts,
+		source:         source,
+		sourceDuration: time.Duration(source.DurationSeconds * float64(time.Second)),
+		subtitles:      subtitles,
+		threads:        budget.Threads,
+		chunks:         chunks,
+	}
+	if err := encoder.finishJob(ctx, pgUUID, renditions, budget.Concurrency); err != nil {
+		return err
+	}
+
+	if err := store.DeletePrefix(ctx, chunksPrefix(t.jobID)); err != nil {
+		log.Printf("Job %s: failed to remove chunks: %v", t.jobID, err)
+	}
+	return nil
+}
+
+// handleChunkFailure retries a failed chunk on its own, with the same backoff as jobs. A chunk
+// that fails permanently, or more often than the job's retry limit, fails the job, which is
+// moved to the dead letter queue.
+func handleChunkFailure(ctx context.Context, queries *db.Queries, store *storage.Storage, consumer *queue.Consumer, t task, chunkErr error) {
+	jobUUID, err := uuid.Parse(t.jobID)
+	if err != nil {
+		log.Printf("Invalid job ID for chunk retry: %s", t.jobID)
+		return
+	}
+	pgUUID := pgtype.UUID{Bytes: jobUUID, Valid: true}
+
+	job, err := queries.GetJob(ctx, pgUUID)
+	if err != nil {
+		log.Printf("Failed to get job %s for chunk retry handling: %v", t.jobID, err)
+		return
+	}
+	chunk, err := queries.GetJobChunk(ctx, db.GetJobChunkParams{
+		JobID:      pgUUID,
+		ChunkIndex: int32(t.chunk),
+	})
+	if err != nil {
+		log.Printf("Failed to get chunk %d of job %s for retry handling: %v", t.chunk, t.jobID, err)
+		return
+	}
+
+	code, permanent := failure.Classify(chunkErr)
+	if permanent || chunk.Attempts > job.MaxRetries {
+		jobErr := fmt.Errorf("chunk %d failed: %w", t.chunk, chunkErr)
+		if permanent {
+			log.Printf("Job %s: chunk %d failed permanently (%s), failing the job", t.jobID, t.chunk, code)
+		} else {
+			log.Printf("Job %s: chunk %d failed %d times, failing the job", t.jobID, t.chunk, chunk.Attempts)
+			jobErr = fmt.Errorf("chunk %d failed after %d attempts: %w", t.chunk, chunk.Attempts, chunkErr)
+		}
+		markJobFailed(ctx, queries, pgUUID, jobErr)
+		if err := consumer.PushDeadLetter(ctx, t.message); err != nil {
+			log.Printf("Failed to push job %s to dead letter queue: %v", t.jobID, err)
+		}
+		if err := store.DeletePrefix(ctx, chunksPrefix(t.jobID)); err != nil {
+			log.Printf("Job %s: failed to remove chunks: %v", t.jobID, err)
+		}
+		return
+	}
+
+	delay := retryDelays[min(max(int(chunk.Attempts)-1, 0), len(retryDelays)-1)]
+	log.Printf("Job %s: chunk %d failed, scheduling retry %d/%d in %v", t.jobID, t.chunk, chunk.Attempts, job.MaxRetries, delay)
+
+	if err := consumer.PushDelayed(ctx, t.message, delay); err != nil {
+		log.Printf("Failed to schedule retry of %s: %v", t.message, err)
+	}
+}
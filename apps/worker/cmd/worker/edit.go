@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/failure"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/storage"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/transcoder"
+)
+
+// prepareInput downloads a job's input and applies the requested edits, returning the path
+// of the file the renditions are encoded from. Concat jobs download every input and join them
+// in order; a trim range is then applied to the (joined) timeline.
+func prepareInput(ctx context.Context, store *storage.Storage, jobIDStr, tempDir, inputKey string, opts jobOptions) (string, error) {
+	var inputPath string
+	var err error
+	if len(opts.ConcatKeys) > 0 {
+		inputPath, err = concatInputs(ctx, store, jobIDStr, tempDir, opts.ConcatKeys)
+	} else {
+		inputPath, err = downloadInput(ctx, store, jobIDStr, tempDir, "input", inputKey)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if opts.Trim == nil {
+		return inputPath, nil
+	}
+
+	source, err := transcoder.Probe(ctx, inputPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to probe input for trimming: %w", err)
+	}
+	start, end := opts.Trim.StartSeconds, opts.Trim.EndSeconds
+	if source.DurationSeconds > 0 && start >= source.DurationSeconds {
+		return "", failure.Permanent(failure.CodeInvalidOptions,
+			fmt.Errorf("trim start %.3fs is beyond the input's duration (%.3fs)", start, source.DurationSeconds))
+	}
+	if source.DurationSeconds > 0 && end >= source.DurationSeconds {
+		end = 0 // Past the end: keep the rest of the input
+	}
+
+	log.Printf("Job %s: trimming input to %.3fs-%.3fs", jobIDStr, start, end)
+	trimmedPath := filepath.Join(tempDir, "trimmed.mkv")
+	if err := transcoder.Trim(ctx, inputPath, trimmedPath, start, end, source.Subtitles); err != nil {
+		return "", err
+	}
+	os.Remove(inputPath)
+	return trimmedPath, nil
+}
+
+// downloadInput fetches an uploaded file to tempDir, keeping its extension so FFmpeg can detect the format
+func downloadInput(ctx context.Context, store *storage.Storage, jobIDStr, tempDir, name, key string) (string, error) {
+	ext := filepath.Ext(key)
+	if ext == "" {
+		ext = ".mp4" // Default extension
+	}
+	localPath := filepath.Join(tempDir, name+ext)
+
+	log.Printf("Job %s: downloading input file from %s", jobIDStr, key)
+	if err := store.Download(ctx, key, localPath); err != nil {
+		return "", fmt.Errorf("failed to download input %s: %w", key, err)
+	}
+	return localPath, nil
+}
+
+// concatInputs downloads the inputs of a concat job and joins them into one file.
+// The output takes the first input's display size and frame rate; the others are fitted to it.
+func concatInputs(ctx context.Context, store *storage.Storage, jobIDStr, tempDir string, keys []string) (string, error) {
+	segments := make([]transcoder.ConcatSegment, 0, len(keys))
+	var width, height int
+	var frameRate float64
+	for i, key := range keys {
+		localPath, err := downloadInput(ctx, store, jobIDStr, tempDir, fmt.Sprintf("part_%d", i), key)
+		if err != nil {
+			return "", err
+		}
+		info, err := transcoder.Probe(ctx, localPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to probe input %s: %w", key, err)
+		}
+		if i == 0 {
+			width, height = displaySize(info)
+			frameRate = info.FrameRate
+		}
+		segments = append(segments, transcoder.ConcatSegment{
+			Path:            localPath,
+			DurationSeconds: info.DurationSeconds,
+			HasVideo:        info.VideoCodec != "",
+			HasAudio:        info.AudioCodec != "",
+		})
+	}
+
+	log.Printf("Job %s: joining %d inputs at %dx%d", jobIDStr, len(segments), width, height)
+	outputPath := filepath.Join(tempDir, "concat.mkv")
+	if err := transcoder.Concat(ctx, segments, outputPath, width, height, frameRate); err != nil {
+		return "", err
+	}
+	for _, s := range segments {
+		os.Remove(s.Path)
+	}
+	return outputPath, nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -13,11 +14,13 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/config"
 	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/db"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/failure"
 	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/metrics"
 	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/queue"
 	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/storage"
@@ -31,6 +34,9 @@ var retryDelays = []time.Duration{
 	60 * time.Second,
 }
 
+// promoteInterval is how often due retries are moved from the delayed set to the queue
+const promoteInterval = time.Second
+
 func main() {
 	log.Println("Worker starting...")
 
@@ -67,6 +73,16 @@ func main() {
 	defer consumer.Close()
 	log.Printf("Connected to Redis (Worker ID: %s)", consumer.WorkerID())
 
+	// Register before the first pop so a crash never leaves an entry in an unknown processing list
+	if err := consumer.Heartbeat(ctx); err != nil {
+		log.Fatalf("Failed to register worker: %v", err)
+	}
+	defer func() {
+		if err := consumer.Deregister(context.Background()); err != nil {
+			log.Printf("Failed to deregister worker: %v", err)
+		}
+	}()
+
 	// Initialize S3/MinIO storage client
 	storageClient, err := storage.New(storage.Config{
 		Endpoint:     cfg.S3Endpoint,
@@ -75,17 +91,36 @@ func main() {
 		Bucket:       cfg.S3Bucket,
 		Region:       cfg.S3Region,
 		UsePathStyle: cfg.S3UsePathStyle,
+		PartSize:     int64(cfg.S3PartSizeMB) << 20,
+		Concurrency:  cfg.S3TransferConcurrency,
 	})
 	if err != nil {
 		log.Fatalf("Failed to initialize storage client: %v", err)
 	}
 	log.Println("Connected to S3/MinIO")
 
+	// Split the CPU budget across renditions encoded in parallel
+	budget := encodeBudget{
+		Concurrency: cfg.RenditionConcurrency,
+		Threads:     cfg.ThreadsPerRendition(),
+	}
+	log.Printf("Encoding up to %d renditions in parallel with %d FFmpeg threads each", budget.Concurrency, budget.Threads)
+
 	// Start metrics server on port 9091
 	metricsServer := metrics.StartMetricsServer("9091")
 
 	// Start queue depth updater goroutine
-	go metrics.StartQueueDepthUpdater(ctx, consumer.Client(), consumer.QueueKey(), 10*time.Second)
+	go metrics.StartQueueDepthUpdater(ctx, consumer.Client(), consumer.QueueKey(), queue.DelayedQueueKey, 10*time.Second)
+
+	// Move retries whose backoff has passed into the queue
+	go consumer.StartPromoter(ctx, promoteInterval)
+
+	// Keep this worker alive in Redis and return entries popped by dead workers to the queue
+	go consumer.StartHeartbeat(ctx, heartbeatInterval)
+	go startRecoverySweep(ctx, queries, consumer, recoveryInterval)
+
+	// Requeue jobs left processing by workers that died; one worker at a time does this
+	go startReaper(ctx, queries, storageClient, consumer, reaperInterval)
 
 	// Handle shutdown signals
 	quit := make(chan os.Signal, 1)
@@ -110,7 +145,7 @@ func main() {
 			return
 		default:
 			// Try to get a job from the queue
-			jobID, err := consumer.Pop(ctx)
+			message, err := consumer.Pop(ctx)
 			if err != nil {
 				if ctx.Err() != nil {
 					// Context cancelled, exit gracefully
@@ -120,85 +155,145 @@ func main() {
 				continue
 			}
 
-			if jobID == "" {
+			if message == "" {
 				// No job available, continue polling
 				continue
 			}
 
-			// Try to acquire distributed lock for this job
-			locked, err := consumer.Lock(ctx, jobID)
+			// Entries are job IDs or the chunk and stitch tasks of chunked jobs
+			t, err := parseTask(message)
+			if err != nil {
+				log.Printf("Skipping queue entry: %v", err)
+				ack(ctx, consumer, message)
+				continue
+			}
+
+			// Skip copies of jobs that were cancelled while queued or awaiting retry
+			cancelled, err := consumer.IsCancelled(ctx, t.jobID)
 			if err != nil {
-				log.Printf("Error acquiring lock for job %s: %v", jobID, err)
+				log.Printf("Error checking cancellation for job %s: %v", t.jobID, err)
+			} else if cancelled {
+				log.Printf("Job %s was cancelled, skipping %s", t.jobID, t.message)
+				ack(ctx, consumer, t.message)
+				continue
+			}
+
+			// Try to acquire distributed lock for this task
+			locked, err := consumer.Lock(ctx, t.message)
+			if err != nil {
+				log.Printf("Error acquiring lock for %s: %v", t.message, err)
+				// Hand the entry back rather than dropping it
+				if err := consumer.Requeue(ctx, t.message); err != nil {
+					log.Printf("Warning: %v", err)
+				}
 				continue
 			}
 			if !locked {
-				// Another worker already has this job, skip it
-				log.Printf("Job %s already locked by another worker, skipping", jobID)
+				// Another worker already has this task, skip it
+				log.Printf("%s already locked by another worker, skipping", t.message)
+				ack(ctx, consumer, t.message)
 				continue
 			}
 
-			// Process the job with metrics and lock extension
+			// Process the task with metrics and lock extension
 			metrics.IncrementActiveJobs()
-			err = processJobWithLock(ctx, queries, storageClient, consumer, jobID)
-			if err != nil {
-				log.Printf("Error processing job %s: %v", jobID, err)
+			err = processTaskWithLock(ctx, queries, storageClient, consumer, budget, t)
+			if ctx.Err() != nil {
+				// Shutting down: the entry stays unacknowledged and another worker recovers it
+				log.Printf("%s interrupted by shutdown, leaving it for recovery", t.message)
+				metrics.DecrementActiveJobs()
+				continue
+			}
+			switch {
+			case errors.Is(err, errJobCancelled):
+				log.Printf("Job %s: cancelled", t.jobID)
+				if t.kind != taskChunk {
+					metrics.RecordJobCancelled()
+				}
+			case errors.Is(err, errJobChunked):
+				log.Printf("Job %s: split into chunks", t.jobID)
+			case err != nil && t.kind == taskChunk:
+				log.Printf("Error processing %s: %v", t.message, err)
+				metrics.RecordChunkFailed()
+				// Only the chunk is retried, not its job
+				handleChunkFailure(ctx, queries, storageClient, consumer, t, err)
+			case err != nil:
+				log.Printf("Error processing job %s: %v", t.jobID, err)
 				metrics.RecordJobFailed()
 				// Handle retry logic
-				handleJobFailure(ctx, queries, consumer, jobID, err)
-			} else {
+				handleJobFailure(ctx, queries, consumer, t, err)
+			case t.kind == taskChunk:
+				metrics.RecordChunkCompleted()
+			default:
 				metrics.RecordJobCompleted()
 			}
 			metrics.DecrementActiveJobs()
 
 			// Release the lock
-			if unlockErr := consumer.Unlock(ctx, jobID); unlockErr != nil {
-				log.Printf("Warning: failed to release lock for job %s: %v", jobID, unlockErr)
+			if unlockErr := consumer.Unlock(ctx, t.message); unlockErr != nil {
+				log.Printf("Warning: failed to release lock for %s: %v", t.message, unlockErr)
 			}
+			ack(ctx, consumer, t.message)
 		}
 	}
 }
 
-// processJobWithLock wraps processJob with a lock extension goroutine
+// processTaskWithLock runs a queue task with a lock extension goroutine
 // to prevent lock expiration during long-running transcodes
-func processJobWithLock(ctx context.Context, queries *db.Queries, store *storage.Storage, consumer *queue.Consumer, jobID string) error {
-	// Create a context that we can cancel when the job completes
-	jobCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
+func processTaskWithLock(ctx context.Context, queries *db.Queries, store *storage.Storage, consumer *queue.Consumer, budget encodeBudget, t task) error {
+	// Create a context that we can cancel when the job completes or is cancelled.
+	// Cancelling it kills any running FFmpeg process.
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
 
-	// Start a goroutine to extend the lock periodically
+	// Start a goroutine to extend the lock periodically and watch for cancellation
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		ticker := time.NewTicker(2 * time.Minute) // Extend every 2 minutes (lock TTL is 5 minutes)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-jobCtx.Done():
-				return
-			case <-ticker.C:
-				if err := consumer.ExtendLock(ctx, jobID, queue.DefaultLockTTL); err != nil {
-					log.Printf("Warning: failed to extend lock for job %s: %v", jobID, err)
-				} else {
-					log.Printf("Job %s: lock extended", jobID)
-				}
-			}
-		}
+		watchTask(ctx, jobCtx, cancel, consumer, t, lockExtendInterval, cancelPollInterval)
 	}()
 
-	// Process the job
-	err := processJob(jobCtx, queries, store, consumer.WorkerID(), jobID)
+	// Process the task
+	var err error
+	switch t.kind {
+	case taskChunk:
+		err = processChunk(jobCtx, queries, store, consumer, budget, t)
+	case taskStitch:
+		err = processStitch(jobCtx, queries, store, consumer, budget, t)
+	default:
+		err = processJob(jobCtx, queries, store, consumer, budget, t.jobID)
+	}
+
+	// A job cancelled just before it finished surfaces as a failed status update,
+	// so re-check the flag rather than treating that as a failure
+	cancelled := errors.Is(context.Cause(jobCtx), errJobCancelled)
+	if !cancelled && err != nil {
+		cancelled, _ = consumer.IsCancelled(ctx, t.jobID)
+	}
 
 	// Stop the lock extension goroutine
-	cancel()
+	cancel(nil)
 	wg.Wait()
 
+	if cancelled {
+		cleanupCancelledJob(ctx, store, t.jobID)
+		return errJobCancelled
+	}
+	// Chunk tasks of a job run on several workers at once and upload under its chunks prefix,
+	// so only a failed job or stitch task sweeps; outputs are written by one task at a time
+	if err != nil && !errors.Is(err, errJobChunked) && t.kind != taskChunk {
+		abortIncompleteUploads(ctx, store, t.jobID, fmt.Sprintf("outputs/%s/", t.jobID))
+		if t.kind == taskJob {
+			abortIncompleteUploads(ctx, store, t.jobID, chunksPrefix(t.jobID))
+		}
+	}
 	return err
 }
 
-// handleJobFailure handles retry logic for failed jobs
-func handleJobFailure(ctx context.Context, queries *db.Queries, consumer *queue.Consumer, jobIDStr string, jobErr error) {
+// handleJobFailure handles retry logic for failed jobs and stitch tasks; the failed task is re-queued
+func handleJobFailure(ctx context.Context, queries *db.Queries, consumer *queue.Consumer, t task, jobErr error) {
+	jobIDStr := t.jobID
 	jobUUID, err := uuid.Parse(jobIDStr)
 	if err != nil {
 		log.Printf("Invalid job ID for retry: %s", jobIDStr)
@@ -214,20 +309,21 @@ func handleJobFailure(ctx context.Context, queries *db.Queries, consumer *queue.
 		return
 	}
 
-	// Check if we should retry
-	if job.RetryCount >= job.MaxRetries {
+	// Permanent failures (a corrupt input, an impossible profile) would fail the same way again
+	code, permanent := failure.Classify(jobErr)
+	if permanent || job.RetryCount >= job.MaxRetries {
 		// Move to dead letter queue
-		log.Printf("Job %s exceeded max retries (%d), moving to dead letter queue", jobIDStr, job.MaxRetries)
-		if err := consumer.PushDeadLetter(ctx, jobIDStr); err != nil {
+		if permanent {
+			log.Printf("Job %s failed permanently (%s), moving to dead letter queue without retrying", jobIDStr, code)
+		} else {
+			log.Printf("Job %s exceeded max retries (%d), moving to dead letter queue", jobIDStr, job.MaxRetries)
+			jobErr = fmt.Errorf("exceeded max retries: %w", jobErr)
+		}
+		if err := consumer.PushDeadLetter(ctx, t.message); err != nil {
 			log.Printf("Failed to push job %s to dead letter queue: %v", jobIDStr, err)
 		}
 		// Mark as failed in database
-		errMsg := fmt.Sprintf("exceeded max retries: %v", jobErr)
-		queries.UpdateJobStatus(ctx, db.UpdateJobStatusParams{
-			ID:           pgUUID,
-			Status:       db.JobStatusFailed,
-			ErrorMessage: &errMsg,
-		})
+		markJobFailed(ctx, queries, pgUUID, jobErr)
 		return
 	}
 
@@ -247,18 +343,14 @@ func handleJobFailure(ctx context.Context, queries *db.Queries, consumer *queue.
 
 	log.Printf("Job %s failed, scheduling retry %d/%d in %v", jobIDStr, job.RetryCount+1, job.MaxRetries, delay)
 
-	// Schedule retry after delay (in a goroutine to not block the worker)
-	go func() {
-		time.Sleep(delay)
-		if err := consumer.Push(context.Background(), jobIDStr); err != nil {
-			log.Printf("Failed to re-queue job %s for retry: %v", jobIDStr, err)
-		} else {
-			log.Printf("Job %s re-queued for retry", jobIDStr)
-		}
-	}()
+	// Park the retry in the delayed set; a promoter on any worker queues it once due
+	if err := consumer.PushDelayed(ctx, t.message, delay); err != nil {
+		log.Printf("Failed to schedule retry of job %s: %v", jobIDStr, err)
+	}
 }
 
-func processJob(ctx context.Context, queries *db.Queries, store *storage.Storage, workerID string, jobIDStr string) error {
+func processJob(ctx context.Context, queries *db.Queries, store *storage.Storage, consumer *queue.Consumer, budget encodeBudget, jobIDStr string) error {
+	workerID := consumer.WorkerID()
 	log.Printf("Processing job: %s (worker: %s)", jobIDStr, workerID)
 
 	// Parse job ID
@@ -277,12 +369,22 @@ func processJob(ctx context.Context, queries *db.Queries, store *storage.Storage
 		ID:       pgUUID,
 		WorkerID: &workerID,
 	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Finished, cancelled, or being processed by a live worker
+		log.Printf("Job %s: not claimable, skipping", jobIDStr)
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to claim job for processing: %w", err)
 	}
 
 	log.Printf("Job %s: claimed for processing, input_key=%s", jobIDStr, job.InputKey)
 
+	opts, err := parseJobOptions(job.Options)
+	if err != nil {
+		return markJobFailed(ctx, queries, pgUUID, err)
+	}
+
 	// Create temp directory for this job
 	tempDir, err := os.MkdirTemp("", "transcode-"+jobIDStr)
 	if err != nil {
@@ -290,20 +392,23 @@ func processJob(ctx context.Context, queries *db.Queries, store *storage.Storage
 	}
 	defer os.RemoveAll(tempDir) // Clean up temp files
 
-	// Get file extension from input key
-	ext := filepath.Ext(job.InputKey)
-	if ext == "" {
-		ext = ".mp4" // Default extension
+	// Download the input from S3, joining and trimming it when the job asks for it
+	inputPath, err := prepareInput(ctx, store, jobIDStr, tempDir, job.InputKey, opts)
+	if err != nil {
+		return markJobFailed(ctx, queries, pgUUID, err)
 	}
+	log.Printf("Job %s: input file ready", jobIDStr)
 
-	inputPath := filepath.Join(tempDir, "input"+ext)
-
-	// Download input file from S3
-	log.Printf("Job %s: downloading input file from %s", jobIDStr, job.InputKey)
-	if err := store.Download(ctx, job.InputKey, inputPath); err != nil {
-		return markJobFailed(ctx, queries, pgUUID, fmt.Errorf("failed to download input: %w", err))
+	// Inspect the input before transcoding and record what we received
+	source, err := probeInput(ctx, queries, pgUUID, inputPath)
+	if err != nil {
+		return markJobFailed(ctx, queries, pgUUID, err)
 	}
-	log.Printf("Job %s: input file downloaded", jobIDStr)
+	log.Printf("Job %s: source is %s, %dx%d, video=%s audio=%s, %.1fs, scan=%s transfer=%s",
+		jobIDStr, source.Container, source.Width, source.Height, source.VideoCodec, source.AudioCodec, source.DurationSeconds,
+		source.FieldOrder, source.ColorTransfer)
+
+	sourceDuration := time.Duration(source.DurationSeconds * float64(time.Second))
 
 	// Get renditions
 	renditions, err := queries.GetRenditionsByJobID(ctx, pgUUID)
@@ -311,67 +416,188 @@ func processJob(ctx context.Context, queries *db.Queries, store *storage.Storage
 		return markJobFailed(ctx, queries, pgUUID, fmt.Errorf("failed to get renditions: %w", err))
 	}
 
+	// Per-title analysis tunes the ladder to this source before anything is encoded.
+	// A stored ladder means a previous attempt already applied it to the renditions.
+	if opts.PerTitle && len(job.Ladder) == 0 && source.VideoCodec != "" {
+		log.Printf("Job %s: analysing source complexity for per-title ladder", jobIDStr)
+		renditions, err = planLadder(ctx, queries, pgUUID, jobIDStr, tempDir, inputPath, source, renditions, budget.Threads*budget.Concurrency)
+		if err != nil {
+			return markJobFailed(ctx, queries, pgUUID, err)
+		}
+	}
+
+	// Publish text tracks as WebVTT and decide how renditions carry them
+	var subtitles subtitlePlan
+	if len(opts.Subtitles) > 0 || len(source.Subtitles) > 0 || source.ClosedCaptions {
+		subtitles, err = prepareSubtitles(ctx, queries, store, pgUUID, jobIDStr, tempDir, inputPath, source, opts)
+		if err != nil {
+			return markJobFailed(ctx, queries, pgUUID, err)
+		}
+	}
+
+	// Chunked jobs are encoded by many workers: split the source and hand it over to chunk tasks
+	if opts.Chunked != nil && source.HasVideo() {
+		if err := splitJob(ctx, queries, store, consumer, pgUUID, jobIDStr, tempDir, inputPath, job.InputKey, opts); err != nil {
+			return markJobFailed(ctx, queries, pgUUID, err)
+		}
+		return errJobChunked
+	}
+
+	// Logos and captions burned into the renditions, from the job or each profile
+	watermarks, err := prepareWatermarks(ctx, queries, store, tempDir, renditions, opts)
+	if err != nil {
+		return markJobFailed(ctx, queries, pgUUID, err)
+	}
+
 	// Extract base filename (without extension) from input key
 	inputBase := filepath.Base(job.InputKey)
 	inputName := strings.TrimSuffix(inputBase, filepath.Ext(inputBase))
 
-	// Process each rendition using FFmpeg transcoding
-	for _, r := range renditions {
-		// Output is always .mp4 (H.264 + AAC)
-		outputKey := fmt.Sprintf("outputs/%s/%s_%s.mp4", jobIDStr, inputName, r.Resolution)
-		outputPath := filepath.Join(tempDir, inputName+"_"+r.Resolution+".mp4")
-
-		log.Printf("Job %s: transcoding to %s", jobIDStr, r.Resolution)
+	// Encode renditions in parallel within the worker's CPU budget
+	encoder := &renditionEncoder{
+		queries:        queries,
+		store:          store,
+		consumer:       consumer,
+		jobID:          jobIDStr,
+		tempDir:        tempDir,
+		inputPath:      inputPath,
+		inputName:      inputName,
+		opts:           opts,
+		source:         source,
+		sourceDuration: sourceDuration,
+		subtitles:      subtitles,
+		watermarks:     watermarks,
+		threads:        budget.Threads,
+	}
+	return encoder.finishJob(ctx, pgUUID, renditions, budget.Concurrency)
+}
 
-		// Transcode using FFmpeg with timing
-		transcodeStart := time.Now()
-		if err := transcoder.Transcode(ctx, inputPath, outputPath, r.Resolution); err != nil {
-			log.Printf("Job %s: failed to transcode rendition %s: %v", jobIDStr, r.Resolution, err)
-			metrics.RecordTranscodeError(r.Resolution)
+// finishJob encodes a job's renditions, packages and publishes them, and sets the job's final status
+func (e *renditionEncoder) finishJob(ctx context.Context, pgUUID pgtype.UUID, renditions []db.Rendition, concurrency int) error {
+	queries, store, jobIDStr, tempDir := e.queries, e.store, e.jobID, e.tempDir
+	results := e.encodeAll(ctx, renditions, concurrency)
+
+	// HLS variants collected for the master playlist
+	var hlsVariants []transcoder.Variant
+	// Successfully encoded video renditions, packaged together for DASH below
+	var encoded []db.Rendition
+	var encodedPaths []string
+	succeeded := 0
+	var errs []error
+	for i, res := range results {
+		if !res.ok {
+			errs = append(errs, res.err)
 			continue
 		}
-		metrics.RecordJobDuration(r.Resolution, time.Since(transcodeStart))
-
-		// Upload output to S3
-		log.Printf("Job %s: uploading rendition %s to %s", jobIDStr, r.Resolution, outputKey)
-		if err := store.Upload(ctx, outputPath, outputKey); err != nil {
-			log.Printf("Job %s: failed to upload rendition %s: %v", jobIDStr, r.Resolution, err)
+		succeeded++
+		if res.audioOnly {
+			// Audio-only renditions are standalone downloads, not adaptive streaming variants
 			continue
 		}
+		encoded = append(encoded, renditions[i])
+		encodedPaths = append(encodedPaths, res.outputPath)
+		if res.variant != nil {
+			hlsVariants = append(hlsVariants, *res.variant)
+		}
+	}
+	if len(renditions) > 0 && succeeded == 0 {
+		return markJobFailed(ctx, queries, pgUUID, renditionsFailedError(errs))
+	}
 
-		// Update rendition output key in database
-		_, err := queries.UpdateRenditionOutputKey(ctx, db.UpdateRenditionOutputKeyParams{
-			ID:        r.ID,
-			OutputKey: &outputKey,
-		})
-		if err != nil {
-			log.Printf("Job %s: failed to update rendition %s in DB: %v", jobIDStr, r.Resolution, err)
+	// Packaging failures leave the encoded renditions published and make the job partial
+	var packagingErrs []string
+
+	// Publish the HLS master playlist listing every packaged variant
+	if len(hlsVariants) > 0 {
+		masterKey, err := publishHLSMaster(ctx, store, jobIDStr, tempDir, hlsVariants)
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil:
+			log.Printf("Job %s: failed to publish HLS master playlist: %v", jobIDStr, err)
+			packagingErrs = append(packagingErrs, fmt.Sprintf("HLS master playlist failed: %v", err))
+		default:
+			if _, err := queries.UpdateJobHLSMasterKey(ctx, db.UpdateJobHLSMasterKeyParams{
+				ID:           pgUUID,
+				HlsMasterKey: &masterKey,
+			}); err != nil {
+				return markJobFailed(ctx, queries, pgUUID, fmt.Errorf("failed to save HLS master playlist key: %w", err))
+			}
+			log.Printf("Job %s: HLS master playlist uploaded to %s", jobIDStr, masterKey)
 		}
+	}
 
-		log.Printf("Job %s: rendition %s completed", jobIDStr, r.Resolution)
+	// Package every encoded rendition into one DASH manifest
+	if e.opts.wantsPackaging(packagingDASH) && len(encoded) > 0 {
+		log.Printf("Job %s: packaging %d renditions as DASH", jobIDStr, len(encoded))
+		manifestKey, representationIDs, err := packageDASH(ctx, store, jobIDStr, tempDir, encodedPaths)
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil:
+			log.Printf("Job %s: DASH packaging failed: %v", jobIDStr, err)
+			packagingErrs = append(packagingErrs, fmt.Sprintf("DASH packaging failed: %v", err))
+		default:
+			for i, r := range encoded {
+				if _, err := queries.UpdateRenditionDASHRepresentation(ctx, db.UpdateRenditionDASHRepresentationParams{
+					ID:                   r.ID,
+					DashRepresentationID: &representationIDs[i],
+				}); err != nil {
+					log.Printf("Job %s: failed to update DASH representation for rendition %s in DB: %v", jobIDStr, r.Resolution, err)
+				}
+			}
+			if _, err := queries.UpdateJobDASHManifestKey(ctx, db.UpdateJobDASHManifestKeyParams{
+				ID:              pgUUID,
+				DashManifestKey: &manifestKey,
+			}); err != nil {
+				return markJobFailed(ctx, queries, pgUUID, fmt.Errorf("failed to save DASH manifest key: %w", err))
+			}
+			log.Printf("Job %s: DASH manifest uploaded to %s", jobIDStr, manifestKey)
+		}
+	}
+
+	// Generate hover-scrub previews if requested (needs a video stream)
+	if e.opts.Thumbnails != nil {
+		if !e.source.HasVideo() {
+			log.Printf("Job %s: source has no video stream, skipping thumbnails", jobIDStr)
+		} else {
+			log.Printf("Job %s: generating thumbnail sprites", jobIDStr)
+			vttKey, err := publishThumbnails(ctx, queries, store, pgUUID, jobIDStr, tempDir, e.inputPath, e.source.DurationSeconds, *e.opts.Thumbnails)
+			if err != nil {
+				return markJobFailed(ctx, queries, pgUUID, fmt.Errorf("failed to generate thumbnails: %w", err))
+			}
+			log.Printf("Job %s: thumbnails uploaded, track at %s", jobIDStr, vttKey)
+		}
 	}
 
-	// Mark job as completed
+	// Derive the job's final status from its renditions
+	renditions, err := queries.GetRenditionsByJobID(ctx, pgUUID)
+	if err != nil {
+		return fmt.Errorf("failed to reload renditions: %w", err)
+	}
+	status, errMsg := jobStatusFromRenditions(renditions, packagingErrs)
 	_, err = queries.UpdateJobStatus(ctx, db.UpdateJobStatusParams{
 		ID:           pgUUID,
-		Status:       db.JobStatusCompleted,
-		ErrorMessage: nil,
+		Status:       status,
+		ErrorMessage: errMsg,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to mark job as completed: %w", err)
+		return fmt.Errorf("failed to mark job as %s: %w", status, err)
 	}
 
-	log.Printf("Job %s: completed successfully", jobIDStr)
+	log.Printf("Job %s: finished with status %s", jobIDStr, status)
 	return nil
 }
 
-// markJobFailed updates the job status to failed with an error message
+// markJobFailed updates the job status to failed with an error message and the error's code
 func markJobFailed(ctx context.Context, queries *db.Queries, jobID pgtype.UUID, jobErr error) error {
 	errMsg := jobErr.Error()
-	_, err := queries.UpdateJobStatus(ctx, db.UpdateJobStatusParams{
+	code, _ := failure.Classify(jobErr)
+	errCode := string(code)
+	_, err := queries.FailJob(ctx, db.FailJobParams{
 		ID:           jobID,
-		Status:       db.JobStatusFailed,
 		ErrorMessage: &errMsg,
+		ErrorCode:    &errCode,
 	})
 	if err != nil {
 		log.Printf("Failed to mark job as failed: %v", err)
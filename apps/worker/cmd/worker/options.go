@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/failure"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/transcoder"
+)
+
+// Packaging formats that can be requested on top of the progressive MP4 renditions
+const (
+	packagingHLS  = "hls"
+	packagingDASH = "dash"
+)
+
+// jobOptions mirrors the options document the API stores in jobs.options
+type jobOptions struct {
+	Packaging       []string           `json:"packaging,omitempty"`
+	Thumbnails      *thumbnailsOptions `json:"thumbnails,omitempty"`        // nil unless scrub previews were requested
+	PerTitle        bool               `json:"per_title,omitempty"`         // Fit the ladder to the source's complexity
+	QualityMetrics  bool               `json:"quality_metrics,omitempty"`   // Score renditions against the source; profiles with min_vmaf always are
+	Subtitles       []subtitleSidecar  `json:"subtitles,omitempty"`         // Subtitle files uploaded with the input
+	BurnInSubtitles string             `json:"burn_in_subtitles,omitempty"` // Language of the track to render into the picture
+	Trim            *trimOptions       `json:"trim,omitempty"`              // nil unless only part of the input is wanted
+	ConcatKeys      []string           `json:"concat_keys,omitempty"`       // Inputs joined in order; the job's input_key is the first
+	Watermark       *watermarkSpec     `json:"watermark,omitempty"`         // Overrides the profiles' watermarks for every rendition
+	Chunked         *chunkedOptions    `json:"chunked,omitempty"`           // nil unless the job is split across workers
+}
+
+// chunkedOptions configures distributed chunked encoding; a zero ChunkSeconds takes the default
+type chunkedOptions struct {
+	ChunkSeconds float64 `json:"chunk_seconds,omitempty"`
+}
+
+// trimOptions is the part of the input to keep, in seconds; an EndSeconds of zero means the end
+type trimOptions struct {
+	StartSeconds float64 `json:"start_seconds,omitempty"`
+	EndSeconds   float64 `json:"end_seconds,omitempty"`
+}
+
+// subtitleSidecar is a subtitle file (SRT, VTT, ASS) supplied alongside the input
+type subtitleSidecar struct {
+	Key      string `json:"key"`
+	Language string `json:"language,omitempty"`
+}
+
+// thumbnailsOptions configures sprite sheet generation; zero fields take the defaults below
+type thumbnailsOptions struct {
+	IntervalSeconds float64 `json:"interval_seconds,omitempty"`
+	Width           int     `json:"width,omitempty"`
+	Columns         int     `json:"columns,omitempty"`
+	Rows            int     `json:"rows,omitempty"`
+}
+
+// settings converts the requested options to transcoder settings, filling in defaults
+func (t thumbnailsOptions) settings() transcoder.ThumbnailOptions {
+	s := transcoder.ThumbnailOptions{
+		IntervalSeconds: 10,
+		Width:           160,
+		Columns:         10,
+		Rows:            10,
+	}
+	if t.IntervalSeconds > 0 {
+		s.IntervalSeconds = t.IntervalSeconds
+	}
+	if t.Width > 0 {
+		s.Width = t.Width
+	}
+	if t.Columns > 0 {
+		s.Columns = t.Columns
+	}
+	if t.Rows > 0 {
+		s.Rows = t.Rows
+	}
+	return s
+}
+
+// parseJobOptions decodes a job's options column; an empty document yields the defaults
+func parseJobOptions(raw []byte) (jobOptions, error) {
+	var opts jobOptions
+	if len(raw) == 0 {
+		return opts, nil
+	}
+	if err := json.Unmarshal(raw, &opts); err != nil {
+		return opts, failure.Permanent(failure.CodeInvalidOptions, fmt.Errorf("invalid job options: %w", err))
+	}
+	return opts, nil
+}
+
+// wantsPackaging reports whether the given packaging format was requested for the job
+func (o jobOptions) wantsPackaging(format string) bool {
+	for _, p := range o.Packaging {
+		if p == format {
+			return true
+		}
+	}
+	return false
+}
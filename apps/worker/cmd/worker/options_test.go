@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/transcoder"
+)
+
+func TestThumbnailsSettings(t *testing.T) {
+	tests := []struct {
+		name string
+		opts thumbnailsOptions
+		want transcoder.ThumbnailOptions
+	}{
+		{name: "defaults", want: transcoder.ThumbnailOptions{IntervalSeconds: 10, Width: 160, Columns: 10, Rows: 10}},
+		{
+			name: "all set",
+			opts: thumbnailsOptions{IntervalSeconds: 2.5, Width: 240, Columns: 5, Rows: 4},
+			want: transcoder.ThumbnailOptions{IntervalSeconds: 2.5, Width: 240, Columns: 5, Rows: 4},
+		},
+		{
+			name: "only interval",
+			opts: thumbnailsOptions{IntervalSeconds: 1},
+			want: transcoder.ThumbnailOptions{IntervalSeconds: 1, Width: 160, Columns: 10, Rows: 10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.settings(); got != tt.want {
+				t.Errorf("settings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/storage"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/transcoder"
+)
+
+// hlsPrefix returns the S3 prefix holding all HLS output for a job
+func hlsPrefix(jobID string) string {
+	return fmt.Sprintf("outputs/%s/hls", jobID)
+}
+
+// dashPrefix returns the S3 prefix holding all DASH output for a job
+func dashPrefix(jobID string) string {
+	return fmt.Sprintf("outputs/%s/dash", jobID)
+}
+
+// packageHLSRendition segments an encoded rendition, uploads its media playlist and segments
+// to outputs/{job}/hls/{resolution}/, and returns the playlist key and master playlist entry
+func packageHLSRendition(ctx context.Context, store *storage.Storage, jobID, tempDir, resolution, renditionPath string) (string, transcoder.Variant, error) {
+	// The rendition's codecs pick the segment type and fill in the variant's CODECS attribute
+	info, err := transcoder.Probe(ctx, renditionPath)
+	if err != nil {
+		return "", transcoder.Variant{}, err
+	}
+
+	outputDir := filepath.Join(tempDir, "hls", resolution)
+	playlistPath, err := transcoder.PackageHLS(ctx, renditionPath, outputDir, info)
+	if err != nil {
+		return "", transcoder.Variant{}, err
+	}
+
+	uri := path.Join(resolution, transcoder.HLSMediaPlaylistName)
+	variant, err := transcoder.NewVariant(info, playlistPath, uri)
+	if err != nil {
+		return "", transcoder.Variant{}, err
+	}
+
+	prefix := path.Join(hlsPrefix(jobID), resolution)
+	if err := store.UploadDir(ctx, outputDir, prefix); err != nil {
+		return "", transcoder.Variant{}, fmt.Errorf("failed to upload HLS segments: %w", err)
+	}
+
+	return path.Join(hlsPrefix(jobID), uri), variant, nil
+}
+
+// publishHLSMaster writes the master playlist for all packaged variants and uploads it
+// next to the per-rendition directories. Returns the master playlist key.
+func publishHLSMaster(ctx context.Context, store *storage.Storage, jobID, tempDir string, variants []transcoder.Variant) (string, error) {
+	masterPath := filepath.Join(tempDir, "hls", transcoder.HLSMasterPlaylistName)
+	if err := transcoder.WriteMasterPlaylist(masterPath, variants); err != nil {
+		return "", err
+	}
+
+	masterKey := path.Join(hlsPrefix(jobID), transcoder.HLSMasterPlaylistName)
+	if err := store.Upload(ctx, masterPath, masterKey); err != nil {
+		return "", fmt.Errorf("failed to upload master playlist: %w", err)
+	}
+
+	return masterKey, nil
+}
+
+// packageDASH packages all encoded renditions into CMAF segments with a single MPD manifest and
+// uploads them to outputs/{job}/dash/. Returns the manifest key and the representation ID of
+// each rendition path, in the order given.
+func packageDASH(ctx context.Context, store *storage.Storage, jobID, tempDir string, renditionPaths []string) (string, []string, error) {
+	outputDir := filepath.Join(tempDir, "dash")
+	_, representationIDs, err := transcoder.PackageDASH(ctx, renditionPaths, outputDir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := store.UploadDir(ctx, outputDir, dashPrefix(jobID)); err != nil {
+		return "", nil, fmt.Errorf("failed to upload DASH segments: %w", err)
+	}
+
+	return path.Join(dashPrefix(jobID), transcoder.DASHManifestName), representationIDs, nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/db"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/transcoder"
+)
+
+const (
+	// minRungStep is how many times more bits a rung must need than the next kept rung below it.
+	// A cheaper rung that saves less than this is dropped: the larger picture costs almost the same.
+	minRungStep = 1.25
+	// minTargetKbps is the lowest video bitrate per-title encoding will choose
+	minTargetKbps = 200
+	// peakRatio sets the -maxrate cap relative to the chosen average bitrate
+	peakRatio = 1.5
+)
+
+// ladderRung records the per-title decision for one rendition; the job's ladder column holds a list of them
+type ladderRung struct {
+	Resolution        string `json:"resolution"`
+	Height            int    `json:"height"`
+	ProbeBitrateKbps  int    `json:"probe_bitrate_kbps,omitempty"`
+	TargetBitrateKbps int    `json:"target_bitrate_kbps,omitempty"`
+	Dropped           bool   `json:"dropped"`
+	Reason            string `json:"reason,omitempty"`
+}
+
+// ladderCandidate is a video rendition taking part in the per-title analysis
+type ladderCandidate struct {
+	rendition db.Rendition
+	profile   transcoder.Profile
+	rung      ladderRung
+}
+
+// maxTargetKbps caps the per-title bitrate for a rung so noisy sources cannot
+// demand more than a conventional ladder would spend at that height
+func maxTargetKbps(height int) int {
+	switch {
+	case height <= 360:
+		return 1000
+	case height <= 480:
+		return 1800
+	case height <= 720:
+		return 4000
+	case height <= 1080:
+		return 7800
+	case height <= 1440:
+		return 14000
+	default:
+		return 25000
+	}
+}
+
+// planLadder runs the per-title analysis for a job: it probes the source's complexity at
+// each video rung, picks a bitrate per rung, drops rungs above the source resolution or too
+// close to the rung above them, and stores the ladder on the job.
+// Kept renditions get their target bitrate recorded and dropped ones are deleted.
+// Returns the renditions left to encode.
+func planLadder(ctx context.Context, queries *db.Queries, jobID pgtype.UUID, jobIDStr, tempDir, inputPath string,
+	source *transcoder.MediaInfo, renditions []db.Rendition, threads int) ([]db.Rendition, error) {
+
+	_, sourceHeight := displaySize(source)
+
+	var candidates []*ladderCandidate
+	var remaining []db.Rendition
+	for _, r := range renditions {
+		profile, err := resolveProfile(ctx, queries, r.Resolution)
+		if err != nil {
+			return nil, err
+		}
+		if profile.Height() == 0 {
+			// Audio-only and width-driven profiles are encoded as configured
+			remaining = append(remaining, r)
+			continue
+		}
+		candidates = append(candidates, &ladderCandidate{
+			rendition: r,
+			profile:   profile,
+			rung:      ladderRung{Resolution: r.Resolution, Height: profile.Height()},
+		})
+	}
+	if len(candidates) == 0 {
+		return renditions, nil
+	}
+
+	// Highest rung first, so each rung is compared with the kept rung above it
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].rung.Height > candidates[j].rung.Height })
+
+	analysed := dropAboveSource(candidates, sourceHeight)
+	for _, c := range analysed {
+		kbps, err := transcoder.ProbeBitrate(ctx, inputPath, tempDir, c.profile, source.DurationSeconds, threads)
+		if err != nil {
+			return nil, fmt.Errorf("complexity probe for %s failed: %w", c.rung.Resolution, err)
+		}
+		c.rung.ProbeBitrateKbps = kbps
+	}
+	pickBitrates(analysed)
+
+	ladder := make([]ladderRung, 0, len(candidates))
+	for _, c := range candidates {
+		ladder = append(ladder, c.rung)
+		if c.rung.Dropped {
+			log.Printf("Job %s: per-title dropped %s (%s)", jobIDStr, c.rung.Resolution, c.rung.Reason)
+			if err := queries.DeleteRendition(ctx, c.rendition.ID); err != nil {
+				return nil, fmt.Errorf("failed to drop rendition %s: %w", c.rung.Resolution, err)
+			}
+			continue
+		}
+
+		log.Printf("Job %s: per-title %s at %dk (probe %dk)", jobIDStr, c.rung.Resolution, c.rung.TargetBitrateKbps, c.rung.ProbeBitrateKbps)
+		target := int32(c.rung.TargetBitrateKbps)
+		r, err := queries.SetRenditionTargetBitrate(ctx, db.SetRenditionTargetBitrateParams{
+			ID:                c.rendition.ID,
+			TargetBitrateKbps: &target,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to save target bitrate for %s: %w", c.rung.Resolution, err)
+		}
+		remaining = append(remaining, r)
+	}
+
+	// Lowest rung first, matching how ladders are usually read
+	sort.Slice(ladder, func(i, j int) bool { return ladder[i].Height < ladder[j].Height })
+	raw, err := json.Marshal(ladder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode ladder: %w", err)
+	}
+	if _, err := queries.UpdateJobLadder(ctx, db.UpdateJobLadderParams{
+		ID:     jobID,
+		Ladder: raw,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to save ladder: %w", err)
+	}
+
+	return remaining, nil
+}
+
+// dropAboveSource marks the rungs taller than the source as dropped, since upscaling only
+// wastes bits, and returns the rest. The smallest rung is kept even if it is above the source.
+// candidates must be sorted highest rung first.
+func dropAboveSource(candidates []*ladderCandidate, sourceHeight int) []*ladderCandidate {
+	lowest := candidates[len(candidates)-1]
+	var kept []*ladderCandidate
+	for _, c := range candidates {
+		if c.rung.Height > sourceHeight && c != lowest {
+			c.rung.Dropped = true
+			c.rung.Reason = fmt.Sprintf("above source height %d", sourceHeight)
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+// pickBitrates turns each probed rung's complexity into a target bitrate, clamped to
+// minTargetKbps and the rung's maxTargetKbps, and drops rungs that would not need at least
+// minRungStep times fewer bits than the kept rung above them.
+// candidates must be sorted highest rung first and have ProbeBitrateKbps set.
+func pickBitrates(candidates []*ladderCandidate) {
+	var above *ladderCandidate
+	for _, c := range candidates {
+		c.rung.TargetBitrateKbps = max(minTargetKbps, min(c.rung.ProbeBitrateKbps, maxTargetKbps(c.rung.Height)))
+
+		if above != nil && float64(c.rung.TargetBitrateKbps)*minRungStep > float64(above.rung.TargetBitrateKbps) {
+			c.rung.Dropped = true
+			c.rung.Reason = fmt.Sprintf("within %.0f%% of %s's bitrate", (minRungStep-1)*100, above.rung.Resolution)
+			continue
+		}
+		above = c
+	}
+}
+
+// applyTargetBitrate switches a profile to the bitrate chosen by per-title analysis.
+// Two-pass profiles stay two-pass; everything else becomes a capped single-pass encode.
+func applyTargetBitrate(profile transcoder.Profile, kbps int) transcoder.Profile {
+	if profile.RateControlMode() != transcoder.RateControlTwoPass {
+		profile.RateControl = transcoder.RateControlVBR
+	}
+	profile.VideoBitrate = fmt.Sprintf("%dk", kbps)
+	profile.MaxRate = fmt.Sprintf("%dk", int(float64(kbps)*peakRatio))
+	profile.BufSize = fmt.Sprintf("%dk", kbps*2)
+	return profile
+}
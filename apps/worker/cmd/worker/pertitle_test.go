@@ -0,0 +1,129 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/transcoder"
+)
+
+// rungs wraps ladder rungs, given highest first, in per-title candidates
+func rungs(specs ...ladderRung) []*ladderCandidate {
+	candidates := make([]*ladderCandidate, len(specs))
+	for i, r := range specs {
+		candidates[i] = &ladderCandidate{rung: r}
+	}
+	return candidates
+}
+
+func TestDropAboveSource(t *testing.T) {
+	tests := []struct {
+		name         string
+		sourceHeight int
+		wantKept     []string
+	}{
+		{"source taller than every rung", 2160, []string{"1080p", "720p", "480p"}},
+		{"720p source", 720, []string{"720p", "480p"}},
+		{"tiny source keeps the lowest rung", 240, []string{"480p"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates := rungs(
+				ladderRung{Resolution: "1080p", Height: 1080},
+				ladderRung{Resolution: "720p", Height: 720},
+				ladderRung{Resolution: "480p", Height: 480},
+			)
+			kept := dropAboveSource(candidates, tt.sourceHeight)
+
+			var got []string
+			for _, c := range kept {
+				got = append(got, c.rung.Resolution)
+			}
+			if len(got) != len(tt.wantKept) {
+				t.Fatalf("kept %v, want %v", got, tt.wantKept)
+			}
+			for i := range got {
+				if got[i] != tt.wantKept[i] {
+					t.Fatalf("kept %v, want %v", got, tt.wantKept)
+				}
+			}
+			for _, c := range candidates {
+				if c.rung.Dropped == contains(got, c.rung.Resolution) {
+					t.Errorf("%s: dropped = %v", c.rung.Resolution, c.rung.Dropped)
+				}
+			}
+		})
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPickBitrates(t *testing.T) {
+	tests := []struct {
+		name        string
+		probes      []int // 1080p, 720p, 480p
+		wantTargets []int
+		wantDropped []bool
+	}{
+		{
+			name:        "distinct rungs are kept",
+			probes:      []int{5000, 3000, 1500},
+			wantTargets: []int{5000, 3000, 1500},
+			wantDropped: []bool{false, false, false},
+		},
+		{
+			name:        "targets are clamped per height",
+			probes:      []int{20000, 9000, 50},
+			wantTargets: []int{7800, 4000, 200},
+			wantDropped: []bool{false, false, false},
+		},
+		{
+			name:        "rung too close to the one above is dropped",
+			probes:      []int{2000, 1800, 1500},
+			wantTargets: []int{2000, 1800, 1500},
+			wantDropped: []bool{false, true, false},
+		},
+		{
+			name:        "simple content collapses to the top rung",
+			probes:      []int{300, 260, 250},
+			wantTargets: []int{300, 260, 250},
+			wantDropped: []bool{false, true, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates := rungs(
+				ladderRung{Resolution: "1080p", Height: 1080, ProbeBitrateKbps: tt.probes[0]},
+				ladderRung{Resolution: "720p", Height: 720, ProbeBitrateKbps: tt.probes[1]},
+				ladderRung{Resolution: "480p", Height: 480, ProbeBitrateKbps: tt.probes[2]},
+			)
+			pickBitrates(candidates)
+			for i, c := range candidates {
+				if c.rung.TargetBitrateKbps != tt.wantTargets[i] {
+					t.Errorf("%s: target = %d, want %d", c.rung.Resolution, c.rung.TargetBitrateKbps, tt.wantTargets[i])
+				}
+				if c.rung.Dropped != tt.wantDropped[i] {
+					t.Errorf("%s: dropped = %v (%s), want %v", c.rung.Resolution, c.rung.Dropped, c.rung.Reason, tt.wantDropped[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyTargetBitrate(t *testing.T) {
+	crf := applyTargetBitrate(transcoder.Profile{RateControl: transcoder.RateControlCRF, CRF: 23}, 2000)
+	if crf.RateControl != transcoder.RateControlVBR || crf.VideoBitrate != "2000k" || crf.MaxRate != "3000k" || crf.BufSize != "4000k" {
+		t.Errorf("crf profile = %+v", crf)
+	}
+
+	twoPass := applyTargetBitrate(transcoder.Profile{RateControl: transcoder.RateControlTwoPass, VideoBitrate: "5000k"}, 1000)
+	if twoPass.RateControl != transcoder.RateControlTwoPass || twoPass.VideoBitrate != "1000k" || twoPass.MaxRate != "1500k" {
+		t.Errorf("two-pass profile = %+v", twoPass)
+	}
+}
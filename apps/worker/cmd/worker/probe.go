@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/db"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/transcoder"
+)
+
+// probeInput runs ffprobe on the downloaded input and persists the source metadata for the job.
+// Video sources are also checked for interlacing, which ffprobe's field_order often misreports.
+func probeInput(ctx context.Context, queries *db.Queries, jobID pgtype.UUID, inputPath string) (*transcoder.MediaInfo, error) {
+	info, err := transcoder.Probe(ctx, inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe input: %w", err)
+	}
+	if info.HasVideo() {
+		info.FieldOrder, err = transcoder.DetectFieldOrder(ctx, inputPath, info.DurationSeconds)
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect interlacing: %w", err)
+		}
+	}
+
+	_, err = queries.UpsertMediaProbe(ctx, db.UpsertMediaProbeParams{
+		JobID:              jobID,
+		DurationSeconds:    optional(info.DurationSeconds),
+		Container:          optional(info.Container),
+		VideoCodec:         optional(info.VideoCodec),
+		AudioCodec:         optional(info.AudioCodec),
+		Width:              optional(int32(info.Width)),
+		Height:             optional(int32(info.Height)),
+		FrameRate:          optional(info.FrameRate),
+		BitRate:            optional(info.BitRate),
+		Rotation:           int32(info.Rotation),
+		AudioChannels:      optional(int32(info.AudioChannels)),
+		AudioChannelLayout: optional(info.AudioChannelLayout),
+		FieldOrder:         optional(info.FieldOrder),
+		ColorTransfer:      optional(info.ColorTransfer),
+		ColorPrimaries:     optional(info.ColorPrimaries),
+		HdrFormat:          optional(info.HDRFormat()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save media probe: %w", err)
+	}
+
+	return info, nil
+}
+
+// reprobeInput probes a file of a job whose source was already probed by probeInput, such as a
+// chunk or a re-downloaded input. The scan type is taken from the stored probe of the whole
+// source rather than detected again.
+func reprobeInput(ctx context.Context, queries *db.Queries, jobID pgtype.UUID, path string) (*transcoder.MediaInfo, error) {
+	info, err := transcoder.Probe(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe input: %w", err)
+	}
+	stored, err := queries.GetMediaProbe(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load media probe: %w", err)
+	}
+	if stored.FieldOrder != nil {
+		info.FieldOrder = *stored.FieldOrder
+	}
+	return info, nil
+}
+
+// optional returns a pointer to v, or nil when v is the zero value (stored as NULL)
+func optional[T comparable](v T) *T {
+	var zero T
+	if v == zero {
+		return nil
+	}
+	return &v
+}
+
+// displaySize returns the source's frame size as shown to viewers. FFmpeg autorotates
+// inputs, so filters see rotated sources with width and height swapped.
+func displaySize(info *transcoder.MediaInfo) (width, height int) {
+	if info.Rotation == 90 || info.Rotation == 270 {
+		return info.Height, info.Width
+	}
+	return info.Width, info.Height
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/db"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/transcoder"
+)
+
+// resolveProfile looks up a rendition's profile in the database registry.
+// The built-in defaults are only used when the registry has no row with that name,
+// e.g. against a database created before the profiles table existed.
+func resolveProfile(ctx context.Context, queries *db.Queries, name string) (transcoder.Profile, error) {
+	p, err := queries.GetProfile(ctx, name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		profile, defaultErr := transcoder.GetProfile(name)
+		if defaultErr != nil {
+			return transcoder.Profile{}, defaultErr
+		}
+		log.Printf("Profile %s not found in registry, using built-in default", name)
+		return profile, nil
+	}
+	if err != nil {
+		return transcoder.Profile{}, fmt.Errorf("failed to load profile %s: %w", name, err)
+	}
+
+	profile := transcoder.Profile{
+		Name:        p.Name,
+		Scale:       p.Scale,
+		VideoCodec:  p.VideoCodec,
+		AudioCodec:  p.AudioCodec,
+		Preset:      p.Preset,
+		Container:   p.Container,
+		AudioOnly:   p.AudioOnly,
+		RateControl: p.RateControl,
+		Deinterlace: p.Deinterlace,
+		ToneMap:     p.ToneMap,
+	}
+	if p.Crf != nil {
+		profile.CRF = int(*p.Crf)
+	}
+	if p.VideoBitrate != nil {
+		profile.VideoBitrate = *p.VideoBitrate
+	}
+	if p.MaxRate != nil {
+		profile.MaxRate = *p.MaxRate
+	}
+	if p.BufSize != nil {
+		profile.BufSize = *p.BufSize
+	}
+	if p.MinVmaf != nil {
+		profile.MinVMAF = *p.MinVmaf
+	}
+	if p.LoudnessTarget != nil {
+		profile.LoudnessTarget = *p.LoudnessTarget
+	}
+	if p.TruePeakLimit != nil {
+		profile.TruePeakLimit = *p.TruePeakLimit
+	}
+	if p.KeyframeInterval != nil {
+		profile.KeyframeInterval = *p.KeyframeInterval
+	}
+	if p.AudioBitrate != nil {
+		profile.AudioBitrate = *p.AudioBitrate
+	}
+	if p.MaxFps != nil {
+		profile.MaxFPS = int(*p.MaxFps)
+	}
+	if p.AudioSampleRate != nil {
+		profile.AudioSampleRate = int(*p.AudioSampleRate)
+	}
+	if p.AudioChannels != nil {
+		profile.AudioChannels = int(*p.AudioChannels)
+	}
+	return profile, nil
+}
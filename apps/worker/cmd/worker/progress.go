@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/queue"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/transcoder"
+)
+
+// progressInterval is the minimum time between progress updates published for one rendition
+const progressInterval = 2 * time.Second
+
+// progressReporter returns a transcoder progress callback that publishes a rendition's
+// progress to Redis, at most once per progressInterval plus a final update on completion
+func progressReporter(ctx context.Context, consumer *queue.Consumer, jobID, resolution string) func(transcoder.Progress) {
+	var last time.Time
+	return func(p transcoder.Progress) {
+		if !p.Done && time.Since(last) < progressInterval {
+			return
+		}
+		last = time.Now()
+
+		err := consumer.SetProgress(ctx, jobID, resolution, queue.RenditionProgress{
+			Percent:    p.Percent,
+			Speed:      p.Speed,
+			ETASeconds: int64(p.ETA.Round(time.Second) / time.Second),
+			UpdatedAt:  last.UTC(),
+		})
+		if err != nil {
+			log.Printf("Job %s: failed to publish progress for rendition %s: %v", jobID, resolution, err)
+		}
+	}
+}
+
+// chunkProgressReporter returns a transcoder progress callback for encoding one chunk into a
+// rendition. Chunks are encoded on several workers at once, so each publishes the seconds it
+// has encoded and the rendition's progress is the total across its chunks over the job's
+// duration, which weights every chunk by its length. No ETA is published: it depends on the
+// chunks other workers are encoding.
+func chunkProgressReporter(ctx context.Context, consumer *queue.Consumer, jobID, resolution string, chunk int, chunkSeconds, totalSeconds float64) func(transcoder.Progress) {
+	var last time.Time
+	return func(p transcoder.Progress) {
+		if !p.Done && time.Since(last) < progressInterval {
+			return
+		}
+		last = time.Now()
+
+		encoded, err := consumer.SetChunkProgress(ctx, jobID, resolution, chunk, chunkSeconds*p.Percent/100)
+		if err != nil {
+			log.Printf("Job %s: failed to publish progress for chunk %d of rendition %s: %v", jobID, chunk, resolution, err)
+			return
+		}
+		err = consumer.SetProgress(ctx, jobID, resolution, queue.RenditionProgress{
+			Percent:   math.Min(100, encoded/totalSeconds*100),
+			Speed:     p.Speed,
+			UpdatedAt: last.UTC(),
+		})
+		if err != nil {
+			log.Printf("Job %s: failed to publish progress for rendition %s: %v", jobID, resolution, err)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/db"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/failure"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/metrics"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/queue"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/storage"
+)
+
+const (
+	// reaperInterval is how often the stale-job reaper runs
+	reaperInterval = time.Minute
+	// reaperLeaseTTL is how long a worker stays the reaper without renewing; another worker
+	// takes over at most this long after the leader dies
+	reaperLeaseTTL = 2 * reaperInterval
+)
+
+// errJobStalled is the error recorded on a stale job that has no retries left
+var errJobStalled = &failure.Error{Code: failure.CodeStalled, Err: errors.New("worker stopped responding")}
+
+// startReaper runs reapStaleJobs every interval until ctx is cancelled. Every worker runs the
+// loop, but only the one holding the reaper leadership reaps, so jobs aren't requeued twice.
+func startReaper(ctx context.Context, queries *db.Queries, store *storage.Storage, consumer *queue.Consumer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			leader, err := consumer.Lead(ctx, queue.ReaperLeaderKey, reaperLeaseTTL)
+			if err != nil {
+				log.Printf("Reaper: %v", err)
+				continue
+			}
+			if leader {
+				reapStaleChunks(ctx, queries, store, consumer)
+				reapStaleJobs(ctx, queries, consumer)
+			}
+		}
+	}
+}
+
+// reapStaleJobs takes back jobs that have been processing for too long without a worker
+// holding their lock, which means the worker died and the job's queue entry was lost with it
+// (the recovery sweep returns entries of dead workers only while Redis still has them).
+// A reaped job counts as a retry: it is queued again, or failed once out of retries.
+// A split job is only found once all its chunks are encoded; its stitch is queued again instead.
+func reapStaleJobs(ctx context.Context, queries *db.Queries, consumer *queue.Consumer) {
+	jobs, err := queries.GetStaleJobs(ctx)
+	if err != nil {
+		log.Printf("Reaper: failed to find stale jobs: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		jobIDStr := uuid.UUID(job.ID.Bytes).String()
+
+		// The stitch of a split job stalled, or the worker that encoded the last chunk died
+		// before queueing it; either way the stitch task is what's missing
+		message := jobIDStr
+		chunks, err := queries.GetJobChunks(ctx, job.ID)
+		if err != nil {
+			log.Printf("Reaper: failed to get chunks of job %s: %v", jobIDStr, err)
+			continue
+		}
+		if len(chunks) > 0 {
+			message = queue.StitchTask(jobIDStr)
+		}
+
+		// A task still locked is running long, not stalled: its worker extends the lock
+		locked, err := consumer.IsLocked(ctx, message)
+		if err != nil {
+			log.Printf("Reaper: job %s: %v", jobIDStr, err)
+			continue
+		}
+		if locked {
+			continue
+		}
+
+		if job.RetryCount >= job.MaxRetries {
+			log.Printf("Reaper: job %s stalled and exceeded max retries (%d), moving to dead letter queue", jobIDStr, job.MaxRetries)
+			if err := consumer.PushDeadLetter(ctx, message); err != nil {
+				log.Printf("Reaper: failed to push job %s to dead letter queue: %v", jobIDStr, err)
+			}
+			markJobFailed(ctx, queries, job.ID, fmt.Errorf("exceeded max retries: %w", errJobStalled))
+			metrics.RecordStaleJobFailed()
+			continue
+		}
+
+		reset, err := queries.ResetStalledJob(ctx, job.ID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Claimed, finished or cancelled since it was found
+			continue
+		}
+		if err != nil {
+			log.Printf("Reaper: failed to reset job %s: %v", jobIDStr, err)
+			continue
+		}
+		if err := consumer.Push(ctx, message); err != nil {
+			log.Printf("Reaper: failed to re-queue %s: %v", message, err)
+			continue
+		}
+		log.Printf("Reaper: %s stalled, re-queued (retry %d/%d)", message, reset.RetryCount, reset.MaxRetries)
+		metrics.RecordStaleJobRequeued()
+	}
+}
+
+// reapAction is what the reaper does with a stale chunk
+type reapAction int
+
+const (
+	reapSkip    reapAction = iota // Leave it: a worker holds it or its entry is still queued
+	reapRequeue                   // Return it to pending and queue it again
+	reapFail                      // Fail its job: it used up its attempts
+)
+
+// staleChunkAction decides what to do with a chunk GetStaleJobChunks found. A chunk whose task
+// is locked is running long and one whose entry is still queued (or waiting for its retry's
+// backoff) will be claimed in turn; either way it isn't lost. Otherwise it is queued again,
+// unless it has used more attempts than the job's retry limit, as handleChunkFailure decides
+// for a chunk that failed.
+func staleChunkAction(chunk db.JobChunk, maxRetries int32, locked, queued bool) reapAction {
+	switch {
+	case locked || queued:
+		return reapSkip
+	case chunk.Attempts > maxRetries:
+		return reapFail
+	default:
+		return reapRequeue
+	}
+}
+
+// reapStaleChunks takes back chunks of split jobs that were lost: their encode has gone on for
+// too long without a worker holding the chunk task's lock, or they have been pending or failed
+// for too long without their task in the queue or the delayed set. The chunk's claim already
+// counted any lost attempt, so the chunk is queued again or, out of attempts, fails its job.
+func reapStaleChunks(ctx context.Context, queries *db.Queries, store *storage.Storage, consumer *queue.Consumer) {
+	chunks, err := queries.GetStaleJobChunks(ctx)
+	if err != nil {
+		log.Printf("Reaper: failed to find stale chunks: %v", err)
+		return
+	}
+
+	for _, chunk := range chunks {
+		jobIDStr := uuid.UUID(chunk.JobID.Bytes).String()
+		message := queue.ChunkTask(jobIDStr, int(chunk.ChunkIndex))
+
+		locked, err := consumer.IsLocked(ctx, message)
+		if err != nil {
+			log.Printf("Reaper: %s: %v", message, err)
+			continue
+		}
+		queued := false
+		if !locked {
+			if queued, err = consumer.IsQueued(ctx, message); err != nil {
+				log.Printf("Reaper: %v", err)
+				continue
+			}
+		}
+
+		job, err := queries.GetJob(ctx, chunk.JobID)
+		if err != nil {
+			log.Printf("Reaper: failed to get job %s of stale chunk %d: %v", jobIDStr, chunk.ChunkIndex, err)
+			continue
+		}
+
+		switch staleChunkAction(chunk, job.MaxRetries, locked, queued) {
+		case reapSkip:
+			continue
+		case reapFail:
+			log.Printf("Reaper: job %s: chunk %d stalled after %d attempts, failing the job", jobIDStr, chunk.ChunkIndex, chunk.Attempts)
+			markJobFailed(ctx, queries, chunk.JobID, fmt.Errorf("chunk %d failed after %d attempts: %w", chunk.ChunkIndex, chunk.Attempts, errJobStalled))
+			if err := consumer.PushDeadLetter(ctx, message); err != nil {
+				log.Printf("Reaper: failed to push job %s to dead letter queue: %v", jobIDStr, err)
+			}
+			if err := store.DeletePrefix(ctx, chunksPrefix(jobIDStr)); err != nil {
+				log.Printf("Reaper: job %s: failed to remove chunks: %v", jobIDStr, err)
+			}
+			metrics.RecordStaleChunkFailed()
+			continue
+		}
+
+		if _, err := queries.ResetStalledJobChunk(ctx, chunk.ID); errors.Is(err, pgx.ErrNoRows) {
+			// Claimed or finished since it was found
+			continue
+		} else if err != nil {
+			log.Printf("Reaper: failed to reset %s: %v", message, err)
+			continue
+		}
+		if err := consumer.Push(ctx, message); err != nil {
+			log.Printf("Reaper: failed to re-queue %s: %v", message, err)
+			continue
+		}
+		if chunk.Status == db.ChunkStatusProcessing {
+			log.Printf("Reaper: job %s: chunk %d stalled, re-queued (attempt %d/%d)", jobIDStr, chunk.ChunkIndex, chunk.Attempts, job.MaxRetries+1)
+		} else {
+			log.Printf("Reaper: job %s: chunk %d lost its queue entry, re-queued", jobIDStr, chunk.ChunkIndex)
+		}
+		metrics.RecordStaleChunkRequeued()
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/db"
+)
+
+func TestStaleChunkAction(t *testing.T) {
+	processing := func(attempts int32) db.JobChunk {
+		return db.JobChunk{Status: db.ChunkStatusProcessing, Attempts: attempts}
+	}
+	pending := db.JobChunk{Status: db.ChunkStatusPending}
+	failed := func(attempts int32) db.JobChunk {
+		return db.JobChunk{Status: db.ChunkStatusFailed, Attempts: attempts}
+	}
+
+	tests := []struct {
+		name       string
+		chunk      db.JobChunk
+		maxRetries int32
+		locked     bool
+		queued     bool
+		want       reapAction
+	}{
+		{"stalled encode", processing(1), 3, false, false, reapRequeue},
+		{"encode running long", processing(1), 3, true, false, reapSkip},
+		{"stalled encode already queued", processing(1), 3, false, true, reapSkip},
+		{"stalled with one attempt left", processing(3), 3, false, false, reapRequeue},
+		{"stalled out of attempts", processing(4), 3, false, false, reapFail},
+		{"out of attempts but running", processing(4), 3, true, false, reapSkip},
+		{"pending entry lost", pending, 3, false, false, reapRequeue},
+		{"pending entry still queued", pending, 3, false, true, reapSkip},
+		{"pending task being claimed", pending, 3, true, false, reapSkip},
+		{"failed retry lost", failed(2), 3, false, false, reapRequeue},
+		{"failed retry waiting for backoff", failed(2), 3, false, true, reapSkip},
+		{"failed out of attempts", failed(4), 3, false, false, reapFail},
+		{"no retries allowed", processing(1), 0, false, false, reapFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := staleChunkAction(tt.chunk, tt.maxRetries, tt.locked, tt.queued); got != tt.want {
+				t.Errorf("staleChunkAction() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/db"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/metrics"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/queue"
+)
+
+const (
+	// heartbeatInterval is how often a worker refreshes its heartbeat (which lasts queue.HeartbeatTTL)
+	heartbeatInterval = 10 * time.Second
+	// recoveryInterval is how often a worker sweeps for entries orphaned by dead workers.
+	// queue.HeartbeatTTL must outlast several sweeps, or a worker that misses one refresh is
+	// recovered while it still runs its task and the task runs twice.
+	recoveryInterval = 30 * time.Second
+)
+
+// ack acknowledges a handled queue entry, logging rather than failing when Redis refuses
+func ack(ctx context.Context, consumer *queue.Consumer, message string) {
+	if err := consumer.Ack(ctx, message); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// startRecoverySweep runs recoverOrphans at startup and then every interval until ctx is cancelled
+func startRecoverySweep(ctx context.Context, queries *db.Queries, consumer *queue.Consumer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		recoverOrphans(ctx, queries, consumer)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recoverOrphans returns the unacknowledged entries of workers whose heartbeat expired to the
+// pending queue. The claims those workers hold in the database are released first, so a
+// recovered job or chunk can be claimed again straight away rather than after the
+// 10-minute stale-claim timeout.
+func recoverOrphans(ctx context.Context, queries *db.Queries, consumer *queue.Consumer) {
+	dead, err := consumer.DeadWorkers(ctx)
+	if err != nil {
+		log.Printf("Recovery sweep: %v", err)
+		return
+	}
+	for _, workerID := range dead {
+		entries, err := consumer.Orphans(ctx, workerID)
+		if err != nil {
+			log.Printf("Recovery sweep: %v", err)
+			continue
+		}
+		for _, message := range entries {
+			releaseClaim(ctx, queries, workerID, message)
+		}
+
+		n, err := consumer.RecoverOrphans(ctx, workerID)
+		if err != nil {
+			log.Printf("Recovery sweep: %v", err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("Recovery sweep: returned %d entries of dead worker %s to the queue", n, workerID)
+			metrics.RecordQueueEntriesRecovered(n)
+		}
+	}
+}
+
+// releaseClaim returns the job or chunk behind a queue entry to its unclaimed state,
+// if the dead worker still holds it. Stitches are claimable while processing and need nothing.
+func releaseClaim(ctx context.Context, queries *db.Queries, workerID, message string) {
+	t, err := parseTask(message)
+	if err != nil {
+		return
+	}
+	pgUUID := pgtype.UUID{Bytes: uuid.MustParse(t.jobID), Valid: true}
+
+	switch t.kind {
+	case taskJob:
+		err = queries.ReleaseWorkerJob(ctx, db.ReleaseWorkerJobParams{
+			ID:       pgUUID,
+			WorkerID: &workerID,
+		})
+	case taskChunk:
+		err = queries.ReleaseWorkerJobChunk(ctx, db.ReleaseWorkerJobChunkParams{
+			JobID:      pgUUID,
+			ChunkIndex: int32(t.chunk),
+			WorkerID:   &workerID,
+		})
+	}
+	if err != nil {
+		log.Printf("Recovery sweep: failed to release %s held by worker %s: %v", message, workerID, err)
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/queue"
+)
+
+// TestHeartbeatOutlastsSweeps checks that a worker has to miss several heartbeat refreshes,
+// over at least two recovery sweeps, before another worker recovers its entries
+func TestHeartbeatOutlastsSweeps(t *testing.T) {
+	if queue.HeartbeatTTL < 3*heartbeatInterval {
+		t.Errorf("HeartbeatTTL %v is less than three heartbeats (%v each)", queue.HeartbeatTTL, heartbeatInterval)
+	}
+	if queue.HeartbeatTTL < 3*recoveryInterval {
+		t.Errorf("HeartbeatTTL %v is less than three recovery sweeps (%v each)", queue.HeartbeatTTL, recoveryInterval)
+	}
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/db"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/failure"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/metrics"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/queue"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/storage"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/transcoder"
+)
+
+// encodeBudget limits how much CPU one job's renditions may use
+type encodeBudget struct {
+	Concurrency int // Renditions encoded at the same time
+	Threads     int // FFmpeg threads per rendition
+}
+
+// renditionEncoder holds the per-job state shared by every rendition encode
+type renditionEncoder struct {
+	queries        *db.Queries
+	store          *storage.Storage
+	consumer       *queue.Consumer
+	jobID          string
+	tempDir        string
+	inputPath      string
+	inputName      string
+	opts           jobOptions
+	source         *transcoder.MediaInfo
+	sourceDuration time.Duration
+	subtitles      subtitlePlan
+	watermarks     map[string]*transcoder.Watermark // Keyed by resolution; missing for renditions without one
+	threads        int
+	// chunks of a chunked job; video renditions are stitched from their encoded chunks
+	// instead of being transcoded from the input
+	chunks []db.JobChunk
+
+	loudnessMu sync.Mutex
+	loudness   map[[2]float64]*transcoder.Loudness // Source measurements by (target, true peak)
+}
+
+// renditionResult is the outcome of encoding one rendition
+type renditionResult struct {
+	ok         bool                // Encoded and uploaded
+	audioOnly  bool                // Rendition has no video stream and is left out of HLS/DASH packaging
+	outputPath string              // Local path of the encoded rendition
+	variant    *transcoder.Variant // HLS master playlist entry, nil unless HLS packaging succeeded
+	err        error               // Why the rendition failed, nil when ok
+}
+
+// encodeAll encodes renditions with at most concurrency of them running at once.
+// Results are returned in the same order as renditions.
+func (e *renditionEncoder) encodeAll(ctx context.Context, renditions []db.Rendition, concurrency int) []renditionResult {
+	return encodeLimited(renditions, concurrency, func(r db.Rendition) renditionResult {
+		return e.encode(ctx, r)
+	})
+}
+
+// encodeLimited runs encode for each rendition with at most concurrency calls running at once
+// and returns the results in the same order as renditions
+func encodeLimited(renditions []db.Rendition, concurrency int, encode func(db.Rendition) renditionResult) []renditionResult {
+	results := make([]renditionResult, len(renditions))
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i, r := range renditions {
+		wg.Add(1)
+		go func(i int, r db.Rendition) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = encode(r)
+		}(i, r)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// renditionsFailedError reports that every rendition of a job failed. It unwraps to the
+// renditions' errors, so the job failure is permanent only when each of them is.
+type renditionsFailedError []error
+
+func (e renditionsFailedError) Error() string {
+	return fmt.Sprintf("all %d renditions failed", len(e))
+}
+
+func (e renditionsFailedError) Unwrap() []error {
+	return e
+}
+
+// jobStatusFromRenditions derives a job's final status from its renditions:
+// completed when every rendition completed, partial when only some did, failed when none did.
+// Failed packaging steps (packagingErrs) make an otherwise completed job partial, since the
+// renditions themselves are published. The error message lists what failed.
+func jobStatusFromRenditions(renditions []db.Rendition, packagingErrs []string) (db.JobStatus, *string) {
+	var failed []string
+	for _, r := range renditions {
+		if r.Status != db.RenditionStatusCompleted {
+			failed = append(failed, r.Resolution)
+		}
+	}
+
+	switch {
+	case len(failed) == 0 && len(packagingErrs) == 0:
+		return db.JobStatusCompleted, nil
+	case len(renditions) > 0 && len(failed) == len(renditions):
+		msg := "all renditions failed"
+		return db.JobStatusFailed, &msg
+	default:
+		var problems []string
+		if len(failed) > 0 {
+			problems = append(problems, fmt.Sprintf("renditions failed: %s", strings.Join(failed, ", ")))
+		}
+		msg := strings.Join(append(problems, packagingErrs...), "; ")
+		return db.JobStatusPartial, &msg
+	}
+}
+
+// encode transcodes, uploads and records a single rendition, tracking its status in the database.
+// Failures are recorded on the rendition and reported through the result so other renditions carry on.
+func (e *renditionEncoder) encode(ctx context.Context, r db.Rendition) renditionResult {
+	if _, err := e.queries.StartRendition(ctx, r.ID); err != nil {
+		log.Printf("Job %s: failed to mark rendition %s as processing: %v", e.jobID, r.Resolution, err)
+	}
+
+	result, err := e.encodeRendition(ctx, r)
+	if err != nil {
+		log.Printf("Job %s: rendition %s failed: %v", e.jobID, r.Resolution, err)
+		errMsg := err.Error()
+		if _, dbErr := e.queries.FailRendition(ctx, db.FailRenditionParams{
+			ID:           r.ID,
+			ErrorMessage: &errMsg,
+		}); dbErr != nil {
+			log.Printf("Job %s: failed to mark rendition %s as failed: %v", e.jobID, r.Resolution, dbErr)
+		}
+		return renditionResult{err: err}
+	}
+
+	log.Printf("Job %s: rendition %s completed", e.jobID, r.Resolution)
+	return result
+}
+
+// encodeRendition does the work behind encode and returns the first error that stops the rendition
+func (e *renditionEncoder) encodeRendition(ctx context.Context, r db.Rendition) (renditionResult, error) {
+	// The rendition's resolution names a profile in the registry
+	profile, err := resolveProfile(ctx, e.queries, r.Resolution)
+	if err != nil {
+		metrics.RecordTranscodeError(r.Resolution)
+		return renditionResult{}, err
+	}
+
+	// Per-title analysis replaces the profile's rate control with a bitrate fitted to this source
+	if r.TargetBitrateKbps != nil && !profile.AudioOnly {
+		profile = applyTargetBitrate(profile, int(*r.TargetBitrateKbps))
+	}
+
+	// Output container (and extension) comes from the profile
+	outputName := e.inputName + "_" + r.Resolution + profile.Extension()
+	outputKey := fmt.Sprintf("outputs/%s/%s", e.jobID, outputName)
+	outputPath := filepath.Join(e.tempDir, outputName)
+
+	// Loudness normalization is two-pass: measure the source, then encode with the measured values
+	var sourceLoudness *transcoder.Loudness
+	audioFilter := ""
+	if profile.LoudnessTarget != 0 && e.source.AudioCodec != "" {
+		sourceLoudness, err = e.sourceLoudness(ctx, profile.LoudnessTarget, profile.TruePeak())
+		if err != nil {
+			metrics.RecordTranscodeError(r.Resolution)
+			return renditionResult{}, err
+		}
+		audioFilter = transcoder.LoudnormFilter(sourceLoudness, profile.LoudnessTarget, profile.TruePeak())
+	}
+
+	deinterlace, toneMap := profile.Corrections(e.source)
+	if deinterlace || toneMap {
+		log.Printf("Job %s: %s corrections: deinterlace=%t tone_map=%t", e.jobID, r.Resolution, deinterlace, toneMap)
+	}
+
+	log.Printf("Job %s: transcoding to %s", e.jobID, r.Resolution)
+
+	options := transcoder.Options{
+		Duration:        e.sourceDuration,
+		OnProgress:      progressReporter(ctx, e.consumer, e.jobID, r.Resolution),
+		FrameRate:       e.source.FrameRate,
+		Threads:         e.threads,
+		SubtitleStreams: e.subtitles.carry,
+		BurnSubtitles:   e.subtitles.burnIn,
+		Watermark:       e.watermarks[r.Resolution],
+		AudioFilter:     audioFilter,
+		Deinterlace:     deinterlace,
+		ToneMap:         toneMap,
+	}
+
+	// Transcode using FFmpeg with timing
+	transcodeStart := time.Now()
+	if e.chunks != nil && !profile.AudioOnly {
+		err = e.stitch(ctx, r, profile, outputPath, options)
+	} else {
+		err = transcoder.TranscodeWithProfile(ctx, e.inputPath, outputPath, profile, options)
+	}
+	if err != nil {
+		metrics.RecordTranscodeError(r.Resolution)
+		return renditionResult{}, fmt.Errorf("failed to transcode: %w", err)
+	}
+	metrics.RecordJobDuration(r.Resolution, time.Since(transcodeStart))
+
+	// Measure the output before uploading it
+	stat, err := os.Stat(outputPath)
+	if err != nil {
+		return renditionResult{}, fmt.Errorf("failed to stat output: %w", err)
+	}
+	outputInfo, err := transcoder.Probe(ctx, outputPath)
+	if err != nil {
+		return renditionResult{}, fmt.Errorf("failed to probe output: %w", err)
+	}
+
+	// Score the rendition against the source when requested or when the profile sets a quality floor
+	if !profile.AudioOnly && (e.opts.QualityMetrics || profile.MinVMAF > 0) {
+		if err := e.measureQuality(ctx, r, profile, outputPath, transcoder.CorrectionFilters(deinterlace, toneMap)); err != nil {
+			return renditionResult{}, err
+		}
+	}
+
+	if sourceLoudness != nil {
+		if err := e.recordLoudness(ctx, r, profile, sourceLoudness, outputPath); err != nil {
+			return renditionResult{}, err
+		}
+	}
+
+	// Upload output to S3
+	log.Printf("Job %s: uploading rendition %s to %s", e.jobID, r.Resolution, outputKey)
+	if err := e.store.Upload(ctx, outputPath, outputKey); err != nil {
+		return renditionResult{}, fmt.Errorf("failed to upload: %w", err)
+	}
+
+	// Update rendition output key in database
+	_, err = e.queries.UpdateRenditionOutputKey(ctx, db.UpdateRenditionOutputKeyParams{
+		ID:        r.ID,
+		OutputKey: &outputKey,
+	})
+	if err != nil {
+		return renditionResult{}, fmt.Errorf("failed to save output key: %w", err)
+	}
+	result := renditionResult{ok: true, audioOnly: profile.AudioOnly, outputPath: outputPath}
+
+	// Segment the rendition for HLS if requested; audio-only outputs are published as plain files
+	if e.opts.wantsPackaging(packagingHLS) && !profile.AudioOnly {
+		log.Printf("Job %s: packaging rendition %s as HLS", e.jobID, r.Resolution)
+		playlistKey, variant, err := packageHLSRendition(ctx, e.store, e.jobID, e.tempDir, r.Resolution, outputPath)
+		if err != nil {
+			return renditionResult{}, fmt.Errorf("failed to package as HLS: %w", err)
+		}
+		result.variant = &variant
+		if _, err := e.queries.UpdateRenditionHLSPlaylistKey(ctx, db.UpdateRenditionHLSPlaylistKeyParams{
+			ID:             r.ID,
+			HlsPlaylistKey: &playlistKey,
+		}); err != nil {
+			return renditionResult{}, fmt.Errorf("failed to save HLS playlist key: %w", err)
+		}
+	}
+
+	size := stat.Size()
+	if _, err := e.queries.CompleteRendition(ctx, db.CompleteRenditionParams{
+		ID:              r.ID,
+		OutputSizeBytes: &size,
+		DurationSeconds: optional(outputInfo.DurationSeconds),
+	}); err != nil {
+		return renditionResult{}, fmt.Errorf("failed to mark rendition as completed: %w", err)
+	}
+
+	return result, nil
+}
+
+// stitch downloads a rendition's encoded chunks and joins them into outputPath with the input's audio
+func (e *renditionEncoder) stitch(ctx context.Context, r db.Rendition, profile transcoder.Profile, outputPath string, opts transcoder.Options) error {
+	chunkDir := filepath.Join(e.tempDir, "chunks_"+r.Resolution)
+	if err := os.MkdirAll(chunkDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create chunk dir: %w", err)
+	}
+	defer os.RemoveAll(chunkDir)
+
+	paths := make([]string, len(e.chunks))
+	for i, c := range e.chunks {
+		key := encodedChunkKey(e.jobID, r.Resolution, int(c.ChunkIndex))
+		paths[i] = filepath.Join(chunkDir, path.Base(key))
+		if err := e.store.Download(ctx, key, paths[i]); err != nil {
+			return fmt.Errorf("failed to download chunk %d: %w", c.ChunkIndex, err)
+		}
+	}
+
+	log.Printf("Job %s: stitching %d chunks of rendition %s", e.jobID, len(paths), r.Resolution)
+	return transcoder.Stitch(ctx, paths, e.inputPath, outputPath, profile, opts)
+}
+
+// measureQuality scores an encoded rendition against the source at the source's display size,
+// stores the scores and enforces the profile's VMAF threshold
+func (e *renditionEncoder) measureQuality(ctx context.Context, r db.Rendition, profile transcoder.Profile, outputPath, corrections string) error {
+	log.Printf("Job %s: measuring quality of rendition %s", e.jobID, r.Resolution)
+	width, height := displaySize(e.source)
+	scores, err := transcoder.MeasureQuality(ctx, outputPath, e.inputPath, width, height, e.threads, corrections)
+	if err != nil {
+		return fmt.Errorf("failed to measure quality: %w", err)
+	}
+	log.Printf("Job %s: rendition %s VMAF mean=%.2f min=%.2f p%d=%.2f, PSNR %.2fdB, SSIM %.4f",
+		e.jobID, r.Resolution, scores.VMAFMean, scores.VMAFMin, transcoder.QualityPercentile, scores.VMAFLow, scores.PSNRMean, scores.SSIMMean)
+
+	if _, err := e.queries.UpdateRenditionQuality(ctx, db.UpdateRenditionQualityParams{
+		ID:       r.ID,
+		VmafMean: &scores.VMAFMean,
+		VmafMin:  &scores.VMAFMin,
+		VmafP5:   &scores.VMAFLow,
+		PsnrMean: &scores.PSNRMean,
+		SsimMean: &scores.SSIMMean,
+	}); err != nil {
+		return fmt.Errorf("failed to save quality scores: %w", err)
+	}
+
+	if profile.MinVMAF > 0 && scores.VMAFMean < profile.MinVMAF {
+		// Encoding is deterministic, so a retry would score the same
+		return failure.Permanent(failure.CodeQualityBelowFloor,
+			fmt.Errorf("VMAF %.2f is below the profile threshold of %.2f", scores.VMAFMean, profile.MinVMAF))
+	}
+	return nil
+}
+
+// sourceLoudness measures the source's loudness for a normalization target. Renditions sharing
+// a target reuse the measurement; it is taken once per job.
+func (e *renditionEncoder) sourceLoudness(ctx context.Context, target, truePeak float64) (*transcoder.Loudness, error) {
+	e.loudnessMu.Lock()
+	defer e.loudnessMu.Unlock()
+
+	key := [2]float64{target, truePeak}
+	if l, ok := e.loudness[key]; ok {
+		return l, nil
+	}
+	log.Printf("Job %s: measuring source loudness for %g LUFS", e.jobID, target)
+	l, err := transcoder.MeasureLoudness(ctx, e.inputPath, target, truePeak)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure source loudness: %w", err)
+	}
+	if e.loudness == nil {
+		e.loudness = make(map[[2]float64]*transcoder.Loudness)
+	}
+	e.loudness[key] = l
+	return l, nil
+}
+
+// recordLoudness measures a normalized rendition and stores its loudness next to the source's,
+// so the result can be audited against the target
+func (e *renditionEncoder) recordLoudness(ctx context.Context, r db.Rendition, profile transcoder.Profile, source *transcoder.Loudness, outputPath string) error {
+	output, err := transcoder.MeasureLoudness(ctx, outputPath, profile.LoudnessTarget, profile.TruePeak())
+	if err != nil {
+		return fmt.Errorf("failed to measure rendition loudness: %w", err)
+	}
+	log.Printf("Job %s: rendition %s loudness %.1f LUFS / %.1f dBTP -> %.1f LUFS / %.1f dBTP (target %g LUFS)",
+		e.jobID, r.Resolution, source.Integrated, source.TruePeak, output.Integrated, output.TruePeak, profile.LoudnessTarget)
+
+	if _, err := e.queries.UpdateRenditionLoudness(ctx, db.UpdateRenditionLoudnessParams{
+		ID:                r.ID,
+		LoudnessInputI:    &source.Integrated,
+		LoudnessInputTp:   &source.TruePeak,
+		LoudnessInputLra:  &source.Range,
+		LoudnessOutputI:   &output.Integrated,
+		LoudnessOutputTp:  &output.TruePeak,
+		LoudnessOutputLra: &output.Range,
+	}); err != nil {
+		return fmt.Errorf("failed to save loudness: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/db"
+)
+
+func TestJobStatusFromRenditions(t *testing.T) {
+	completed := db.Rendition{Resolution: "720p", Status: db.RenditionStatusCompleted}
+	failed := db.Rendition{Resolution: "1080p", Status: db.RenditionStatusFailed}
+	failedSD := db.Rendition{Resolution: "480p", Status: db.RenditionStatusFailed}
+	unfinished := db.Rendition{Resolution: "360p", Status: db.RenditionStatusProcessing}
+
+	tests := []struct {
+		name          string
+		renditions    []db.Rendition
+		packagingErrs []string
+		wantStatus    db.JobStatus
+		wantMsg       string
+	}{
+		{"all completed", []db.Rendition{completed, completed}, nil, db.JobStatusCompleted, ""},
+		{"some failed", []db.Rendition{completed, failed}, nil, db.JobStatusPartial, "renditions failed: 1080p"},
+		{"all failed", []db.Rendition{failed, failed}, nil, db.JobStatusFailed, "all renditions failed"},
+		{"packaging failed", []db.Rendition{completed}, []string{"DASH packaging failed: boom"}, db.JobStatusPartial, "DASH packaging failed: boom"},
+		{
+			"rendition and packaging failed",
+			[]db.Rendition{completed, failed},
+			[]string{"DASH packaging failed: boom"},
+			db.JobStatusPartial,
+			"renditions failed: 1080p; DASH packaging failed: boom",
+		},
+		{"all failed outranks packaging", []db.Rendition{failed}, []string{"DASH packaging failed: boom"}, db.JobStatusFailed, "all renditions failed"},
+		{"failures listed in order", []db.Rendition{failed, completed, failedSD}, nil, db.JobStatusPartial, "renditions failed: 1080p, 480p"},
+		{"unfinished counts as failed", []db.Rendition{completed, unfinished}, nil, db.JobStatusPartial, "renditions failed: 360p"},
+		{"no renditions", nil, nil, db.JobStatusCompleted, ""},
+		{"only packaging without renditions", nil, []string{"HLS packaging failed: boom"}, db.JobStatusPartial, "HLS packaging failed: boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, msg := jobStatusFromRenditions(tt.renditions, tt.packagingErrs)
+			if status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", status, tt.wantStatus)
+			}
+			got := ""
+			if msg != nil {
+				got = *msg
+			}
+			if got != tt.wantMsg {
+				t.Errorf("message = %q, want %q", got, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestEncodeLimited(t *testing.T) {
+	renditions := []db.Rendition{
+		{Resolution: "1080p"}, {Resolution: "720p"}, {Resolution: "480p"}, {Resolution: "360p"}, {Resolution: "240p"},
+	}
+
+	for _, concurrency := range []int{1, 2, 5, 8} {
+		t.Run(fmt.Sprintf("concurrency %d", concurrency), func(t *testing.T) {
+			started := make(chan string, len(renditions))
+			release := make(chan struct{})
+			done := make(chan []renditionResult)
+			go func() {
+				done <- encodeLimited(renditions, concurrency, func(r db.Rendition) renditionResult {
+					started <- r.Resolution
+					<-release
+					return renditionResult{ok: r.Resolution != "480p", outputPath: r.Resolution + ".mp4"}
+				})
+			}()
+
+			// The first encodes fill the limit and hold it until released
+			want := min(concurrency, len(renditions))
+			for i := 0; i < want; i++ {
+				<-started
+			}
+			select {
+			case r := <-started:
+				t.Errorf("%s started while %d encodes were running", r, want)
+			case <-time.After(20 * time.Millisecond):
+			}
+			close(release)
+
+			results := <-done
+			if len(results) != len(renditions) {
+				t.Fatalf("got %d results, want %d", len(results), len(renditions))
+			}
+			for i, r := range renditions {
+				if results[i].outputPath != r.Resolution+".mp4" || results[i].ok != (r.Resolution != "480p") {
+					t.Errorf("result %d = %+v, want the result of %s", i, results[i], r.Resolution)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/db"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/failure"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/storage"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/transcoder"
+)
+
+// Artifact kinds recorded in job_artifacts for text tracks
+const (
+	artifactSubtitles = "subtitles" // Sidecar or embedded subtitle track converted to WebVTT
+	artifactCaptions  = "captions"  // EIA-608/708 closed captions extracted to WebVTT
+)
+
+// subtitlePlan is how a job's renditions handle subtitles
+type subtitlePlan struct {
+	carry  []int  // Embedded text streams copied into each rendition as soft subtitles
+	burnIn string // subtitles filter rendering one track into the picture, empty for none
+}
+
+// subtitlesPrefix returns the S3 prefix holding a job's WebVTT tracks
+func subtitlesPrefix(jobID string) string {
+	return fmt.Sprintf("outputs/%s/subtitles", jobID)
+}
+
+// prepareSubtitles publishes every text track of a job as a WebVTT sidecar under
+// outputs/{job}/subtitles/: the subtitle files supplied with the job, the source's embedded
+// text tracks and any closed captions in its video stream. Each file is recorded as a job
+// artifact. It returns how renditions should carry and burn in subtitles.
+func prepareSubtitles(ctx context.Context, queries *db.Queries, store *storage.Storage, jobID pgtype.UUID, jobIDStr, tempDir, inputPath string,
+	source *transcoder.MediaInfo, opts jobOptions) (subtitlePlan, error) {
+
+	var plan subtitlePlan
+	outputDir := filepath.Join(tempDir, "subtitles")
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return plan, fmt.Errorf("failed to create subtitles dir: %w", err)
+	}
+	prefix := subtitlesPrefix(jobIDStr)
+
+	publish := func(localPath, kind, language string) error {
+		key := path.Join(prefix, filepath.Base(localPath))
+		if err := store.Upload(ctx, localPath, key); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", filepath.Base(localPath), err)
+		}
+		_, err := queries.UpsertJobArtifact(ctx, db.UpsertJobArtifactParams{
+			JobID:       jobID,
+			Kind:        kind,
+			OutputKey:   key,
+			ContentType: storage.ContentType(key),
+			Language:    optional(language),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record subtitle artifact %s: %w", key, err)
+		}
+		return nil
+	}
+
+	// Sidecar files uploaded alongside the input
+	burnInPath, burnInPosition := "", -1
+	for i, sidecar := range opts.Subtitles {
+		localPath := filepath.Join(outputDir, fmt.Sprintf("sidecar_%d%s", i, filepath.Ext(sidecar.Key)))
+		if err := store.Download(ctx, sidecar.Key, localPath); err != nil {
+			return plan, fmt.Errorf("failed to download subtitles %s: %w", sidecar.Key, err)
+		}
+		vttPath := filepath.Join(outputDir, trackFileName("sidecar", i, sidecar.Language))
+		if err := transcoder.ConvertToWebVTT(ctx, localPath, vttPath, -1); err != nil {
+			return plan, fmt.Errorf("failed to convert subtitles %s: %w", sidecar.Key, err)
+		}
+		if err := publish(vttPath, artifactSubtitles, sidecar.Language); err != nil {
+			return plan, err
+		}
+		if burnInPath == "" && opts.BurnInSubtitles != "" && sidecar.Language == opts.BurnInSubtitles {
+			burnInPath = localPath
+		}
+	}
+
+	// Text tracks embedded in the source; bitmap tracks cannot be converted and are skipped
+	for _, s := range source.Subtitles {
+		if !s.IsText() {
+			log.Printf("Job %s: skipping bitmap subtitle stream %d (%s)", jobIDStr, s.Index, s.Codec)
+			continue
+		}
+		vttPath := filepath.Join(outputDir, trackFileName("embedded", s.Index, s.Language))
+		if err := transcoder.ConvertToWebVTT(ctx, inputPath, vttPath, s.Index); err != nil {
+			return plan, fmt.Errorf("failed to extract subtitle stream %d: %w", s.Index, err)
+		}
+		if err := publish(vttPath, artifactSubtitles, s.Language); err != nil {
+			return plan, err
+		}
+		plan.carry = append(plan.carry, s.Index)
+		if burnInPath == "" && burnInPosition < 0 && opts.BurnInSubtitles != "" && s.Language == opts.BurnInSubtitles {
+			burnInPosition = s.Position
+		}
+	}
+
+	// Closed captions travel inside the video bitstream and are lost when it is re-encoded
+	if source.ClosedCaptions {
+		vttPath := filepath.Join(outputDir, "captions.vtt")
+		if err := transcoder.ExtractClosedCaptions(ctx, inputPath, vttPath); err != nil {
+			return plan, fmt.Errorf("failed to extract closed captions: %w", err)
+		}
+		if err := publish(vttPath, artifactCaptions, ""); err != nil {
+			return plan, err
+		}
+	}
+
+	switch {
+	case opts.BurnInSubtitles == "":
+	case burnInPath != "":
+		plan.burnIn = transcoder.BurnInFilter(burnInPath, -1)
+	case burnInPosition >= 0:
+		plan.burnIn = transcoder.BurnInFilter(inputPath, burnInPosition)
+	default:
+		return plan, failure.Permanent(failure.CodeInvalidOptions,
+			fmt.Errorf("no text subtitle track with language %q to burn in", opts.BurnInSubtitles))
+	}
+
+	return plan, nil
+}
+
+// trackFileName names a WebVTT sidecar after its origin and language, e.g. "embedded_2_eng.vtt"
+func trackFileName(origin string, index int, language string) string {
+	if language == "" {
+		language = "und"
+	}
+	return fmt.Sprintf("%s_%d_%s.vtt", origin, index, language)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/db"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/storage"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/transcoder"
+)
+
+// Artifact kinds recorded in job_artifacts for scrub previews
+const (
+	artifactSprite        = "sprite"
+	artifactThumbnailsVTT = "thumbnails_vtt"
+)
+
+// thumbnailsPrefix returns the S3 prefix holding a job's sprite sheets and VTT track
+func thumbnailsPrefix(jobID string) string {
+	return fmt.Sprintf("outputs/%s/thumbnails", jobID)
+}
+
+// publishThumbnails generates sprite sheets and a WebVTT scrub track from the input, uploads them
+// to outputs/{job}/thumbnails/ and records each file as a job artifact.
+// Returns the S3 key of the VTT track.
+func publishThumbnails(ctx context.Context, queries *db.Queries, store *storage.Storage, jobID pgtype.UUID, jobIDStr, tempDir, inputPath string, durationSeconds float64, opts thumbnailsOptions) (string, error) {
+	outputDir := filepath.Join(tempDir, "thumbnails")
+	set, err := transcoder.GenerateThumbnails(ctx, inputPath, outputDir, durationSeconds, opts.settings())
+	if err != nil {
+		return "", err
+	}
+
+	prefix := thumbnailsPrefix(jobIDStr)
+	if err := store.UploadDir(ctx, outputDir, prefix); err != nil {
+		return "", fmt.Errorf("failed to upload thumbnails: %w", err)
+	}
+
+	artifacts := map[string]string{
+		path.Join(prefix, filepath.Base(set.VTTPath)): artifactThumbnailsVTT,
+	}
+	for _, p := range set.SpritePaths {
+		artifacts[path.Join(prefix, filepath.Base(p))] = artifactSprite
+	}
+	for key, kind := range artifacts {
+		if _, err := queries.UpsertJobArtifact(ctx, db.UpsertJobArtifactParams{
+			JobID:       jobID,
+			Kind:        kind,
+			OutputKey:   key,
+			ContentType: storage.ContentType(key),
+		}); err != nil {
+			return "", fmt.Errorf("failed to record thumbnail artifact %s: %w", key, err)
+		}
+	}
+
+	return path.Join(prefix, transcoder.ThumbnailsVTTName), nil
+}
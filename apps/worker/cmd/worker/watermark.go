@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/db"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/storage"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/transcoder"
+)
+
+// watermarkSpec mirrors the watermark document stored on a job's options or a profile;
+// zero fields take the defaults in settings
+type watermarkSpec struct {
+	ImageKey string         `json:"image_key,omitempty"` // Logo image in the bucket
+	Position string         `json:"position,omitempty"`
+	Margin   *int           `json:"margin,omitempty"` // Pixels; nil for the default, 0 is flush with the edge
+	Opacity  float64        `json:"opacity,omitempty"`
+	Scale    float64        `json:"scale,omitempty"`
+	Text     *watermarkText `json:"text,omitempty"`
+}
+
+// watermarkText is the optional text part of a watermark
+type watermarkText struct {
+	Text     string  `json:"text"`
+	Position string  `json:"position,omitempty"`
+	Size     float64 `json:"size,omitempty"`
+	Color    string  `json:"color,omitempty"`
+}
+
+// settings converts the spec to transcoder settings, filling in defaults: logo top-right at
+// 10% of the output height, text bottom-left at 4%, 16px margins, fully opaque
+func (w watermarkSpec) settings(imagePath string) *transcoder.Watermark {
+	s := &transcoder.Watermark{
+		ImagePath:    imagePath,
+		Position:     transcoder.PositionTopRight,
+		Margin:       16,
+		Opacity:      1,
+		Scale:        0.1,
+		TextPosition: transcoder.PositionBottomLeft,
+		TextSize:     0.04,
+		TextColor:    "white",
+	}
+	if w.Position != "" {
+		s.Position = w.Position
+	}
+	if w.Margin != nil {
+		s.Margin = *w.Margin
+	}
+	if w.Opacity > 0 {
+		s.Opacity = w.Opacity
+	}
+	if w.Scale > 0 {
+		s.Scale = w.Scale
+	}
+	if t := w.Text; t != nil {
+		s.Text = t.Text
+		if t.Position != "" {
+			s.TextPosition = t.Position
+		}
+		if t.Size > 0 {
+			s.TextSize = t.Size
+		}
+		if t.Color != "" {
+			s.TextColor = t.Color
+		}
+	}
+	return s
+}
+
+// prepareWatermarks resolves the watermark each rendition is encoded with, keyed by resolution.
+// A watermark on the job applies to every rendition; otherwise each profile's own watermark is used.
+// Logo images are downloaded once per job.
+func prepareWatermarks(ctx context.Context, queries *db.Queries, store *storage.Storage, tempDir string,
+	renditions []db.Rendition, opts jobOptions) (map[string]*transcoder.Watermark, error) {
+
+	images := map[string]string{} // Object key -> local path
+	resolve := func(spec watermarkSpec) (*transcoder.Watermark, error) {
+		if spec.ImageKey == "" {
+			return spec.settings(""), nil
+		}
+		localPath, ok := images[spec.ImageKey]
+		if !ok {
+			localPath = filepath.Join(tempDir, fmt.Sprintf("watermark_%d%s", len(images), filepath.Ext(spec.ImageKey)))
+			if err := store.Download(ctx, spec.ImageKey, localPath); err != nil {
+				return nil, fmt.Errorf("failed to download watermark image %s: %w", spec.ImageKey, err)
+			}
+			images[spec.ImageKey] = localPath
+		}
+		return spec.settings(localPath), nil
+	}
+
+	watermarks := make(map[string]*transcoder.Watermark)
+	if opts.Watermark != nil {
+		w, err := resolve(*opts.Watermark)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range renditions {
+			watermarks[r.Resolution] = w
+		}
+		return watermarks, nil
+	}
+
+	for _, r := range renditions {
+		p, err := queries.GetProfile(ctx, r.Resolution)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue // Built-in default profiles have no watermark
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load profile %s: %w", r.Resolution, err)
+		}
+		if len(p.Watermark) == 0 {
+			continue
+		}
+		var spec watermarkSpec
+		if err := json.Unmarshal(p.Watermark, &spec); err != nil {
+			return nil, fmt.Errorf("invalid watermark on profile %s: %w", r.Resolution, err)
+		}
+		w, err := resolve(spec)
+		if err != nil {
+			return nil, err
+		}
+		watermarks[r.Resolution] = w
+	}
+	return watermarks, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/transcoder"
+)
+
+func TestWatermarkSettings(t *testing.T) {
+	zero := 0
+	tests := []struct {
+		name      string
+		spec      watermarkSpec
+		imagePath string
+		want      transcoder.Watermark
+	}{
+		{
+			name:      "logo defaults",
+			spec:      watermarkSpec{ImageKey: "logos/acme.png"},
+			imagePath: "/tmp/job/watermark_0.png",
+			want: transcoder.Watermark{
+				ImagePath: "/tmp/job/watermark_0.png", Position: transcoder.PositionTopRight, Margin: 16, Opacity: 1, Scale: 0.1,
+				TextPosition: transcoder.PositionBottomLeft, TextSize: 0.04, TextColor: "white",
+			},
+		},
+		{
+			name: "text defaults",
+			spec: watermarkSpec{Text: &watermarkText{Text: "draft"}},
+			want: transcoder.Watermark{
+				Position: transcoder.PositionTopRight, Margin: 16, Opacity: 1, Scale: 0.1,
+				Text: "draft", TextPosition: transcoder.PositionBottomLeft, TextSize: 0.04, TextColor: "white",
+			},
+		},
+		{
+			name: "everything set",
+			spec: watermarkSpec{
+				ImageKey: "logos/acme.png", Position: transcoder.PositionCenter, Margin: &zero, Opacity: 0.5, Scale: 0.2,
+				Text: &watermarkText{Text: "draft", Position: transcoder.PositionTopLeft, Size: 0.1, Color: "#ffcc00"},
+			},
+			imagePath: "logo.png",
+			want: transcoder.Watermark{
+				ImagePath: "logo.png", Position: transcoder.PositionCenter, Margin: 0, Opacity: 0.5, Scale: 0.2,
+				Text: "draft", TextPosition: transcoder.PositionTopLeft, TextSize: 0.1, TextColor: "#ffcc00",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.spec.settings(tt.imagePath); *got != tt.want {
+				t.Errorf("settings() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
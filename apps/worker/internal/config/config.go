@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"runtime"
+	"strconv"
 )
 
 // Config holds all configuration for the worker service
@@ -15,6 +17,16 @@ type Config struct {
 	S3Bucket       string
 	S3Region       string
 	S3UsePathStyle bool
+
+	// CPUBudget is the number of FFmpeg threads this worker may run at once, split across parallel renditions
+	CPUBudget int
+	// RenditionConcurrency is the number of renditions of one job encoded in parallel
+	RenditionConcurrency int
+
+	// S3PartSizeMB is the part size, in MiB, of multipart uploads and ranged downloads
+	S3PartSizeMB int
+	// S3TransferConcurrency is the number of parts of one file transferred at once
+	S3TransferConcurrency int
 }
 
 // Load reads configuration from environment variables
@@ -30,6 +42,29 @@ func Load() (*Config, error) {
 		S3UsePathStyle: getEnv("S3_USE_PATH_STYLE", "true") == "true",
 	}
 
+	var err error
+	if cfg.CPUBudget, err = getEnvInt("WORKER_CPU_BUDGET", runtime.NumCPU()); err != nil {
+		return nil, err
+	}
+	if cfg.RenditionConcurrency, err = getEnvInt("RENDITION_CONCURRENCY", 3); err != nil {
+		return nil, err
+	}
+	if cfg.CPUBudget < 1 || cfg.RenditionConcurrency < 1 {
+		return nil, fmt.Errorf("WORKER_CPU_BUDGET and RENDITION_CONCURRENCY must be at least 1")
+	}
+	if cfg.S3PartSizeMB, err = getEnvInt("S3_PART_SIZE_MB", 16); err != nil {
+		return nil, err
+	}
+	if cfg.S3TransferConcurrency, err = getEnvInt("S3_TRANSFER_CONCURRENCY", 4); err != nil {
+		return nil, err
+	}
+	if cfg.S3PartSizeMB < 5 {
+		return nil, fmt.Errorf("S3_PART_SIZE_MB must be at least 5, the S3 minimum part size")
+	}
+	if cfg.S3TransferConcurrency < 1 {
+		return nil, fmt.Errorf("S3_TRANSFER_CONCURRENCY must be at least 1")
+	}
+
 	if cfg.DatabaseURL == "" {
 		return nil, fmt.Errorf("DATABASE_URL environment variable is required")
 	}
@@ -44,3 +79,23 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+func getEnvInt(key string, fallback int) (int, error) {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer: %w", key, err)
+	}
+	return n, nil
+}
+
+// ThreadsPerRendition divides the CPU budget evenly across parallel renditions (at least one thread each)
+func (c *Config) ThreadsPerRendition() int {
+	threads := c.CPUBudget / c.RenditionConcurrency
+	if threads < 1 {
+		return 1
+	}
+	return threads
+}
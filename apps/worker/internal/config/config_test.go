@@ -0,0 +1,22 @@
+package config
+
+import "testing"
+
+func TestThreadsPerRendition(t *testing.T) {
+	tests := []struct {
+		budget, concurrency int
+		want                int
+	}{
+		{budget: 8, concurrency: 1, want: 8},
+		{budget: 8, concurrency: 3, want: 2},
+		{budget: 12, concurrency: 3, want: 4},
+		{budget: 2, concurrency: 3, want: 1},
+		{budget: 1, concurrency: 1, want: 1},
+	}
+	for _, tt := range tests {
+		c := &Config{CPUBudget: tt.budget, RenditionConcurrency: tt.concurrency}
+		if got := c.ThreadsPerRendition(); got != tt.want {
+			t.Errorf("ThreadsPerRendition() with budget %d over %d renditions = %d, want %d", tt.budget, tt.concurrency, got, tt.want)
+		}
+	}
+}
@@ -11,8 +11,277 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const completeJobChunk = `-- name: CompleteJobChunk :one
+UPDATE job_chunks
+SET status = 'completed',
+    finished_at = NOW()
+WHERE id = $1
+RETURNING id, job_id, chunk_index, source_key, start_seconds, duration_seconds, status, attempts, worker_id, error_message, started_at, finished_at, created_at, updated_at
+`
+
+func (q *Queries) CompleteJobChunk(ctx context.Context, id pgtype.UUID) (JobChunk, error) {
+	row := q.db.QueryRow(ctx, completeJobChunk, id)
+	var i JobChunk
+	err := row.Scan(
+		&i.ID,
+		&i.JobID,
+		&i.ChunkIndex,
+		&i.SourceKey,
+		&i.StartSeconds,
+		&i.DurationSeconds,
+		&i.Status,
+		&i.Attempts,
+		&i.WorkerID,
+		&i.ErrorMessage,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const completeRendition = `-- name: CompleteRendition :one
+UPDATE renditions
+SET status = 'completed',
+    finished_at = NOW(),
+    output_size_bytes = $2,
+    duration_seconds = $3
+WHERE id = $1
+RETURNING id, job_id, resolution, output_key, hls_playlist_key, dash_representation_id, status, error_message, started_at, finished_at, output_size_bytes, duration_seconds, target_bitrate_kbps, vmaf_mean, vmaf_min, vmaf_p5, psnr_mean, ssim_mean, loudness_input_i, loudness_input_tp, loudness_input_lra, loudness_output_i, loudness_output_tp, loudness_output_lra, created_at
+`
+
+type CompleteRenditionParams struct {
+	ID              pgtype.UUID `json:"id"`
+	OutputSizeBytes *int64      `json:"output_size_bytes"`
+	DurationSeconds *float64    `json:"duration_seconds"`
+}
+
+func (q *Queries) CompleteRendition(ctx context.Context, arg CompleteRenditionParams) (Rendition, error) {
+	row := q.db.QueryRow(ctx, completeRendition, arg.ID, arg.OutputSizeBytes, arg.DurationSeconds)
+	var i Rendition
+	err := row.Scan(
+		&i.ID,
+		&i.JobID,
+		&i.Resolution,
+		&i.OutputKey,
+		&i.HlsPlaylistKey,
+		&i.DashRepresentationID,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.OutputSizeBytes,
+		&i.DurationSeconds,
+		&i.TargetBitrateKbps,
+		&i.VmafMean,
+		&i.VmafMin,
+		&i.VmafP5,
+		&i.PsnrMean,
+		&i.SsimMean,
+		&i.LoudnessInputI,
+		&i.LoudnessInputTp,
+		&i.LoudnessInputLra,
+		&i.LoudnessOutputI,
+		&i.LoudnessOutputTp,
+		&i.LoudnessOutputLra,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const countUnfinishedJobChunks = `-- name: CountUnfinishedJobChunks :one
+SELECT COUNT(*) FROM job_chunks
+WHERE job_id = $1 AND status <> 'completed'
+`
+
+func (q *Queries) CountUnfinishedJobChunks(ctx context.Context, jobID pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countUnfinishedJobChunks, jobID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createJobChunk = `-- name: CreateJobChunk :one
+INSERT INTO job_chunks (job_id, chunk_index, source_key, start_seconds, duration_seconds)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, job_id, chunk_index, source_key, start_seconds, duration_seconds, status, attempts, worker_id, error_message, started_at, finished_at, created_at, updated_at
+`
+
+type CreateJobChunkParams struct {
+	JobID           pgtype.UUID `json:"job_id"`
+	ChunkIndex      int32       `json:"chunk_index"`
+	SourceKey       string      `json:"source_key"`
+	StartSeconds    float64     `json:"start_seconds"`
+	DurationSeconds float64     `json:"duration_seconds"`
+}
+
+func (q *Queries) CreateJobChunk(ctx context.Context, arg CreateJobChunkParams) (JobChunk, error) {
+	row := q.db.QueryRow(ctx, createJobChunk, arg.JobID, arg.ChunkIndex, arg.SourceKey, arg.StartSeconds, arg.DurationSeconds)
+	var i JobChunk
+	err := row.Scan(
+		&i.ID,
+		&i.JobID,
+		&i.ChunkIndex,
+		&i.SourceKey,
+		&i.StartSeconds,
+		&i.DurationSeconds,
+		&i.Status,
+		&i.Attempts,
+		&i.WorkerID,
+		&i.ErrorMessage,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteJobChunks = `-- name: DeleteJobChunks :exec
+DELETE FROM job_chunks
+WHERE job_id = $1
+`
+
+// Drop the chunks of an earlier split before a job is split again
+func (q *Queries) DeleteJobChunks(ctx context.Context, jobID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteJobChunks, jobID)
+	return err
+}
+
+const deleteRendition = `-- name: DeleteRendition :exec
+DELETE FROM renditions
+WHERE id = $1
+`
+
+// Drop a rung the per-title analysis found redundant
+func (q *Queries) DeleteRendition(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteRendition, id)
+	return err
+}
+
+const failJob = `-- name: FailJob :one
+UPDATE jobs
+SET status = 'failed', error_message = $2, error_code = $3
+WHERE id = $1 AND status NOT IN ('completed', 'partial', 'cancelled')
+RETURNING id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at
+`
+
+type FailJobParams struct {
+	ID           pgtype.UUID `json:"id"`
+	ErrorMessage *string     `json:"error_message"`
+	ErrorCode    *string     `json:"error_code"`
+}
+
+// Mark a job as failed with its classified error; jobs that already finished are left alone
+func (q *Queries) FailJob(ctx context.Context, arg FailJobParams) (Job, error) {
+	row := q.db.QueryRow(ctx, failJob, arg.ID, arg.ErrorMessage, arg.ErrorCode)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.InputKey,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.ErrorCode,
+		&i.RetryCount,
+		&i.MaxRetries,
+		&i.StartedAt,
+		&i.WorkerID,
+		&i.Options,
+		&i.HlsMasterKey,
+		&i.DashManifestKey,
+		&i.Ladder,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const failJobChunk = `-- name: FailJobChunk :one
+UPDATE job_chunks
+SET status = 'failed',
+    finished_at = NOW(),
+    error_message = $2
+WHERE id = $1
+RETURNING id, job_id, chunk_index, source_key, start_seconds, duration_seconds, status, attempts, worker_id, error_message, started_at, finished_at, created_at, updated_at
+`
+
+type FailJobChunkParams struct {
+	ID           pgtype.UUID `json:"id"`
+	ErrorMessage *string     `json:"error_message"`
+}
+
+func (q *Queries) FailJobChunk(ctx context.Context, arg FailJobChunkParams) (JobChunk, error) {
+	row := q.db.QueryRow(ctx, failJobChunk, arg.ID, arg.ErrorMessage)
+	var i JobChunk
+	err := row.Scan(
+		&i.ID,
+		&i.JobID,
+		&i.ChunkIndex,
+		&i.SourceKey,
+		&i.StartSeconds,
+		&i.DurationSeconds,
+		&i.Status,
+		&i.Attempts,
+		&i.WorkerID,
+		&i.ErrorMessage,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const failRendition = `-- name: FailRendition :one
+UPDATE renditions
+SET status = 'failed',
+    finished_at = NOW(),
+    error_message = $2
+WHERE id = $1
+RETURNING id, job_id, resolution, output_key, hls_playlist_key, dash_representation_id, status, error_message, started_at, finished_at, output_size_bytes, duration_seconds, target_bitrate_kbps, vmaf_mean, vmaf_min, vmaf_p5, psnr_mean, ssim_mean, loudness_input_i, loudness_input_tp, loudness_input_lra, loudness_output_i, loudness_output_tp, loudness_output_lra, created_at
+`
+
+type FailRenditionParams struct {
+	ID           pgtype.UUID `json:"id"`
+	ErrorMessage *string     `json:"error_message"`
+}
+
+func (q *Queries) FailRendition(ctx context.Context, arg FailRenditionParams) (Rendition, error) {
+	row := q.db.QueryRow(ctx, failRendition, arg.ID, arg.ErrorMessage)
+	var i Rendition
+	err := row.Scan(
+		&i.ID,
+		&i.JobID,
+		&i.Resolution,
+		&i.OutputKey,
+		&i.HlsPlaylistKey,
+		&i.DashRepresentationID,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.OutputSizeBytes,
+		&i.DurationSeconds,
+		&i.TargetBitrateKbps,
+		&i.VmafMean,
+		&i.VmafMin,
+		&i.VmafP5,
+		&i.PsnrMean,
+		&i.SsimMean,
+		&i.LoudnessInputI,
+		&i.LoudnessInputTp,
+		&i.LoudnessInputLra,
+		&i.LoudnessOutputI,
+		&i.LoudnessOutputTp,
+		&i.LoudnessOutputLra,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const getJob = `-- name: GetJob :one
-SELECT id, input_key, status, error_message, retry_count, max_retries, started_at, worker_id, created_at, updated_at FROM jobs
+SELECT id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at FROM jobs
 WHERE id = $1
 `
 
@@ -24,10 +293,156 @@ func (q *Queries) GetJob(ctx context.Context, id pgtype.UUID) (Job, error) {
 		&i.InputKey,
 		&i.Status,
 		&i.ErrorMessage,
+		&i.ErrorCode,
 		&i.RetryCount,
 		&i.MaxRetries,
 		&i.StartedAt,
 		&i.WorkerID,
+		&i.Options,
+		&i.HlsMasterKey,
+		&i.DashManifestKey,
+		&i.Ladder,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getJobChunk = `-- name: GetJobChunk :one
+SELECT id, job_id, chunk_index, source_key, start_seconds, duration_seconds, status, attempts, worker_id, error_message, started_at, finished_at, created_at, updated_at FROM job_chunks
+WHERE job_id = $1 AND chunk_index = $2
+`
+
+type GetJobChunkParams struct {
+	JobID      pgtype.UUID `json:"job_id"`
+	ChunkIndex int32       `json:"chunk_index"`
+}
+
+func (q *Queries) GetJobChunk(ctx context.Context, arg GetJobChunkParams) (JobChunk, error) {
+	row := q.db.QueryRow(ctx, getJobChunk, arg.JobID, arg.ChunkIndex)
+	var i JobChunk
+	err := row.Scan(
+		&i.ID,
+		&i.JobID,
+		&i.ChunkIndex,
+		&i.SourceKey,
+		&i.StartSeconds,
+		&i.DurationSeconds,
+		&i.Status,
+		&i.Attempts,
+		&i.WorkerID,
+		&i.ErrorMessage,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getJobChunks = `-- name: GetJobChunks :many
+SELECT id, job_id, chunk_index, source_key, start_seconds, duration_seconds, status, attempts, worker_id, error_message, started_at, finished_at, created_at, updated_at FROM job_chunks
+WHERE job_id = $1
+ORDER BY chunk_index
+`
+
+func (q *Queries) GetJobChunks(ctx context.Context, jobID pgtype.UUID) ([]JobChunk, error) {
+	rows, err := q.db.Query(ctx, getJobChunks, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []JobChunk{}
+	for rows.Next() {
+		var i JobChunk
+		if err := rows.Scan(
+			&i.ID,
+			&i.JobID,
+			&i.ChunkIndex,
+			&i.SourceKey,
+			&i.StartSeconds,
+			&i.DurationSeconds,
+			&i.Status,
+			&i.Attempts,
+			&i.WorkerID,
+			&i.ErrorMessage,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMediaProbe = `-- name: GetMediaProbe :one
+SELECT job_id, duration_seconds, container, video_codec, audio_codec, width, height, frame_rate, bit_rate, rotation, audio_channels, audio_channel_layout, field_order, color_transfer, color_primaries, hdr_format, created_at FROM media_probes
+WHERE job_id = $1
+`
+
+func (q *Queries) GetMediaProbe(ctx context.Context, jobID pgtype.UUID) (MediaProbe, error) {
+	row := q.db.QueryRow(ctx, getMediaProbe, jobID)
+	var i MediaProbe
+	err := row.Scan(
+		&i.JobID,
+		&i.DurationSeconds,
+		&i.Container,
+		&i.VideoCodec,
+		&i.AudioCodec,
+		&i.Width,
+		&i.Height,
+		&i.FrameRate,
+		&i.BitRate,
+		&i.Rotation,
+		&i.AudioChannels,
+		&i.AudioChannelLayout,
+		&i.FieldOrder,
+		&i.ColorTransfer,
+		&i.ColorPrimaries,
+		&i.HdrFormat,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getProfile = `-- name: GetProfile :one
+SELECT name, scale, video_codec, audio_codec, preset, rate_control, crf, video_bitrate, max_rate, buf_size, keyframe_interval, audio_bitrate, max_fps, container, audio_only, audio_sample_rate, audio_channels, min_vmaf, watermark, loudness_target, true_peak_limit, deinterlace, tone_map, created_at, updated_at FROM profiles
+WHERE name = $1
+`
+
+func (q *Queries) GetProfile(ctx context.Context, name string) (Profile, error) {
+	row := q.db.QueryRow(ctx, getProfile, name)
+	var i Profile
+	err := row.Scan(
+		&i.Name,
+		&i.Scale,
+		&i.VideoCodec,
+		&i.AudioCodec,
+		&i.Preset,
+		&i.RateControl,
+		&i.Crf,
+		&i.VideoBitrate,
+		&i.MaxRate,
+		&i.BufSize,
+		&i.KeyframeInterval,
+		&i.AudioBitrate,
+		&i.MaxFps,
+		&i.Container,
+		&i.AudioOnly,
+		&i.AudioSampleRate,
+		&i.AudioChannels,
+		&i.MinVmaf,
+		&i.Watermark,
+		&i.LoudnessTarget,
+		&i.TruePeakLimit,
+		&i.Deinterlace,
+		&i.ToneMap,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -35,7 +450,7 @@ func (q *Queries) GetJob(ctx context.Context, id pgtype.UUID) (Job, error) {
 }
 
 const getRenditionsByJobID = `-- name: GetRenditionsByJobID :many
-SELECT id, job_id, resolution, output_key, created_at FROM renditions
+SELECT id, job_id, resolution, output_key, hls_playlist_key, dash_representation_id, status, error_message, started_at, finished_at, output_size_bytes, duration_seconds, target_bitrate_kbps, vmaf_mean, vmaf_min, vmaf_p5, psnr_mean, ssim_mean, loudness_input_i, loudness_input_tp, loudness_input_lra, loudness_output_i, loudness_output_tp, loudness_output_lra, created_at FROM renditions
 WHERE job_id = $1
 ORDER BY resolution
 `
@@ -54,7 +469,74 @@ func (q *Queries) GetRenditionsByJobID(ctx context.Context, jobID pgtype.UUID) (
 			&i.JobID,
 			&i.Resolution,
 			&i.OutputKey,
+			&i.HlsPlaylistKey,
+			&i.DashRepresentationID,
+			&i.Status,
+			&i.ErrorMessage,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.OutputSizeBytes,
+			&i.DurationSeconds,
+			&i.TargetBitrateKbps,
+			&i.VmafMean,
+			&i.VmafMin,
+			&i.VmafP5,
+			&i.PsnrMean,
+			&i.SsimMean,
+			&i.LoudnessInputI,
+			&i.LoudnessInputTp,
+			&i.LoudnessInputLra,
+			&i.LoudnessOutputI,
+			&i.LoudnessOutputTp,
+			&i.LoudnessOutputLra,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStaleJobChunks = `-- name: GetStaleJobChunks :many
+SELECT id, job_id, chunk_index, source_key, start_seconds, duration_seconds, status, attempts, worker_id, error_message, started_at, finished_at, created_at, updated_at FROM job_chunks
+WHERE (
+    (status = 'processing' AND started_at < NOW() - INTERVAL '10 minutes')
+    OR (status IN ('pending', 'failed') AND updated_at < NOW() - INTERVAL '10 minutes')
+)
+AND EXISTS (SELECT 1 FROM jobs WHERE jobs.id = job_chunks.job_id AND jobs.status = 'processing')
+LIMIT 100
+`
+
+// Find chunks of running jobs that have been encoding for too long (stuck workers), or waiting
+// to be encoded for too long (their queue entry or scheduled retry was lost)
+func (q *Queries) GetStaleJobChunks(ctx context.Context) ([]JobChunk, error) {
+	rows, err := q.db.Query(ctx, getStaleJobChunks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []JobChunk{}
+	for rows.Next() {
+		var i JobChunk
+		if err := rows.Scan(
+			&i.ID,
+			&i.JobID,
+			&i.ChunkIndex,
+			&i.SourceKey,
+			&i.StartSeconds,
+			&i.DurationSeconds,
+			&i.Status,
+			&i.Attempts,
+			&i.WorkerID,
+			&i.ErrorMessage,
+			&i.StartedAt,
+			&i.FinishedAt,
 			&i.CreatedAt,
+			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -67,13 +549,19 @@ func (q *Queries) GetRenditionsByJobID(ctx context.Context, jobID pgtype.UUID) (
 }
 
 const getStaleJobs = `-- name: GetStaleJobs :many
-SELECT id, input_key, status, error_message, retry_count, max_retries, started_at, worker_id, created_at, updated_at FROM jobs
+SELECT id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at FROM jobs
 WHERE status = 'processing'
 AND started_at < NOW() - INTERVAL '10 minutes'
+AND NOT EXISTS (
+    SELECT 1 FROM job_chunks WHERE job_chunks.job_id = jobs.id
+    AND (job_chunks.status <> 'completed' OR job_chunks.finished_at > NOW() - INTERVAL '10 minutes')
+)
 LIMIT 100
 `
 
-// Find jobs that have been processing for too long (stuck workers)
+// Find jobs that have been processing for too long (stuck workers).
+// A split job's chunks are timed out one by one (GetStaleJobChunks), so it is only found here
+// once every chunk was encoded over 10 minutes ago: its stitch stalled or was never queued.
 func (q *Queries) GetStaleJobs(ctx context.Context) ([]Job, error) {
 	rows, err := q.db.Query(ctx, getStaleJobs)
 	if err != nil {
@@ -88,10 +576,15 @@ func (q *Queries) GetStaleJobs(ctx context.Context) ([]Job, error) {
 			&i.InputKey,
 			&i.Status,
 			&i.ErrorMessage,
+			&i.ErrorCode,
 			&i.RetryCount,
 			&i.MaxRetries,
 			&i.StartedAt,
 			&i.WorkerID,
+			&i.Options,
+			&i.HlsMasterKey,
+			&i.DashManifestKey,
+			&i.Ladder,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -111,8 +604,8 @@ SET status = 'queued',
     retry_count = retry_count + 1,
     worker_id = NULL,
     started_at = NULL
-WHERE id = $1
-RETURNING id, input_key, status, error_message, retry_count, max_retries, started_at, worker_id, created_at, updated_at
+WHERE id = $1 AND status <> 'cancelled'
+RETURNING id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at
 `
 
 // Increment retry count and reset status to queued for retry
@@ -124,26 +617,73 @@ func (q *Queries) IncrementRetryCount(ctx context.Context, id pgtype.UUID) (Job,
 		&i.InputKey,
 		&i.Status,
 		&i.ErrorMessage,
+		&i.ErrorCode,
 		&i.RetryCount,
 		&i.MaxRetries,
 		&i.StartedAt,
 		&i.WorkerID,
+		&i.Options,
+		&i.HlsMasterKey,
+		&i.DashManifestKey,
+		&i.Ladder,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
+const releaseWorkerJob = `-- name: ReleaseWorkerJob :exec
+UPDATE jobs
+SET status = 'queued',
+    worker_id = NULL,
+    started_at = NULL
+WHERE id = $1 AND status = 'processing' AND worker_id = $2
+`
+
+type ReleaseWorkerJobParams struct {
+	ID       pgtype.UUID `json:"id"`
+	WorkerID *string     `json:"worker_id"`
+}
+
+// Return a job claimed by a dead worker to queued so its recovered queue entry can claim it again
+func (q *Queries) ReleaseWorkerJob(ctx context.Context, arg ReleaseWorkerJobParams) error {
+	_, err := q.db.Exec(ctx, releaseWorkerJob, arg.ID, arg.WorkerID)
+	return err
+}
+
+const releaseWorkerJobChunk = `-- name: ReleaseWorkerJobChunk :exec
+UPDATE job_chunks
+SET status = 'pending',
+    worker_id = NULL,
+    started_at = NULL
+WHERE job_id = $1 AND chunk_index = $2 AND status = 'processing' AND worker_id = $3
+`
+
+type ReleaseWorkerJobChunkParams struct {
+	JobID      pgtype.UUID `json:"job_id"`
+	ChunkIndex int32       `json:"chunk_index"`
+	WorkerID   *string     `json:"worker_id"`
+}
+
+// Return a chunk claimed by a dead worker to pending so its recovered queue entry can claim it again
+func (q *Queries) ReleaseWorkerJobChunk(ctx context.Context, arg ReleaseWorkerJobChunkParams) error {
+	_, err := q.db.Exec(ctx, releaseWorkerJobChunk, arg.JobID, arg.ChunkIndex, arg.WorkerID)
+	return err
+}
+
 const resetStalledJob = `-- name: ResetStalledJob :one
 UPDATE jobs
 SET status = 'queued',
+    retry_count = retry_count + 1,
     worker_id = NULL,
     started_at = NULL
 WHERE id = $1 AND status = 'processing'
-RETURNING id, input_key, status, error_message, retry_count, max_retries, started_at, worker_id, created_at, updated_at
+AND started_at < NOW() - INTERVAL '10 minutes'
+RETURNING id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at
 `
 
-// Reset a stalled job back to queued status
+// Reset a stalled job back to queued status, counting the lost attempt as a retry.
+// A job reclaimed since it was found stale is left alone.
 func (q *Queries) ResetStalledJob(ctx context.Context, id pgtype.UUID) (Job, error) {
 	row := q.db.QueryRow(ctx, resetStalledJob, id)
 	var i Job
@@ -152,24 +692,155 @@ func (q *Queries) ResetStalledJob(ctx context.Context, id pgtype.UUID) (Job, err
 		&i.InputKey,
 		&i.Status,
 		&i.ErrorMessage,
+		&i.ErrorCode,
 		&i.RetryCount,
 		&i.MaxRetries,
 		&i.StartedAt,
 		&i.WorkerID,
+		&i.Options,
+		&i.HlsMasterKey,
+		&i.DashManifestKey,
+		&i.Ladder,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
-const startJobProcessing = `-- name: StartJobProcessing :one
-UPDATE jobs
-SET status = 'processing',
-    worker_id = $2,
+const resetStalledJobChunk = `-- name: ResetStalledJobChunk :one
+UPDATE job_chunks
+SET status = 'pending',
+    worker_id = NULL,
+    started_at = NULL
+WHERE id = $1
+AND (
+    (status = 'processing' AND started_at < NOW() - INTERVAL '10 minutes')
+    OR (status IN ('pending', 'failed') AND updated_at < NOW() - INTERVAL '10 minutes')
+)
+RETURNING id, job_id, chunk_index, source_key, start_seconds, duration_seconds, status, attempts, worker_id, error_message, started_at, finished_at, created_at, updated_at
+`
+
+// Return a stalled chunk to pending before it is queued again; a claim already counted any lost
+// attempt. Bumping updated_at keeps the chunk from being found stale again while it waits.
+// A chunk claimed since it was found stale is left alone.
+func (q *Queries) ResetStalledJobChunk(ctx context.Context, id pgtype.UUID) (JobChunk, error) {
+	row := q.db.QueryRow(ctx, resetStalledJobChunk, id)
+	var i JobChunk
+	err := row.Scan(
+		&i.ID,
+		&i.JobID,
+		&i.ChunkIndex,
+		&i.SourceKey,
+		&i.StartSeconds,
+		&i.DurationSeconds,
+		&i.Status,
+		&i.Attempts,
+		&i.WorkerID,
+		&i.ErrorMessage,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const setRenditionTargetBitrate = `-- name: SetRenditionTargetBitrate :one
+UPDATE renditions
+SET target_bitrate_kbps = $2
+WHERE id = $1
+RETURNING id, job_id, resolution, output_key, hls_playlist_key, dash_representation_id, status, error_message, started_at, finished_at, output_size_bytes, duration_seconds, target_bitrate_kbps, vmaf_mean, vmaf_min, vmaf_p5, psnr_mean, ssim_mean, loudness_input_i, loudness_input_tp, loudness_input_lra, loudness_output_i, loudness_output_tp, loudness_output_lra, created_at
+`
+
+type SetRenditionTargetBitrateParams struct {
+	ID                pgtype.UUID `json:"id"`
+	TargetBitrateKbps *int32      `json:"target_bitrate_kbps"`
+}
+
+func (q *Queries) SetRenditionTargetBitrate(ctx context.Context, arg SetRenditionTargetBitrateParams) (Rendition, error) {
+	row := q.db.QueryRow(ctx, setRenditionTargetBitrate, arg.ID, arg.TargetBitrateKbps)
+	var i Rendition
+	err := row.Scan(
+		&i.ID,
+		&i.JobID,
+		&i.Resolution,
+		&i.OutputKey,
+		&i.HlsPlaylistKey,
+		&i.DashRepresentationID,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.OutputSizeBytes,
+		&i.DurationSeconds,
+		&i.TargetBitrateKbps,
+		&i.VmafMean,
+		&i.VmafMin,
+		&i.VmafP5,
+		&i.PsnrMean,
+		&i.SsimMean,
+		&i.LoudnessInputI,
+		&i.LoudnessInputTp,
+		&i.LoudnessInputLra,
+		&i.LoudnessOutputI,
+		&i.LoudnessOutputTp,
+		&i.LoudnessOutputLra,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const startJobChunk = `-- name: StartJobChunk :one
+UPDATE job_chunks
+SET status = 'processing',
+    attempts = attempts + 1,
+    worker_id = $3,
     started_at = NOW(),
+    finished_at = NULL,
     error_message = NULL
+WHERE job_id = $1 AND chunk_index = $2
+AND (status IN ('pending', 'failed') OR (status = 'processing' AND started_at < NOW() - INTERVAL '10 minutes'))
+RETURNING id, job_id, chunk_index, source_key, start_seconds, duration_seconds, status, attempts, worker_id, error_message, started_at, finished_at, created_at, updated_at
+`
+
+type StartJobChunkParams struct {
+	JobID      pgtype.UUID `json:"job_id"`
+	ChunkIndex int32       `json:"chunk_index"`
+	WorkerID   *string     `json:"worker_id"`
+}
+
+// Atomically claim a pending or failed chunk for encoding, or one whose worker stalled
+func (q *Queries) StartJobChunk(ctx context.Context, arg StartJobChunkParams) (JobChunk, error) {
+	row := q.db.QueryRow(ctx, startJobChunk, arg.JobID, arg.ChunkIndex, arg.WorkerID)
+	var i JobChunk
+	err := row.Scan(
+		&i.ID,
+		&i.JobID,
+		&i.ChunkIndex,
+		&i.SourceKey,
+		&i.StartSeconds,
+		&i.DurationSeconds,
+		&i.Status,
+		&i.Attempts,
+		&i.WorkerID,
+		&i.ErrorMessage,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const startJobProcessing = `-- name: StartJobProcessing :one
+UPDATE jobs
+SET status = 'processing',
+    worker_id = $2,
+    started_at = NOW(),
+    error_message = NULL,
+    error_code = NULL
 WHERE id = $1 AND (status = 'queued' OR (status = 'processing' AND started_at < NOW() - INTERVAL '10 minutes'))
-RETURNING id, input_key, status, error_message, retry_count, max_retries, started_at, worker_id, created_at, updated_at
+RETURNING id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at
 `
 
 type StartJobProcessingParams struct {
@@ -186,21 +857,216 @@ func (q *Queries) StartJobProcessing(ctx context.Context, arg StartJobProcessing
 		&i.InputKey,
 		&i.Status,
 		&i.ErrorMessage,
+		&i.ErrorCode,
 		&i.RetryCount,
 		&i.MaxRetries,
 		&i.StartedAt,
 		&i.WorkerID,
+		&i.Options,
+		&i.HlsMasterKey,
+		&i.DashManifestKey,
+		&i.Ladder,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
-const updateJobStatus = `-- name: UpdateJobStatus :one
+const startJobStitch = `-- name: StartJobStitch :one
+UPDATE jobs
+SET status = 'processing',
+    worker_id = $2,
+    started_at = NOW(),
+    error_message = NULL,
+    error_code = NULL
+WHERE id = $1 AND status IN ('processing', 'queued')
+AND NOT EXISTS (SELECT 1 FROM job_chunks WHERE job_chunks.job_id = jobs.id AND job_chunks.status <> 'completed')
+RETURNING id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at
+`
+
+type StartJobStitchParams struct {
+	ID       pgtype.UUID `json:"id"`
+	WorkerID *string     `json:"worker_id"`
+}
+
+// Claim a chunked job for stitching once every chunk is encoded. A retried stitch finds the job re-queued.
+func (q *Queries) StartJobStitch(ctx context.Context, arg StartJobStitchParams) (Job, error) {
+	row := q.db.QueryRow(ctx, startJobStitch, arg.ID, arg.WorkerID)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.InputKey,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.ErrorCode,
+		&i.RetryCount,
+		&i.MaxRetries,
+		&i.StartedAt,
+		&i.WorkerID,
+		&i.Options,
+		&i.HlsMasterKey,
+		&i.DashManifestKey,
+		&i.Ladder,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const startRendition = `-- name: StartRendition :one
+UPDATE renditions
+SET status = 'processing',
+    started_at = NOW(),
+    finished_at = NULL,
+    error_message = NULL
+WHERE id = $1
+RETURNING id, job_id, resolution, output_key, hls_playlist_key, dash_representation_id, status, error_message, started_at, finished_at, output_size_bytes, duration_seconds, target_bitrate_kbps, vmaf_mean, vmaf_min, vmaf_p5, psnr_mean, ssim_mean, loudness_input_i, loudness_input_tp, loudness_input_lra, loudness_output_i, loudness_output_tp, loudness_output_lra, created_at
+`
+
+// Mark a rendition as encoding, clearing the outcome of any previous attempt
+func (q *Queries) StartRendition(ctx context.Context, id pgtype.UUID) (Rendition, error) {
+	row := q.db.QueryRow(ctx, startRendition, id)
+	var i Rendition
+	err := row.Scan(
+		&i.ID,
+		&i.JobID,
+		&i.Resolution,
+		&i.OutputKey,
+		&i.HlsPlaylistKey,
+		&i.DashRepresentationID,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.OutputSizeBytes,
+		&i.DurationSeconds,
+		&i.TargetBitrateKbps,
+		&i.VmafMean,
+		&i.VmafMin,
+		&i.VmafP5,
+		&i.PsnrMean,
+		&i.SsimMean,
+		&i.LoudnessInputI,
+		&i.LoudnessInputTp,
+		&i.LoudnessInputLra,
+		&i.LoudnessOutputI,
+		&i.LoudnessOutputTp,
+		&i.LoudnessOutputLra,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const updateJobDASHManifestKey = `-- name: UpdateJobDASHManifestKey :one
+UPDATE jobs
+SET dash_manifest_key = $2
+WHERE id = $1
+RETURNING id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at
+`
+
+type UpdateJobDASHManifestKeyParams struct {
+	ID              pgtype.UUID `json:"id"`
+	DashManifestKey *string     `json:"dash_manifest_key"`
+}
+
+func (q *Queries) UpdateJobDASHManifestKey(ctx context.Context, arg UpdateJobDASHManifestKeyParams) (Job, error) {
+	row := q.db.QueryRow(ctx, updateJobDASHManifestKey, arg.ID, arg.DashManifestKey)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.InputKey,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.ErrorCode,
+		&i.RetryCount,
+		&i.MaxRetries,
+		&i.StartedAt,
+		&i.WorkerID,
+		&i.Options,
+		&i.HlsMasterKey,
+		&i.DashManifestKey,
+		&i.Ladder,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const updateJobHLSMasterKey = `-- name: UpdateJobHLSMasterKey :one
+UPDATE jobs
+SET hls_master_key = $2
+WHERE id = $1
+RETURNING id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at
+`
+
+type UpdateJobHLSMasterKeyParams struct {
+	ID           pgtype.UUID `json:"id"`
+	HlsMasterKey *string     `json:"hls_master_key"`
+}
+
+func (q *Queries) UpdateJobHLSMasterKey(ctx context.Context, arg UpdateJobHLSMasterKeyParams) (Job, error) {
+	row := q.db.QueryRow(ctx, updateJobHLSMasterKey, arg.ID, arg.HlsMasterKey)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.InputKey,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.ErrorCode,
+		&i.RetryCount,
+		&i.MaxRetries,
+		&i.StartedAt,
+		&i.WorkerID,
+		&i.Options,
+		&i.HlsMasterKey,
+		&i.DashManifestKey,
+		&i.Ladder,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const updateJobLadder = `-- name: UpdateJobLadder :one
 UPDATE jobs
-SET status = $2, error_message = $3
+SET ladder = $2
 WHERE id = $1
-RETURNING id, input_key, status, error_message, retry_count, max_retries, started_at, worker_id, created_at, updated_at
+RETURNING id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at
+`
+
+type UpdateJobLadderParams struct {
+	ID     pgtype.UUID `json:"id"`
+	Ladder []byte      `json:"ladder"`
+}
+
+func (q *Queries) UpdateJobLadder(ctx context.Context, arg UpdateJobLadderParams) (Job, error) {
+	row := q.db.QueryRow(ctx, updateJobLadder, arg.ID, arg.Ladder)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.InputKey,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.ErrorCode,
+		&i.RetryCount,
+		&i.MaxRetries,
+		&i.StartedAt,
+		&i.WorkerID,
+		&i.Options,
+		&i.HlsMasterKey,
+		&i.DashManifestKey,
+		&i.Ladder,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const updateJobStatus = `-- name: UpdateJobStatus :one
+UPDATE jobs
+SET status = $2, error_message = $3, error_code = NULL
+WHERE id = $1 AND status <> 'cancelled'
+RETURNING id, input_key, status, error_message, error_code, retry_count, max_retries, started_at, worker_id, options, hls_master_key, dash_manifest_key, ladder, created_at, updated_at
 `
 
 type UpdateJobStatusParams struct {
@@ -209,6 +1075,8 @@ type UpdateJobStatusParams struct {
 	ErrorMessage *string     `json:"error_message"`
 }
 
+// Cancellation is final, so a cancelled job is never moved to another status.
+// Failures with an error code go through FailJob.
 func (q *Queries) UpdateJobStatus(ctx context.Context, arg UpdateJobStatusParams) (Job, error) {
 	row := q.db.QueryRow(ctx, updateJobStatus, arg.ID, arg.Status, arg.ErrorMessage)
 	var i Job
@@ -217,21 +1085,171 @@ func (q *Queries) UpdateJobStatus(ctx context.Context, arg UpdateJobStatusParams
 		&i.InputKey,
 		&i.Status,
 		&i.ErrorMessage,
+		&i.ErrorCode,
 		&i.RetryCount,
 		&i.MaxRetries,
 		&i.StartedAt,
 		&i.WorkerID,
+		&i.Options,
+		&i.HlsMasterKey,
+		&i.DashManifestKey,
+		&i.Ladder,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
+const updateRenditionDASHRepresentation = `-- name: UpdateRenditionDASHRepresentation :one
+UPDATE renditions
+SET dash_representation_id = $2
+WHERE id = $1
+RETURNING id, job_id, resolution, output_key, hls_playlist_key, dash_representation_id, status, error_message, started_at, finished_at, output_size_bytes, duration_seconds, target_bitrate_kbps, vmaf_mean, vmaf_min, vmaf_p5, psnr_mean, ssim_mean, loudness_input_i, loudness_input_tp, loudness_input_lra, loudness_output_i, loudness_output_tp, loudness_output_lra, created_at
+`
+
+type UpdateRenditionDASHRepresentationParams struct {
+	ID                   pgtype.UUID `json:"id"`
+	DashRepresentationID *string     `json:"dash_representation_id"`
+}
+
+func (q *Queries) UpdateRenditionDASHRepresentation(ctx context.Context, arg UpdateRenditionDASHRepresentationParams) (Rendition, error) {
+	row := q.db.QueryRow(ctx, updateRenditionDASHRepresentation, arg.ID, arg.DashRepresentationID)
+	var i Rendition
+	err := row.Scan(
+		&i.ID,
+		&i.JobID,
+		&i.Resolution,
+		&i.OutputKey,
+		&i.HlsPlaylistKey,
+		&i.DashRepresentationID,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.OutputSizeBytes,
+		&i.DurationSeconds,
+		&i.TargetBitrateKbps,
+		&i.VmafMean,
+		&i.VmafMin,
+		&i.VmafP5,
+		&i.PsnrMean,
+		&i.SsimMean,
+		&i.LoudnessInputI,
+		&i.LoudnessInputTp,
+		&i.LoudnessInputLra,
+		&i.LoudnessOutputI,
+		&i.LoudnessOutputTp,
+		&i.LoudnessOutputLra,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const updateRenditionHLSPlaylistKey = `-- name: UpdateRenditionHLSPlaylistKey :one
+UPDATE renditions
+SET hls_playlist_key = $2
+WHERE id = $1
+RETURNING id, job_id, resolution, output_key, hls_playlist_key, dash_representation_id, status, error_message, started_at, finished_at, output_size_bytes, duration_seconds, target_bitrate_kbps, vmaf_mean, vmaf_min, vmaf_p5, psnr_mean, ssim_mean, loudness_input_i, loudness_input_tp, loudness_input_lra, loudness_output_i, loudness_output_tp, loudness_output_lra, created_at
+`
+
+type UpdateRenditionHLSPlaylistKeyParams struct {
+	ID             pgtype.UUID `json:"id"`
+	HlsPlaylistKey *string     `json:"hls_playlist_key"`
+}
+
+func (q *Queries) UpdateRenditionHLSPlaylistKey(ctx context.Context, arg UpdateRenditionHLSPlaylistKeyParams) (Rendition, error) {
+	row := q.db.QueryRow(ctx, updateRenditionHLSPlaylistKey, arg.ID, arg.HlsPlaylistKey)
+	var i Rendition
+	err := row.Scan(
+		&i.ID,
+		&i.JobID,
+		&i.Resolution,
+		&i.OutputKey,
+		&i.HlsPlaylistKey,
+		&i.DashRepresentationID,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.OutputSizeBytes,
+		&i.DurationSeconds,
+		&i.TargetBitrateKbps,
+		&i.VmafMean,
+		&i.VmafMin,
+		&i.VmafP5,
+		&i.PsnrMean,
+		&i.SsimMean,
+		&i.LoudnessInputI,
+		&i.LoudnessInputTp,
+		&i.LoudnessInputLra,
+		&i.LoudnessOutputI,
+		&i.LoudnessOutputTp,
+		&i.LoudnessOutputLra,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const updateRenditionLoudness = `-- name: UpdateRenditionLoudness :one
+UPDATE renditions
+SET loudness_input_i = $2,
+    loudness_input_tp = $3,
+    loudness_input_lra = $4,
+    loudness_output_i = $5,
+    loudness_output_tp = $6,
+    loudness_output_lra = $7
+WHERE id = $1
+RETURNING id, job_id, resolution, output_key, hls_playlist_key, dash_representation_id, status, error_message, started_at, finished_at, output_size_bytes, duration_seconds, target_bitrate_kbps, vmaf_mean, vmaf_min, vmaf_p5, psnr_mean, ssim_mean, loudness_input_i, loudness_input_tp, loudness_input_lra, loudness_output_i, loudness_output_tp, loudness_output_lra, created_at
+`
+
+type UpdateRenditionLoudnessParams struct {
+	ID                pgtype.UUID `json:"id"`
+	LoudnessInputI    *float64    `json:"loudness_input_i"`
+	LoudnessInputTp   *float64    `json:"loudness_input_tp"`
+	LoudnessInputLra  *float64    `json:"loudness_input_lra"`
+	LoudnessOutputI   *float64    `json:"loudness_output_i"`
+	LoudnessOutputTp  *float64    `json:"loudness_output_tp"`
+	LoudnessOutputLra *float64    `json:"loudness_output_lra"`
+}
+
+func (q *Queries) UpdateRenditionLoudness(ctx context.Context, arg UpdateRenditionLoudnessParams) (Rendition, error) {
+	row := q.db.QueryRow(ctx, updateRenditionLoudness, arg.ID, arg.LoudnessInputI, arg.LoudnessInputTp, arg.LoudnessInputLra, arg.LoudnessOutputI, arg.LoudnessOutputTp, arg.LoudnessOutputLra)
+	var i Rendition
+	err := row.Scan(
+		&i.ID,
+		&i.JobID,
+		&i.Resolution,
+		&i.OutputKey,
+		&i.HlsPlaylistKey,
+		&i.DashRepresentationID,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.OutputSizeBytes,
+		&i.DurationSeconds,
+		&i.TargetBitrateKbps,
+		&i.VmafMean,
+		&i.VmafMin,
+		&i.VmafP5,
+		&i.PsnrMean,
+		&i.SsimMean,
+		&i.LoudnessInputI,
+		&i.LoudnessInputTp,
+		&i.LoudnessInputLra,
+		&i.LoudnessOutputI,
+		&i.LoudnessOutputTp,
+		&i.LoudnessOutputLra,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const updateRenditionOutputKey = `-- name: UpdateRenditionOutputKey :one
 UPDATE renditions
 SET output_key = $2
 WHERE id = $1
-RETURNING id, job_id, resolution, output_key, created_at
+RETURNING id, job_id, resolution, output_key, hls_playlist_key, dash_representation_id, status, error_message, started_at, finished_at, output_size_bytes, duration_seconds, target_bitrate_kbps, vmaf_mean, vmaf_min, vmaf_p5, psnr_mean, ssim_mean, loudness_input_i, loudness_input_tp, loudness_input_lra, loudness_output_i, loudness_output_tp, loudness_output_lra, created_at
 `
 
 type UpdateRenditionOutputKeyParams struct {
@@ -247,6 +1265,183 @@ func (q *Queries) UpdateRenditionOutputKey(ctx context.Context, arg UpdateRendit
 		&i.JobID,
 		&i.Resolution,
 		&i.OutputKey,
+		&i.HlsPlaylistKey,
+		&i.DashRepresentationID,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.OutputSizeBytes,
+		&i.DurationSeconds,
+		&i.TargetBitrateKbps,
+		&i.VmafMean,
+		&i.VmafMin,
+		&i.VmafP5,
+		&i.PsnrMean,
+		&i.SsimMean,
+		&i.LoudnessInputI,
+		&i.LoudnessInputTp,
+		&i.LoudnessInputLra,
+		&i.LoudnessOutputI,
+		&i.LoudnessOutputTp,
+		&i.LoudnessOutputLra,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const updateRenditionQuality = `-- name: UpdateRenditionQuality :one
+UPDATE renditions
+SET vmaf_mean = $2,
+    vmaf_min = $3,
+    vmaf_p5 = $4,
+    psnr_mean = $5,
+    ssim_mean = $6
+WHERE id = $1
+RETURNING id, job_id, resolution, output_key, hls_playlist_key, dash_representation_id, status, error_message, started_at, finished_at, output_size_bytes, duration_seconds, target_bitrate_kbps, vmaf_mean, vmaf_min, vmaf_p5, psnr_mean, ssim_mean, loudness_input_i, loudness_input_tp, loudness_input_lra, loudness_output_i, loudness_output_tp, loudness_output_lra, created_at
+`
+
+type UpdateRenditionQualityParams struct {
+	ID       pgtype.UUID `json:"id"`
+	VmafMean *float64    `json:"vmaf_mean"`
+	VmafMin  *float64    `json:"vmaf_min"`
+	VmafP5   *float64    `json:"vmaf_p5"`
+	PsnrMean *float64    `json:"psnr_mean"`
+	SsimMean *float64    `json:"ssim_mean"`
+}
+
+func (q *Queries) UpdateRenditionQuality(ctx context.Context, arg UpdateRenditionQualityParams) (Rendition, error) {
+	row := q.db.QueryRow(ctx, updateRenditionQuality, arg.ID, arg.VmafMean, arg.VmafMin, arg.VmafP5, arg.PsnrMean, arg.SsimMean)
+	var i Rendition
+	err := row.Scan(
+		&i.ID,
+		&i.JobID,
+		&i.Resolution,
+		&i.OutputKey,
+		&i.HlsPlaylistKey,
+		&i.DashRepresentationID,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.OutputSizeBytes,
+		&i.DurationSeconds,
+		&i.TargetBitrateKbps,
+		&i.VmafMean,
+		&i.VmafMin,
+		&i.VmafP5,
+		&i.PsnrMean,
+		&i.SsimMean,
+		&i.LoudnessInputI,
+		&i.LoudnessInputTp,
+		&i.LoudnessInputLra,
+		&i.LoudnessOutputI,
+		&i.LoudnessOutputTp,
+		&i.LoudnessOutputLra,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const upsertJobArtifact = `-- name: UpsertJobArtifact :one
+INSERT INTO job_artifacts (job_id, kind, output_key, content_type, language)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (job_id, output_key) DO UPDATE
+SET kind = EXCLUDED.kind,
+    content_type = EXCLUDED.content_type,
+    language = EXCLUDED.language
+RETURNING id, job_id, kind, output_key, content_type, language, created_at
+`
+
+type UpsertJobArtifactParams struct {
+	JobID       pgtype.UUID `json:"job_id"`
+	Kind        string      `json:"kind"`
+	OutputKey   string      `json:"output_key"`
+	ContentType string      `json:"content_type"`
+	Language    *string     `json:"language"`
+}
+
+func (q *Queries) UpsertJobArtifact(ctx context.Context, arg UpsertJobArtifactParams) (JobArtifact, error) {
+	row := q.db.QueryRow(ctx, upsertJobArtifact, arg.JobID, arg.Kind, arg.OutputKey, arg.ContentType, arg.Language)
+	var i JobArtifact
+	err := row.Scan(
+		&i.ID,
+		&i.JobID,
+		&i.Kind,
+		&i.OutputKey,
+		&i.ContentType,
+		&i.Language,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const upsertMediaProbe = `-- name: UpsertMediaProbe :one
+INSERT INTO media_probes (
+    job_id, duration_seconds, container, video_codec, audio_codec, width, height,
+    frame_rate, bit_rate, rotation, audio_channels, audio_channel_layout,
+    field_order, color_transfer, color_primaries, hdr_format
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+ON CONFLICT (job_id) DO UPDATE
+SET duration_seconds = EXCLUDED.duration_seconds,
+    container = EXCLUDED.container,
+    video_codec = EXCLUDED.video_codec,
+    audio_codec = EXCLUDED.audio_codec,
+    width = EXCLUDED.width,
+    height = EXCLUDED.height,
+    frame_rate = EXCLUDED.frame_rate,
+    bit_rate = EXCLUDED.bit_rate,
+    rotation = EXCLUDED.rotation,
+    audio_channels = EXCLUDED.audio_channels,
+    audio_channel_layout = EXCLUDED.audio_channel_layout,
+    field_order = EXCLUDED.field_order,
+    color_transfer = EXCLUDED.color_transfer,
+    color_primaries = EXCLUDED.color_primaries,
+    hdr_format = EXCLUDED.hdr_format
+RETURNING job_id, duration_seconds, container, video_codec, audio_codec, width, height, frame_rate, bit_rate, rotation, audio_channels, audio_channel_layout, field_order, color_transfer, color_primaries, hdr_format, created_at
+`
+
+type UpsertMediaProbeParams struct {
+	JobID              pgtype.UUID `json:"job_id"`
+	DurationSeconds    *float64    `json:"duration_seconds"`
+	Container          *string     `json:"container"`
+	VideoCodec         *string     `json:"video_codec"`
+	AudioCodec         *string     `json:"audio_codec"`
+	Width              *int32      `json:"width"`
+	Height             *int32      `json:"height"`
+	FrameRate          *float64    `json:"frame_rate"`
+	BitRate            *int64      `json:"bit_rate"`
+	Rotation           int32       `json:"rotation"`
+	AudioChannels      *int32      `json:"audio_channels"`
+	AudioChannelLayout *string     `json:"audio_channel_layout"`
+	FieldOrder         *string     `json:"field_order"`
+	ColorTransfer      *string     `json:"color_transfer"`
+	ColorPrimaries     *string     `json:"color_primaries"`
+	HdrFormat          *string     `json:"hdr_format"`
+}
+
+// Store the ffprobe results for a job's input (replaced on retry)
+func (q *Queries) UpsertMediaProbe(ctx context.Context, arg UpsertMediaProbeParams) (MediaProbe, error) {
+	row := q.db.QueryRow(ctx, upsertMediaProbe, arg.JobID, arg.DurationSeconds, arg.Container, arg.VideoCodec, arg.AudioCodec, arg.Width, arg.Height, arg.FrameRate, arg.BitRate, arg.Rotation, arg.AudioChannels, arg.AudioChannelLayout, arg.FieldOrder, arg.ColorTransfer, arg.ColorPrimaries, arg.HdrFormat)
+	var i MediaProbe
+	err := row.Scan(
+		&i.JobID,
+		&i.DurationSeconds,
+		&i.Container,
+		&i.VideoCodec,
+		&i.AudioCodec,
+		&i.Width,
+		&i.Height,
+		&i.FrameRate,
+		&i.BitRate,
+		&i.Rotation,
+		&i.AudioChannels,
+		&i.AudioChannelLayout,
+		&i.FieldOrder,
+		&i.ColorTransfer,
+		&i.ColorPrimaries,
+		&i.HdrFormat,
 		&i.CreatedAt,
 	)
 	return i, err
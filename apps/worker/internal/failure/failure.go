@@ -0,0 +1,143 @@
+// Package failure classifies job errors as permanent or transient and gives each a
+// machine-readable code. Permanent failures fail the job at once instead of being retried.
+package failure
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/storage"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/transcoder"
+)
+
+// Code identifies the kind of failure stored on a failed job (jobs.error_code)
+type Code string
+
+// Permanent failures
+const (
+	CodeInvalidInput      Code = "invalid_input"       // The input can't be decoded or has no usable streams
+	CodeInvalidOptions    Code = "invalid_options"     // Job options or a profile ask for something that can't be done
+	CodeUnsupported       Code = "unsupported"         // FFmpeg lacks an encoder, filter or option the profile needs
+	CodeObjectNotFound    Code = "object_not_found"    // An input or intermediate file is missing from storage
+	CodeStorageRejected   Code = "storage_rejected"    // S3 refused the request, e.g., access denied
+	CodeNotFound          Code = "not_found"           // A database row the job needs no longer exists
+	CodeDatabaseError     Code = "database_error"      // A query failed in a way repeating it won't fix
+	CodeQualityBelowFloor Code = "quality_below_floor" // Renditions scored under the profile's VMAF threshold
+)
+
+// Transient failures
+const (
+	CodeStorageUnavailable  Code = "storage_unavailable"  // S3 errored, throttled or could not be reached
+	CodeDatabaseUnavailable Code = "database_unavailable" // Postgres could not be reached or aborted the query
+	CodeEncoderFailed       Code = "encoder_failed"       // FFmpeg failed for a reason not known to be permanent
+	CodeTimeout             Code = "timeout"
+	CodeStalled             Code = "stalled"  // The worker processing the job stopped without finishing it
+	CodeInternal            Code = "internal" // Anything unclassified
+)
+
+// Error attaches a classification to an error raised by the worker itself
+type Error struct {
+	Code      Code
+	Permanent bool
+	Err       error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Permanent marks err as a failure that retrying cannot fix
+func Permanent(code Code, err error) error {
+	return &Error{Code: code, Permanent: true, Err: err}
+}
+
+// Classify returns the code of err and whether it is permanent. The wrap chain is searched
+// from the outside in and the first recognized error decides. Joined errors (several
+// renditions failing, say) are permanent only when every one of them is; unrecognized
+// errors are transient, so the retry limit still bounds them.
+func Classify(err error) (Code, bool) {
+	for err != nil {
+		if code, permanent, ok := classify(err); ok {
+			return code, permanent
+		}
+		switch e := err.(type) {
+		case interface{ Unwrap() []error }:
+			return classifyAll(e.Unwrap())
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			err = nil
+		}
+	}
+	return CodeInternal, false
+}
+
+func classifyAll(errs []error) (Code, bool) {
+	if len(errs) == 0 {
+		return CodeInternal, false
+	}
+	first, _ := Classify(errs[0])
+	for _, err := range errs {
+		if code, permanent := Classify(err); !permanent {
+			return code, false
+		}
+	}
+	return first, true
+}
+
+// classify recognizes a single error without looking at what it wraps
+func classify(err error) (code Code, permanent, ok bool) {
+	switch e := err.(type) {
+	case *Error:
+		return e.Code, e.Permanent, true
+	case *transcoder.CommandError:
+		switch {
+		case e.InvalidInput():
+			return CodeInvalidInput, true, true
+		case e.Unsupported():
+			return CodeUnsupported, true, true
+		default:
+			return CodeEncoderFailed, false, true
+		}
+	case *transcoder.ProfileError:
+		return CodeInvalidOptions, true, true
+	case *storage.Error:
+		switch {
+		case e.NotFound():
+			return CodeObjectNotFound, true, true
+		case e.Retryable():
+			return CodeStorageUnavailable, false, true
+		default:
+			return CodeStorageRejected, true, true
+		}
+	case *pgconn.PgError:
+		// Connection exceptions, serialization failures and deadlocks, insufficient
+		// resources, operator intervention (e.g., shutdown) and system errors are transient
+		switch e.Code[:min(len(e.Code), 2)] {
+		case "08", "40", "53", "57", "58":
+			return CodeDatabaseUnavailable, false, true
+		default:
+			return CodeDatabaseError, true, true
+		}
+	case *pgconn.ConnectError:
+		return CodeDatabaseUnavailable, false, true
+	}
+
+	switch err {
+	case transcoder.ErrUnknownProfile:
+		return CodeInvalidOptions, true, true
+	case transcoder.ErrNoStreams, transcoder.ErrNoVideoStream, transcoder.ErrNoDuration, transcoder.ErrSilentAudio:
+		return CodeInvalidInput, true, true
+	case pgx.ErrNoRows:
+		return CodeNotFound, true, true
+	case context.DeadlineExceeded:
+		return CodeTimeout, false, true
+	}
+	return "", false, false
+}
@@ -0,0 +1,145 @@
+package failure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/storage"
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/apps/worker/internal/transcoder"
+)
+
+// s3Error returns a storage error for a request S3 answered with status
+func s3Error(status int) error {
+	return &storage.Error{Op: "get object", Key: "uploads/input.mp4", Err: &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+			Err:      errors.New("api error"),
+		},
+	}}
+}
+
+// ffmpegError returns a failed FFmpeg run that printed output
+func ffmpegError(output string) error {
+	return &transcoder.CommandError{Op: "ffmpeg", Err: errors.New("exit status 1"), Output: output}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantCode      Code
+		wantPermanent bool
+	}{
+		// Errors raised by the worker itself carry their classification
+		{name: "permanent error", err: Permanent(CodeQualityBelowFloor, errors.New("vmaf 80 < 90")), wantCode: CodeQualityBelowFloor, wantPermanent: true},
+		{name: "transient error", err: &Error{Code: CodeStalled, Err: errors.New("worker stopped responding")}, wantCode: CodeStalled},
+
+		// FFmpeg runs are told apart by their stderr
+		{name: "ffmpeg invalid data", err: ffmpegError("input.mp4: Invalid data found when processing input"), wantCode: CodeInvalidInput, wantPermanent: true},
+		{name: "ffmpeg moov atom", err: ffmpegError("[mov,mp4] moov atom not found"), wantCode: CodeInvalidInput, wantPermanent: true},
+		{name: "ffmpeg no streams", err: ffmpegError("Stream map '0:v:0' matches no streams."), wantCode: CodeInvalidInput, wantPermanent: true},
+		{name: "ffmpeg unknown encoder", err: ffmpegError("Unknown encoder 'libsvtav1'"), wantCode: CodeUnsupported, wantPermanent: true},
+		{name: "ffmpeg missing filter", err: ffmpegError("No such filter: 'zscale'"), wantCode: CodeUnsupported, wantPermanent: true},
+		{name: "ffmpeg unrecognized option", err: ffmpegError("Unrecognized option 'fpsmax'."), wantCode: CodeUnsupported, wantPermanent: true},
+		{name: "ffmpeg killed", err: ffmpegError("Killed"), wantCode: CodeEncoderFailed},
+		{name: "ffmpeg no output", err: ffmpegError(""), wantCode: CodeEncoderFailed},
+
+		{name: "profile error", err: &transcoder.ProfileError{Profile: "720p", Reason: "unknown preset turbo"}, wantCode: CodeInvalidOptions, wantPermanent: true},
+
+		// S3 errors are classified by the response status
+		{name: "s3 not found", err: s3Error(http.StatusNotFound), wantCode: CodeObjectNotFound, wantPermanent: true},
+		{name: "s3 access denied", err: s3Error(http.StatusForbidden), wantCode: CodeStorageRejected, wantPermanent: true},
+		{name: "s3 bad request", err: s3Error(http.StatusBadRequest), wantCode: CodeStorageRejected, wantPermanent: true},
+		{name: "s3 server error", err: s3Error(http.StatusInternalServerError), wantCode: CodeStorageUnavailable},
+		{name: "s3 unavailable", err: s3Error(http.StatusServiceUnavailable), wantCode: CodeStorageUnavailable},
+		{name: "s3 throttled", err: s3Error(http.StatusTooManyRequests), wantCode: CodeStorageUnavailable},
+		{name: "s3 request timeout", err: s3Error(http.StatusRequestTimeout), wantCode: CodeStorageUnavailable},
+		{name: "s3 object changed", err: s3Error(http.StatusPreconditionFailed), wantCode: CodeStorageUnavailable},
+		{name: "s3 unreachable", err: &storage.Error{Op: "upload to", Key: "outputs/x", Err: errors.New("dial tcp: connection refused")}, wantCode: CodeStorageUnavailable},
+
+		// Postgres errors are classified by SQLSTATE class
+		{name: "sqlstate connection exception", err: &pgconn.PgError{Code: "08006"}, wantCode: CodeDatabaseUnavailable},
+		{name: "sqlstate serialization failure", err: &pgconn.PgError{Code: "40001"}, wantCode: CodeDatabaseUnavailable},
+		{name: "sqlstate deadlock", err: &pgconn.PgError{Code: "40P01"}, wantCode: CodeDatabaseUnavailable},
+		{name: "sqlstate too many connections", err: &pgconn.PgError{Code: "53300"}, wantCode: CodeDatabaseUnavailable},
+		{name: "sqlstate admin shutdown", err: &pgconn.PgError{Code: "57P01"}, wantCode: CodeDatabaseUnavailable},
+		{name: "sqlstate system error", err: &pgconn.PgError{Code: "58030"}, wantCode: CodeDatabaseUnavailable},
+		{name: "sqlstate unique violation", err: &pgconn.PgError{Code: "23505"}, wantCode: CodeDatabaseError, wantPermanent: true},
+		{name: "sqlstate undefined column", err: &pgconn.PgError{Code: "42703"}, wantCode: CodeDatabaseError, wantPermanent: true},
+		{name: "sqlstate missing", err: &pgconn.PgError{}, wantCode: CodeDatabaseError, wantPermanent: true},
+		{name: "postgres unreachable", err: &pgconn.ConnectError{}, wantCode: CodeDatabaseUnavailable},
+
+		// Sentinel errors
+		{name: "unknown profile", err: transcoder.ErrUnknownProfile, wantCode: CodeInvalidOptions, wantPermanent: true},
+		{name: "no streams", err: transcoder.ErrNoStreams, wantCode: CodeInvalidInput, wantPermanent: true},
+		{name: "no video stream", err: transcoder.ErrNoVideoStream, wantCode: CodeInvalidInput, wantPermanent: true},
+		{name: "no duration", err: transcoder.ErrNoDuration, wantCode: CodeInvalidInput, wantPermanent: true},
+		{name: "silent audio", err: transcoder.ErrSilentAudio, wantCode: CodeInvalidInput, wantPermanent: true},
+		{name: "no rows", err: pgx.ErrNoRows, wantCode: CodeNotFound, wantPermanent: true},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, wantCode: CodeTimeout},
+		{name: "cancelled", err: context.Canceled, wantCode: CodeInternal},
+
+		// Wrap chains are searched from the outside in
+		{name: "wrapped no rows", err: fmt.Errorf("failed to get job: %w", pgx.ErrNoRows), wantCode: CodeNotFound, wantPermanent: true},
+		{name: "wrapped twice", err: fmt.Errorf("rendition 720p: %w", fmt.Errorf("probe: %w", transcoder.ErrNoVideoStream)), wantCode: CodeInvalidInput, wantPermanent: true},
+		{name: "outer classification wins", err: &Error{Code: CodeStalled, Err: pgx.ErrNoRows}, wantCode: CodeStalled},
+		{name: "storage error around deadline", err: &storage.Error{Op: "get object", Key: "k", Err: context.DeadlineExceeded}, wantCode: CodeStorageUnavailable},
+
+		// Joined errors are permanent only when every one of them is
+		{
+			name:          "joined all permanent",
+			err:           errors.Join(transcoder.ErrNoVideoStream, &transcoder.ProfileError{Profile: "4k", Reason: "bad"}),
+			wantCode:      CodeInvalidInput,
+			wantPermanent: true,
+		},
+		{
+			name:     "joined mixed",
+			err:      errors.Join(transcoder.ErrNoVideoStream, s3Error(http.StatusServiceUnavailable)),
+			wantCode: CodeStorageUnavailable,
+		},
+		{
+			name:     "joined with unknown",
+			err:      errors.Join(pgx.ErrNoRows, errors.New("something else")),
+			wantCode: CodeInternal,
+		},
+		{
+			name:          "wrapped join",
+			err:           fmt.Errorf("renditions failed: %w", errors.Join(pgx.ErrNoRows, pgx.ErrNoRows)),
+			wantCode:      CodeNotFound,
+			wantPermanent: true,
+		},
+		{
+			name:          "join inside classified error",
+			err:           Permanent(CodeInvalidOptions, errors.Join(errors.New("a"), errors.New("b"))),
+			wantCode:      CodeInvalidOptions,
+			wantPermanent: true,
+		},
+		{name: "empty join", err: fmt.Errorf("wrap: %w", joinedErrors{}), wantCode: CodeInternal},
+
+		// Anything unrecognized is internal and transient
+		{name: "unknown", err: errors.New("boom"), wantCode: CodeInternal},
+		{name: "nil", err: nil, wantCode: CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, permanent := Classify(tt.err)
+			if code != tt.wantCode || permanent != tt.wantPermanent {
+				t.Errorf("Classify() = (%s, %t), want (%s, %t)", code, permanent, tt.wantCode, tt.wantPermanent)
+			}
+		})
+	}
+}
+
+// joinedErrors is a multi-error wrapping nothing, which errors.Join never returns
+type joinedErrors []error
+
+func (e joinedErrors) Error() string   { return "no errors" }
+func (e joinedErrors) Unwrap() []error { return e }
@@ -17,7 +17,16 @@ var (
 	JobsProcessedTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "jobs_processed_total",
-			Help: "Total number of jobs processed by status (completed/failed)",
+			Help: "Total number of jobs processed by status (completed/failed/cancelled)",
+		},
+		[]string{"status"},
+	)
+
+	// ChunksProcessedTotal counts chunks of chunked jobs encoded by status
+	ChunksProcessedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "chunks_processed_total",
+			Help: "Total number of chunks of chunked jobs encoded by status (completed/failed)",
 		},
 		[]string{"status"},
 	)
@@ -49,6 +58,40 @@ var (
 		},
 	)
 
+	// DelayedJobs shows the number of retries waiting in the delayed set for their backoff to pass
+	DelayedJobs = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "delayed_jobs",
+			Help: "Number of retries waiting for their backoff before re-entering the queue",
+		},
+	)
+
+	// QueueEntriesRecoveredTotal counts queue entries returned to the queue from dead workers' processing lists
+	QueueEntriesRecoveredTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "queue_entries_recovered_total",
+			Help: "Total number of queue entries recovered from workers that died before acknowledging them",
+		},
+	)
+
+	// StaleJobsReapedTotal counts jobs the stale-job reaper took back from stalled workers by outcome
+	StaleJobsReapedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "stale_jobs_reaped_total",
+			Help: "Total number of stale jobs reaped from stalled workers by outcome (requeued/failed)",
+		},
+		[]string{"outcome"},
+	)
+
+	// StaleChunksReapedTotal counts chunks the stale-job reaper took back from stalled workers by outcome
+	StaleChunksReapedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "stale_chunks_reaped_total",
+			Help: "Total number of stale chunks of chunked jobs reaped from stalled workers by outcome (requeued/failed)",
+		},
+		[]string{"outcome"},
+	)
+
 	// ActiveJobs shows the number of jobs currently being processed
 	ActiveJobs = promauto.NewGauge(
 		prometheus.GaugeOpts{
@@ -68,6 +111,21 @@ func RecordJobFailed() {
 	JobsProcessedTotal.WithLabelValues("failed").Inc()
 }
 
+// RecordJobCancelled increments the cancelled jobs counter
+func RecordJobCancelled() {
+	JobsProcessedTotal.WithLabelValues("cancelled").Inc()
+}
+
+// RecordChunkCompleted increments the completed chunks counter
+func RecordChunkCompleted() {
+	ChunksProcessedTotal.WithLabelValues("completed").Inc()
+}
+
+// RecordChunkFailed increments the failed chunks counter
+func RecordChunkFailed() {
+	ChunksProcessedTotal.WithLabelValues("failed").Inc()
+}
+
 // RecordJobDuration records the duration of processing a job for a specific resolution
 func RecordJobDuration(resolution string, duration time.Duration) {
 	JobDurationSeconds.WithLabelValues(resolution).Observe(duration.Seconds())
@@ -78,6 +136,32 @@ func RecordTranscodeError(resolution string) {
 	TranscodeErrorsTotal.WithLabelValues(resolution).Inc()
 }
 
+// RecordQueueEntriesRecovered adds recovered entries to the recovered queue entries counter
+func RecordQueueEntriesRecovered(n int) {
+	QueueEntriesRecoveredTotal.Add(float64(n))
+}
+
+// RecordStaleJobRequeued increments the reaped jobs counter for a job put back in the queue
+func RecordStaleJobRequeued() {
+	StaleJobsReapedTotal.WithLabelValues("requeued").Inc()
+}
+
+// RecordStaleJobFailed increments the reaped jobs counter for a job out of retries
+func RecordStaleJobFailed() {
+	StaleJobsReapedTotal.WithLabelValues("failed").Inc()
+}
+
+// RecordStaleChunkRequeued increments the reaped chunks counter for a chunk put back in the queue
+func RecordStaleChunkRequeued() {
+	StaleChunksReapedTotal.WithLabelValues("requeued").Inc()
+}
+
+// RecordStaleChunkFailed increments the reaped chunks counter for a chunk out of retries,
+// which fails its job
+func RecordStaleChunkFailed() {
+	StaleChunksReapedTotal.WithLabelValues("failed").Inc()
+}
+
 // IncrementActiveJobs increments the active jobs gauge
 func IncrementActiveJobs() {
 	ActiveJobs.Inc()
@@ -108,8 +192,8 @@ func StartMetricsServer(port string) *http.Server {
 	return srv
 }
 
-// StartQueueDepthUpdater periodically updates the queue depth metric
-func StartQueueDepthUpdater(ctx context.Context, redisClient *redis.Client, queueKey string, interval time.Duration) {
+// StartQueueDepthUpdater periodically updates the queue depth and delayed jobs metrics
+func StartQueueDepthUpdater(ctx context.Context, redisClient *redis.Client, queueKey, delayedKey string, interval time.Duration) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
@@ -124,6 +208,13 @@ func StartQueueDepthUpdater(ctx context.Context, redisClient *redis.Client, queu
 				continue
 			}
 			QueueDepth.Set(float64(depth))
+
+			delayed, err := redisClient.ZCard(ctx, delayedKey).Result()
+			if err != nil {
+				log.Printf("Failed to get delayed jobs: %v", err)
+				continue
+			}
+			DelayedJobs.Set(float64(delayed))
 		}
 	}
 }
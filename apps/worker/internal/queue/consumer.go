@@ -2,24 +2,127 @@ package queue
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/packages/jobqueue"
 )
 
 const (
 	// JobQueueKey is the Redis key for the pending jobs queue
-	JobQueueKey = "jobs:pending"
+	JobQueueKey = jobqueue.JobQueueKey
+	// DelayedQueueKey is the Redis sorted set of retries waiting for their backoff, scored by due time (Unix ms)
+	DelayedQueueKey = jobqueue.DelayedQueueKey
 	// DeadLetterQueueKey is the Redis key for failed jobs that exceeded max retries
 	DeadLetterQueueKey = "jobs:dead"
 	// LockKeyPrefix is the prefix for job lock keys
 	LockKeyPrefix = "job:lock:"
 	// DefaultLockTTL is the default time-to-live for job locks (5 minutes)
 	DefaultLockTTL = 5 * time.Minute
+	// ProgressKeyPrefix is the prefix for per-job progress hashes (one field per rendition)
+	ProgressKeyPrefix = jobqueue.ProgressKeyPrefix
+	// ChunkProgressKeyPrefix is the prefix for chunked jobs' hashes of encoded seconds
+	// (one field per rendition and chunk, "{resolution}:{index}")
+	ChunkProgressKeyPrefix = "job:chunk-progress:"
+	// ProgressTTL is how long progress is kept after the last update
+	ProgressTTL = 24 * time.Hour
+	// CancelKeyPrefix is the prefix for job cancellation flags set by the API
+	CancelKeyPrefix = jobqueue.CancelKeyPrefix
+	// ChunkTaskPrefix marks queue entries that encode one chunk of a chunked job ("chunk:{job}:{index}")
+	ChunkTaskPrefix = "chunk:"
+	// StitchTaskPrefix marks queue entries that join a chunked job's encoded chunks ("stitch:{job}")
+	StitchTaskPrefix = "stitch:"
+	// ProcessingKeyPrefix is the prefix for each worker's list of popped but unacknowledged entries
+	ProcessingKeyPrefix = "jobs:processing:"
+	// WorkersKey is the Redis set of worker IDs that may own a processing list
+	WorkersKey = "workers"
+	// HeartbeatKeyPrefix is the prefix for worker liveness keys; a worker without one is dead
+	HeartbeatKeyPrefix = "worker:heartbeat:"
+	// HeartbeatTTL is how long a worker's heartbeat lasts without being refreshed. It spans
+	// several refreshes and recovery sweeps, so a live worker that misses a few (a Redis blip,
+	// a host starved by FFmpeg) isn't recovered while it is still running its task.
+	HeartbeatTTL = 90 * time.Second
+	// ReaperLeaderKey holds the ID of the worker currently running the stale-job reaper
+	ReaperLeaderKey = "reaper:leader"
 )
 
+// promoteScript moves up to ARGV[2] entries of the delayed set due by ARGV[1] to the pending
+// queue. Each entry leaves the set and joins the queue in one step, so promoters running on
+// several workers never push an entry twice and a crash never loses one.
+// KEYS: delayed set, pending queue. ARGV: now in Unix ms, batch size.
+var promoteScript = redis.NewScript(`
+	local due = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, ARGV[2])
+	for _, entry in ipairs(due) do
+		redis.call("ZREM", KEYS[1], entry)
+		redis.call("LPUSH", KEYS[2], entry)
+	end
+	return #due
+`)
+
+// promoteBatch is the most delayed entries promoteScript moves per call
+const promoteBatch = 100
+
+// leadScript takes a leadership key if it is free, or renews it if this worker already holds it.
+// KEYS: leadership key. ARGV: worker ID, TTL in milliseconds.
+var leadScript = redis.NewScript(`
+	if redis.call("GET", KEYS[1]) == ARGV[1] then
+		return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	end
+	if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+		return 1
+	end
+	return 0
+`)
+
+// recoverScript returns a dead worker's unacknowledged entries to the pending queue, where
+// they are popped next, and releases the task locks it held. It does nothing if the worker
+// has a heartbeat again, so two sweeps (or a worker that merely stalled) can't double up.
+// KEYS: heartbeat, processing list, pending queue, workers set. ARGV: worker ID, lock prefix.
+var recoverScript = redis.NewScript(`
+	if redis.call("EXISTS", KEYS[1]) == 1 then
+		return -1
+	end
+	local n = 0
+	while true do
+		local entry = redis.call("LMOVE", KEYS[2], KEYS[3], "LEFT", "RIGHT")
+		if not entry then
+			break
+		end
+		local lock = ARGV[2] .. entry
+		if redis.call("GET", lock) == ARGV[1] then
+			redis.call("DEL", lock)
+		end
+		n = n + 1
+	end
+	redis.call("SREM", KEYS[4], ARGV[1])
+	return n
+`)
+
+// ChunkTask returns the queue entry for encoding chunk index of a chunked job
+func ChunkTask(jobID string, index int) string {
+	return fmt.Sprintf("%s%s:%d", ChunkTaskPrefix, jobID, index)
+}
+
+// StitchTask returns the queue entry for stitching a chunked job
+func StitchTask(jobID string) string {
+	return StitchTaskPrefix + jobID
+}
+
+// RenditionProgress is the progress of a single rendition encode, stored as JSON in the job's progress hash
+type RenditionProgress struct {
+	Percent    float64   `json:"percent"`
+	Speed      float64   `json:"speed"`
+	ETASeconds int64     `json:"eta_seconds"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
 // Consumer handles pulling jobs from the Redis queue
 type Consumer struct {
 	client   *redis.Client
@@ -52,13 +155,20 @@ func (c *Consumer) WorkerID() string {
 	return c.workerID
 }
 
+// processingKey returns the Redis key of a worker's processing list
+func processingKey(workerID string) string {
+	return ProcessingKeyPrefix + workerID
+}
+
 // Pop blocks until a job is available and returns the job ID
-// Returns empty string and context error if context is cancelled
+// Returns empty string and context error if context is cancelled.
+// The entry moves atomically into this worker's processing list, where it stays until
+// Ack or Requeue, so a worker dying after the pop can't lose it.
 func (c *Consumer) Pop(ctx context.Context) (string, error) {
-	// BRPOP blocks until an element is available
+	// BLMOVE blocks until an element is available
 	// Timeout of 0 means block indefinitely (but still respects context)
 	// We use a shorter timeout to allow checking context cancellation
-	result, err := c.client.BRPop(ctx, 5*time.Second, JobQueueKey).Result()
+	result, err := c.client.BLMove(ctx, JobQueueKey, processingKey(c.workerID), "RIGHT", "LEFT", 5*time.Second).Result()
 	if err != nil {
 		if err == redis.Nil {
 			// Timeout, no job available - this is normal
@@ -66,13 +176,103 @@ func (c *Consumer) Pop(ctx context.Context) (string, error) {
 		}
 		return "", err
 	}
+	return result, nil
+}
+
+// Ack removes a popped entry from this worker's processing list once it has been handled,
+// including when handling it meant dropping it or queueing a retry
+func (c *Consumer) Ack(ctx context.Context, message string) error {
+	if err := c.client.LRem(ctx, processingKey(c.workerID), 1, message).Err(); err != nil {
+		return fmt.Errorf("failed to acknowledge %s: %w", message, err)
+	}
+	return nil
+}
+
+// Requeue hands a popped entry back to the pending queue without handling it
+func (c *Consumer) Requeue(ctx context.Context, message string) error {
+	pipe := c.client.TxPipeline()
+	pipe.LRem(ctx, processingKey(c.workerID), 1, message)
+	pipe.LPush(ctx, JobQueueKey, message)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to requeue %s: %w", message, err)
+	}
+	return nil
+}
+
+// Heartbeat registers this worker and marks it alive for HeartbeatTTL
+func (c *Consumer) Heartbeat(ctx context.Context) error {
+	pipe := c.client.TxPipeline()
+	pipe.SAdd(ctx, WorkersKey, c.workerID)
+	pipe.Set(ctx, HeartbeatKeyPrefix+c.workerID, time.Now().Unix(), HeartbeatTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to send heartbeat: %w", err)
+	}
+	return nil
+}
+
+// StartHeartbeat refreshes this worker's heartbeat every interval until ctx is cancelled
+func (c *Consumer) StartHeartbeat(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Heartbeat(ctx); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+	}
+}
+
+// Deregister drops this worker's heartbeat on shutdown, so entries it leaves unacknowledged
+// are recovered by the next sweep instead of after HeartbeatTTL
+func (c *Consumer) Deregister(ctx context.Context) error {
+	return c.client.Del(ctx, HeartbeatKeyPrefix+c.workerID).Err()
+}
+
+// DeadWorkers returns the registered workers, other than this one, without a heartbeat
+func (c *Consumer) DeadWorkers(ctx context.Context) ([]string, error) {
+	workers, err := c.client.SMembers(ctx, WorkersKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workers: %w", err)
+	}
+	var dead []string
+	for _, id := range workers {
+		if id == c.workerID {
+			continue
+		}
+		n, err := c.client.Exists(ctx, HeartbeatKeyPrefix+id).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to check heartbeat of worker %s: %w", id, err)
+		}
+		if n == 0 {
+			dead = append(dead, id)
+		}
+	}
+	return dead, nil
+}
 
-	// result[0] is the key name, result[1] is the value (job ID)
-	if len(result) < 2 {
-		return "", fmt.Errorf("unexpected BRPOP result: %v", result)
+// Orphans returns the entries left in a worker's processing list
+func (c *Consumer) Orphans(ctx context.Context, workerID string) ([]string, error) {
+	entries, err := c.client.LRange(ctx, processingKey(workerID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list entries of worker %s: %w", workerID, err)
 	}
+	return entries, nil
+}
 
-	return result[1], nil
+// RecoverOrphans moves a dead worker's unacknowledged entries back to the pending queue
+// and unregisters it. It returns how many entries were moved; none if the worker is alive.
+func (c *Consumer) RecoverOrphans(ctx context.Context, workerID string) (int, error) {
+	keys := []string{HeartbeatKeyPrefix + workerID, processingKey(workerID), JobQueueKey, WorkersKey}
+	n, err := recoverScript.Run(ctx, c.client, keys, workerID, LockKeyPrefix).Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to recover entries of worker %s: %w", workerID, err)
+	}
+	return max(n, 0), nil
 }
 
 // Close closes the Redis connection
@@ -125,6 +325,46 @@ func (c *Consumer) Unlock(ctx context.Context, jobID string) error {
 	return nil
 }
 
+// IsLocked reports whether any worker holds the lock for a job or task
+func (c *Consumer) IsLocked(ctx context.Context, jobID string) (bool, error) {
+	n, err := c.client.Exists(ctx, LockKeyPrefix+jobID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check lock: %w", err)
+	}
+	return n > 0, nil
+}
+
+// IsQueued reports whether a queue entry is waiting in the pending queue or the delayed set.
+// Entries popped by a worker are covered by the task's lock instead.
+func (c *Consumer) IsQueued(ctx context.Context, message string) (bool, error) {
+	err := c.client.LPos(ctx, JobQueueKey, message, redis.LPosArgs{}).Err()
+	if err == nil {
+		return true, nil
+	}
+	if err != redis.Nil {
+		return false, fmt.Errorf("failed to find %s in the queue: %w", message, err)
+	}
+	err = c.client.ZScore(ctx, DelayedQueueKey, message).Err()
+	if err == nil {
+		return true, nil
+	}
+	if err != redis.Nil {
+		return false, fmt.Errorf("failed to find %s in the delayed set: %w", message, err)
+	}
+	return false, nil
+}
+
+// Lead makes this worker the holder of a fleet-wide leadership key for ttl, or renews it
+// if it already holds it. Returns false while another worker holds the key.
+func (c *Consumer) Lead(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	ttlMs := int64(ttl / time.Millisecond)
+	result, err := leadScript.Run(ctx, c.client, []string{key}, c.workerID, ttlMs).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to take leadership of %s: %w", key, err)
+	}
+	return result == 1, nil
+}
+
 // ExtendLock extends the TTL of a job lock.
 // Used for long-running jobs to prevent lock expiration.
 // Only extends if this worker owns the lock.
@@ -156,13 +396,116 @@ func (c *Consumer) Push(ctx context.Context, jobID string) error {
 	return c.client.LPush(ctx, JobQueueKey, jobID).Err()
 }
 
-// PushDeadLetter moves a job to the dead letter queue.
-// Jobs in this queue have exceeded max retries and need manual inspection.
-func (c *Consumer) PushDeadLetter(ctx context.Context, jobID string) error {
-	return c.client.LPush(ctx, DeadLetterQueueKey, jobID).Err()
+// PushDelayed schedules a queue entry to be pushed to the pending queue after delay (for retries).
+// The entry is kept in Redis meanwhile, so it survives the worker that scheduled it.
+func (c *Consumer) PushDelayed(ctx context.Context, message string, delay time.Duration) error {
+	due := time.Now().Add(delay).UnixMilli()
+	if err := c.client.ZAdd(ctx, DelayedQueueKey, redis.Z{Score: float64(due), Member: message}).Err(); err != nil {
+		return fmt.Errorf("failed to schedule %s: %w", message, err)
+	}
+	return nil
+}
+
+// PromoteDue moves delayed entries whose time has come to the pending queue and returns how many moved
+func (c *Consumer) PromoteDue(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		n, err := promoteScript.Run(ctx, c.client, []string{DelayedQueueKey, JobQueueKey}, time.Now().UnixMilli(), promoteBatch).Int()
+		if err != nil {
+			return total, fmt.Errorf("failed to promote delayed entries: %w", err)
+		}
+		total += n
+		if n < promoteBatch {
+			return total, nil
+		}
+	}
+}
+
+// StartPromoter calls PromoteDue every interval until ctx is cancelled
+func (c *Consumer) StartPromoter(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := c.PromoteDue(ctx)
+			if err != nil {
+				log.Printf("Warning: %v", err)
+			} else if n > 0 {
+				log.Printf("Promoted %d delayed entries to the queue", n)
+			}
+		}
+	}
+}
+
+// PushDeadLetter moves a failed task to the dead letter queue.
+// Entries are the queue message of the task that failed: a job ID, "chunk:{job}:{index}" or
+// "stitch:{job}", so pushing one back onto the job queue replays exactly that task.
+// Tasks in this queue failed permanently or exceeded max retries and need manual inspection.
+func (c *Consumer) PushDeadLetter(ctx context.Context, message string) error {
+	return c.client.LPush(ctx, DeadLetterQueueKey, message).Err()
 }
 
 // GetDeadLetterQueueLength returns the number of jobs in the dead letter queue.
 func (c *Consumer) GetDeadLetterQueueLength(ctx context.Context) (int64, error) {
 	return c.client.LLen(ctx, DeadLetterQueueKey).Result()
 }
+
+// SetProgress records the progress of one rendition of a job.
+// The whole progress hash expires ProgressTTL after the last update.
+func (c *Consumer) SetProgress(ctx context.Context, jobID, resolution string, progress RenditionProgress) error {
+	value, err := json.Marshal(progress)
+	if err != nil {
+		return fmt.Errorf("failed to encode progress: %w", err)
+	}
+
+	key := ProgressKeyPrefix + jobID
+	pipe := c.client.TxPipeline()
+	pipe.HSet(ctx, key, resolution, value)
+	pipe.Expire(ctx, key, ProgressTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to set progress: %w", err)
+	}
+	return nil
+}
+
+// SetChunkProgress records how many seconds of one chunk have been encoded into a rendition
+// and returns the seconds encoded into that rendition across all of the job's chunks.
+// The hash expires ProgressTTL after the last update.
+func (c *Consumer) SetChunkProgress(ctx context.Context, jobID, resolution string, chunk int, seconds float64) (float64, error) {
+	key := ChunkProgressKeyPrefix + jobID
+	pipe := c.client.TxPipeline()
+	pipe.HSet(ctx, key, fmt.Sprintf("%s:%d", resolution, chunk), seconds)
+	all := pipe.HGetAll(ctx, key)
+	pipe.Expire(ctx, key, ProgressTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to set chunk progress: %w", err)
+	}
+
+	// Profile names can't contain ':', so the prefix matches this rendition's chunks only
+	prefix := resolution + ":"
+	var encoded float64
+	for field, value := range all.Val() {
+		if !strings.HasPrefix(field, prefix) {
+			continue
+		}
+		s, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid chunk progress %s: %w", field, err)
+		}
+		encoded += s
+	}
+	return encoded, nil
+}
+
+// IsCancelled reports whether the API has flagged a job as cancelled.
+func (c *Consumer) IsCancelled(ctx context.Context, jobID string) (bool, error) {
+	n, err := c.client.Exists(ctx, CancelKeyPrefix+jobID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check cancellation: %w", err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,291 @@
+package queue
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/packages/jobqueue"
+)
+
+// newTestConsumer returns a consumer for workerID backed by an in-memory Redis
+func newTestConsumer(t *testing.T, workerID string) (*Consumer, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return &Consumer{client: client, workerID: workerID}, mr
+}
+
+func TestIsQueued(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestConsumer(t, "worker-a")
+
+	pending := ChunkTask("job-1", 0)
+	delayed := ChunkTask("job-1", 1)
+	popped := ChunkTask("job-1", 2)
+	lost := ChunkTask("job-1", 3)
+
+	mr.Lpush(JobQueueKey, "job-2")
+	mr.Lpush(JobQueueKey, pending)
+	mr.Lpush(JobQueueKey, StitchTask("job-3"))
+	if _, err := mr.ZAdd(DelayedQueueKey, float64(time.Now().Add(time.Minute).UnixMilli()), delayed); err != nil {
+		t.Fatal(err)
+	}
+	mr.Lpush(processingKey("worker-b"), popped)
+
+	tests := []struct {
+		message string
+		want    bool
+	}{
+		{pending, true},
+		{delayed, true},
+		{popped, false}, // Covered by its lock, not the queue
+		{lost, false},
+	}
+	for _, tt := range tests {
+		got, err := c.IsQueued(ctx, tt.message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("IsQueued(%s) = %t, want %t", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestPromoteDue(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestConsumer(t, "worker-a")
+
+	// Retries scheduled in the past are due; the one a minute out is not
+	for i, delay := range []time.Duration{-3 * time.Second, -2 * time.Second, -time.Second} {
+		if err := c.PushDelayed(ctx, ChunkTask("job-1", i), delay); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.PushDelayed(ctx, "job-2", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := c.PromoteDue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("PromoteDue() = %d, want 3", n)
+	}
+
+	// Workers pop from the right, so the retry due first must be rightmost
+	queued, err := mr.List(JobQueueKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{ChunkTask("job-1", 2), ChunkTask("job-1", 1), ChunkTask("job-1", 0)}
+	if !reflect.DeepEqual(queued, want) {
+		t.Errorf("queue = %v, want %v", queued, want)
+	}
+	delayed, err := mr.ZMembers(DelayedQueueKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(delayed, []string{"job-2"}) {
+		t.Errorf("delayed set = %v, want [job-2]", delayed)
+	}
+
+	// Nothing else is due
+	if n, err := c.PromoteDue(ctx); err != nil || n != 0 {
+		t.Errorf("second PromoteDue() = %d, %v, want 0", n, err)
+	}
+}
+
+func TestPromoteDueBatches(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestConsumer(t, "worker-a")
+
+	total := 2*promoteBatch + 50
+	due := float64(time.Now().Add(-time.Second).UnixMilli())
+	for i := 0; i < total; i++ {
+		if _, err := mr.ZAdd(DelayedQueueKey, due, ChunkTask("job-1", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// One run of the script moves at most a batch
+	keys := []string{DelayedQueueKey, JobQueueKey}
+	n, err := promoteScript.Run(ctx, c.client, keys, time.Now().UnixMilli(), promoteBatch).Int()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != promoteBatch {
+		t.Errorf("promoteScript moved %d entries, want %d", n, promoteBatch)
+	}
+
+	// PromoteDue keeps running it until a batch comes back short
+	moved, err := c.PromoteDue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != total-promoteBatch {
+		t.Errorf("PromoteDue() = %d, want %d", moved, total-promoteBatch)
+	}
+	queued, err := mr.List(JobQueueKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != total {
+		t.Errorf("queue holds %d entries, want %d", len(queued), total)
+	}
+	if mr.Exists(DelayedQueueKey) {
+		t.Error("delayed set not emptied")
+	}
+}
+
+func TestDeadWorkers(t *testing.T) {
+	ctx := context.Background()
+	self, mr := newTestConsumer(t, "worker-self")
+	alive := &Consumer{client: self.client, workerID: "worker-alive"}
+	stalled := &Consumer{client: self.client, workerID: "worker-stalled"}
+	for _, c := range []*Consumer{self, alive, stalled} {
+		if err := c.Heartbeat(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ttl := mr.TTL(HeartbeatKeyPrefix + "worker-alive"); ttl != HeartbeatTTL {
+		t.Errorf("heartbeat TTL = %v, want %v", ttl, HeartbeatTTL)
+	}
+	// A registered worker that never sent a heartbeat, or dropped it on shutdown
+	mr.SAdd(WorkersKey, "worker-gone")
+
+	// Missing a refresh or two doesn't make a worker dead
+	mr.FastForward(HeartbeatTTL / 2)
+	if err := alive.Heartbeat(ctx); err != nil {
+		t.Fatal(err)
+	}
+	dead, err := self.DeadWorkers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dead, []string{"worker-gone"}) {
+		t.Errorf("DeadWorkers() = %v, want [worker-gone]", dead)
+	}
+
+	// The stalled worker's heartbeat runs out; this worker's own never counts
+	mr.FastForward(HeartbeatTTL/2 + time.Second)
+	if err := alive.Heartbeat(ctx); err != nil {
+		t.Fatal(err)
+	}
+	dead, err = self.DeadWorkers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(dead)
+	if want := []string{"worker-gone", "worker-stalled"}; !reflect.DeepEqual(dead, want) {
+		t.Errorf("DeadWorkers() = %v, want %v", dead, want)
+	}
+}
+
+func TestRecoverOrphans(t *testing.T) {
+	ctx := context.Background()
+	sweeper, mr := newTestConsumer(t, "worker-sweeper")
+	dead := &Consumer{client: sweeper.client, workerID: "worker-dead"}
+	mr.SAdd(WorkersKey, "worker-dead", "worker-sweeper")
+
+	// The dead worker popped two entries; it still holds the lock of the second, while the
+	// first was already locked by another worker that popped a duplicate
+	mr.Lpush(JobQueueKey, "job-waiting")
+	mr.Lpush(JobQueueKey, "job-1")
+	mr.Lpush(JobQueueKey, "job-2")
+	for _, want := range []string{"job-waiting", "job-1"} {
+		if got, err := dead.Pop(ctx); err != nil || got != want {
+			t.Fatalf("Pop() = %q, %v, want %q", got, err, want)
+		}
+	}
+	mr.Set(LockKeyPrefix+"job-waiting", "worker-other")
+	mr.Set(LockKeyPrefix+"job-1", "worker-dead")
+
+	n, err := sweeper.RecoverOrphans(ctx, "worker-dead")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("RecoverOrphans() = %d, want 2", n)
+	}
+
+	// Recovered entries go to the consuming end, oldest first
+	for _, want := range []string{"job-waiting", "job-1", "job-2"} {
+		if got, err := sweeper.Pop(ctx); err != nil || got != want {
+			t.Errorf("Pop() = %q, %v, want %q", got, err, want)
+		}
+	}
+	if mr.Exists(processingKey("worker-dead")) {
+		t.Error("dead worker's processing list not emptied")
+	}
+	if mr.Exists(LockKeyPrefix + "job-1") {
+		t.Error("lock held by the dead worker not released")
+	}
+	if owner, _ := mr.Get(LockKeyPrefix + "job-waiting"); owner != "worker-other" {
+		t.Errorf("lock of another worker = %q, want it kept", owner)
+	}
+	if ok, _ := mr.SIsMember(WorkersKey, "worker-dead"); ok {
+		t.Error("dead worker still registered")
+	}
+}
+
+func TestRecoverOrphansLiveWorker(t *testing.T) {
+	ctx := context.Background()
+	sweeper, mr := newTestConsumer(t, "worker-sweeper")
+	worker := &Consumer{client: sweeper.client, workerID: "worker-slow"}
+
+	// Found dead by one sweep, the worker refreshes its heartbeat before the recovery runs
+	mr.SAdd(WorkersKey, "worker-slow")
+	mr.Lpush(JobQueueKey, "job-1")
+	if _, err := worker.Pop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	mr.Set(LockKeyPrefix+"job-1", "worker-slow")
+	if err := worker.Heartbeat(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := sweeper.RecoverOrphans(ctx, "worker-slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("RecoverOrphans() = %d, want 0", n)
+	}
+	if entries, _ := mr.List(processingKey("worker-slow")); !reflect.DeepEqual(entries, []string{"job-1"}) {
+		t.Errorf("processing list = %v, want [job-1]", entries)
+	}
+	if !mr.Exists(LockKeyPrefix + "job-1") {
+		t.Error("live worker's lock released")
+	}
+	if ok, _ := mr.SIsMember(WorkersKey, "worker-slow"); !ok {
+		t.Error("live worker unregistered")
+	}
+}
+
+func TestIsCancelled(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestConsumer(t, "worker-a")
+
+	// The API and GraphQL service cancel through jobqueue.Cancel
+	if err := jobqueue.Cancel(ctx, c.client, "job-1"); err != nil {
+		t.Fatal(err)
+	}
+	for jobID, want := range map[string]bool{"job-1": true, "job-2": false} {
+		got, err := c.IsCancelled(ctx, jobID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("IsCancelled(%s) = %t, want %t", jobID, got, want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+)
+
+// Error is returned when a request to S3 fails
+type Error struct {
+	Op  string // What was attempted, e.g., "get object" or "upload to"
+	Key string // Object key or prefix
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("failed to %s %s: %v", e.Op, e.Key, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode returns the HTTP status S3 answered with, or 0 if no response was received
+func (e *Error) StatusCode() int {
+	var respErr *awshttp.ResponseError
+	if errors.As(e.Err, &respErr) {
+		return respErr.HTTPStatusCode()
+	}
+	return 0
+}
+
+// NotFound reports whether the object (or the bucket) does not exist
+func (e *Error) NotFound() bool {
+	return e.StatusCode() == http.StatusNotFound
+}
+
+// Retryable reports whether the request may succeed if repeated: S3 could not be reached,
+// timed out, throttled it, failed server-side, or the object changed during a download
+func (e *Error) Retryable() bool {
+	switch code := e.StatusCode(); {
+	case code == 0, code >= 500:
+		return true
+	case code == http.StatusRequestTimeout, code == http.StatusPreconditionFailed, code == http.StatusTooManyRequests:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// responseError returns an error for a request S3 answered with status
+func responseError(status int) error {
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+			Err:      errors.New("api error"),
+		},
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantStatus    int
+		wantNotFound  bool
+		wantRetryable bool
+	}{
+		{name: "no response", err: errors.New("dial tcp: connection refused"), wantStatus: 0, wantRetryable: true},
+		{name: "not found", err: responseError(http.StatusNotFound), wantStatus: 404, wantNotFound: true},
+		{name: "forbidden", err: responseError(http.StatusForbidden), wantStatus: 403},
+		{name: "bad request", err: responseError(http.StatusBadRequest), wantStatus: 400},
+		{name: "request timeout", err: responseError(http.StatusRequestTimeout), wantStatus: 408, wantRetryable: true},
+		{name: "precondition failed", err: responseError(http.StatusPreconditionFailed), wantStatus: 412, wantRetryable: true},
+		{name: "throttled", err: responseError(http.StatusTooManyRequests), wantStatus: 429, wantRetryable: true},
+		{name: "internal error", err: responseError(http.StatusInternalServerError), wantStatus: 500, wantRetryable: true},
+		{name: "slow down", err: responseError(http.StatusServiceUnavailable), wantStatus: 503, wantRetryable: true},
+		{name: "wrapped response", err: fmt.Errorf("part 3: %w", responseError(http.StatusNotFound)), wantStatus: 404, wantNotFound: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Error{Op: "get object", Key: "uploads/input.mp4", Err: tt.err}
+			if got := e.StatusCode(); got != tt.wantStatus {
+				t.Errorf("StatusCode() = %d, want %d", got, tt.wantStatus)
+			}
+			if got := e.NotFound(); got != tt.wantNotFound {
+				t.Errorf("NotFound() = %t, want %t", got, tt.wantNotFound)
+			}
+			if got := e.Retryable(); got != tt.wantRetryable {
+				t.Errorf("Retryable() = %t, want %t", got, tt.wantRetryable)
+			}
+		})
+	}
+}
@@ -4,18 +4,35 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"mime"
 	"os"
+	"path"
+	"path/filepath"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
+// contentTypes maps streaming output extensions that the system MIME table may not know
+var contentTypes = map[string]string{
+	".m3u8": "application/vnd.apple.mpegurl",
+	".ts":   "video/mp2t",
+	".mp4":  "video/mp4",
+	".mpd":  "application/dash+xml",
+	".m4s":  "video/iso.segment",
+	".vtt":  "text/vtt",
+	".jpg":  "image/jpeg",
+}
+
 // Storage handles S3/MinIO operations for the worker
 type Storage struct {
-	client *s3.Client
-	bucket string
+	client      *s3.Client
+	bucket      string
+	partSize    int64
+	concurrency int
 }
 
 // Config holds S3 configuration
@@ -26,10 +43,26 @@ type Config struct {
 	Bucket       string
 	Region       string
 	UsePathStyle bool
+
+	// PartSize is the size of each part of multipart uploads and ranged downloads.
+	// Files no larger than one part go up in a single PUT.
+	PartSize int64
+	// Concurrency is the number of parts of one file transferred at once
+	Concurrency int
 }
 
 // New creates a new Storage client
 func New(cfg Config) (*Storage, error) {
+	if cfg.PartSize == 0 {
+		cfg.PartSize = DefaultPartSize
+	}
+	if cfg.PartSize < MinPartSize {
+		return nil, fmt.Errorf("part size %d is below the S3 minimum of %d bytes", cfg.PartSize, MinPartSize)
+	}
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = DefaultConcurrency
+	}
+
 	// Create custom credentials provider
 	creds := credentials.NewStaticCredentialsProvider(cfg.AccessKey, cfg.SecretKey, "")
 
@@ -49,22 +82,24 @@ func New(cfg Config) (*Storage, error) {
 	})
 
 	return &Storage{
-		client: client,
-		bucket: cfg.Bucket,
+		client:      client,
+		bucket:      cfg.Bucket,
+		partSize:    cfg.PartSize,
+		concurrency: cfg.Concurrency,
 	}, nil
 }
 
-// Download downloads a file from S3 to a local path
+// Download downloads a file from S3 to a local path. Objects larger than one part
+// are fetched as parallel ranged GETs, each retried on its own.
 func (s *Storage) Download(ctx context.Context, key string, destPath string) error {
-	// Get the object from S3
-	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(key),
 	})
 	if err != nil {
-		return fmt.Errorf("failed to get object %s: %w", key, err)
+		return &Error{Op: "get object", Key: key, Err: err}
 	}
-	defer result.Body.Close()
+	size := aws.ToInt64(head.ContentLength)
 
 	// Create the destination file
 	file, err := os.Create(destPath)
@@ -73,7 +108,24 @@ func (s *Storage) Download(ctx context.Context, key string, destPath string) err
 	}
 	defer file.Close()
 
-	// Copy the data
+	if size > s.partSize {
+		if err := s.downloadRanges(ctx, key, head.ETag, size, file); err != nil {
+			return &Error{Op: "download", Key: key, Err: err}
+		}
+		return nil
+	}
+
+	// Get the object from S3
+	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return &Error{Op: "get object", Key: key, Err: err}
+	}
+	defer result.Body.Close()
+
+	// Stream the data to disk
 	_, err = io.Copy(file, result.Body)
 	if err != nil {
 		return fmt.Errorf("failed to write file %s: %w", destPath, err)
@@ -82,7 +134,8 @@ func (s *Storage) Download(ctx context.Context, key string, destPath string) err
 	return nil
 }
 
-// Upload uploads a local file to S3
+// Upload uploads a local file to S3. Files larger than one part go up as a
+// parallel multipart upload, which also lifts S3's 5 GB single-PUT limit.
 func (s *Storage) Upload(ctx context.Context, srcPath string, key string) error {
 	// Open the source file
 	file, err := os.Open(srcPath)
@@ -96,6 +149,9 @@ func (s *Storage) Upload(ctx context.Context, srcPath string, key string) error
 	if err != nil {
 		return fmt.Errorf("failed to stat file %s: %w", srcPath, err)
 	}
+	if fileInfo.Size() > s.partSize {
+		return s.uploadMultipart(ctx, file, fileInfo.Size(), key)
+	}
 
 	// Upload to S3
 	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
@@ -103,14 +159,92 @@ func (s *Storage) Upload(ctx context.Context, srcPath string, key string) error
 		Key:           aws.String(key),
 		Body:          file,
 		ContentLength: aws.Int64(fileInfo.Size()),
+		ContentType:   aws.String(ContentType(key)),
 	})
 	if err != nil {
-		return fmt.Errorf("failed to upload to %s: %w", key, err)
+		return &Error{Op: "upload to", Key: key, Err: err}
+	}
+
+	return nil
+}
+
+// UploadDir uploads every regular file in a local directory tree under the given key prefix,
+// preserving relative paths (e.g., dir/720p/index.m3u8 -> prefix/720p/index.m3u8)
+func (s *Storage) UploadDir(ctx context.Context, dir string, prefix string) error {
+	return filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		return s.Upload(ctx, p, path.Join(prefix, filepath.ToSlash(rel)))
+	})
+}
+
+// ContentType returns the MIME type to store with an object based on its key extension
+func ContentType(key string) string {
+	ext := path.Ext(key)
+	if ct, ok := contentTypes[ext]; ok {
+		return ct
+	}
+	if ct := mime.TypeByExtension(ext); ct != "" {
+		return ct
 	}
+	return "application/octet-stream"
+}
+
+// DeletePrefix removes every object whose key starts with prefix
+func (s *Storage) DeletePrefix(ctx context.Context, prefix string) error {
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return &Error{Op: "list objects under", Key: prefix, Err: err}
+		}
+		if len(page.Contents) == 0 {
+			continue
+		}
 
+		// A listing page holds at most 1000 keys, the DeleteObjects limit
+		objects := make([]types.ObjectIdentifier, len(page.Contents))
+		for i, obj := range page.Contents {
+			objects[i] = types.ObjectIdentifier{Key: obj.Key}
+		}
+		out, err := s.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(s.bucket),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return &Error{Op: "delete objects under", Key: prefix, Err: err}
+		}
+		// Quiet mode still reports the keys S3 failed to delete
+		if err := deleteErrors(out.Errors); err != nil {
+			return &Error{Op: "delete objects under", Key: prefix, Err: err}
+		}
+	}
 	return nil
 }
 
+// deleteErrors summarizes the per-key failures of a DeleteObjects call, or returns nil if there were none
+func deleteErrors(errs []types.Error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	first := errs[0]
+	return fmt.Errorf("%d objects not deleted, first %s: %s (%s)",
+		len(errs), aws.ToString(first.Key), aws.ToString(first.Message), aws.ToString(first.Code))
+}
+
 // ObjectExists checks if an object exists in the bucket
 func (s *Storage) ObjectExists(ctx context.Context, key string) (bool, error) {
 	_, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
@@ -0,0 +1,23 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestDeleteErrors(t *testing.T) {
+	if err := deleteErrors(nil); err != nil {
+		t.Errorf("deleteErrors(nil) = %v, want nil", err)
+	}
+
+	err := deleteErrors([]types.Error{
+		{Key: aws.String("outputs/a/720p.mp4"), Code: aws.String("AccessDenied"), Message: aws.String("Access Denied")},
+		{Key: aws.String("outputs/a/480p.mp4"), Code: aws.String("InternalError"), Message: aws.String("We encountered an internal error")},
+	})
+	want := "2 objects not deleted, first outputs/a/720p.mp4: Access Denied (AccessDenied)"
+	if err == nil || err.Error() != want {
+		t.Errorf("deleteErrors() = %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	// DefaultPartSize is the part size used when Config.PartSize is unset
+	DefaultPartSize = 16 << 20
+	// MinPartSize is the smallest part S3 accepts for all but the last part of an upload
+	MinPartSize = 5 << 20
+	// DefaultConcurrency is the number of parts transferred at once when Config.Concurrency is unset
+	DefaultConcurrency = 4
+
+	// maxParts is the most parts a multipart upload may have
+	maxParts = 10000
+	// partAttempts is how many times a part is tried before the transfer fails.
+	// The SDK already retries throttling and transient request errors; this also
+	// covers a connection dropped while a part's body is being streamed.
+	partAttempts = 3
+	// partRetryDelay is the wait before a part's second attempt, growing linearly after that
+	partRetryDelay = time.Second
+	// abortTimeout bounds aborting an upload once its transfer has failed or been cancelled
+	abortTimeout = 30 * time.Second
+)
+
+// uploadMultipart uploads file in parts of at least the configured part size, several at once.
+// A failed or cancelled upload is aborted so its parts don't linger in the bucket.
+func (s *Storage) uploadMultipart(ctx context.Context, file *os.File, size int64, key string) error {
+	// Objects larger than maxParts parts get bigger parts instead
+	partSize := max(s.partSize, (size+maxParts-1)/maxParts)
+
+	created, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(ContentType(key)),
+	})
+	if err != nil {
+		return &Error{Op: "start multipart upload to", Key: key, Err: err}
+	}
+
+	parts := make([]types.CompletedPart, partCount(size, partSize))
+	err = s.forEachPart(ctx, size, partSize, func(ctx context.Context, index int, offset, length int64) error {
+		return retryPart(ctx, func() error {
+			out, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+				Bucket:        aws.String(s.bucket),
+				Key:           aws.String(key),
+				UploadId:      created.UploadId,
+				PartNumber:    aws.Int32(int32(index + 1)),
+				Body:          io.NewSectionReader(file, offset, length),
+				ContentLength: aws.Int64(length),
+			})
+			if err != nil {
+				return fmt.Errorf("part %d: %w", index+1, err)
+			}
+			parts[index] = types.CompletedPart{ETag: out.ETag, PartNumber: aws.Int32(int32(index + 1))}
+			return nil
+		})
+	})
+	if err == nil {
+		_, err = s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(s.bucket),
+			Key:             aws.String(key),
+			UploadId:        created.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+		})
+	}
+	if err != nil {
+		// The job's context may be what failed, so abort on one of our own
+		abortCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), abortTimeout)
+		defer cancel()
+		if abortErr := s.abortUpload(abortCtx, key, created.UploadId); abortErr != nil {
+			return &Error{Op: "upload to", Key: key, Err: fmt.Errorf("%w (abort also failed: %v)", err, abortErr)}
+		}
+		return &Error{Op: "upload to", Key: key, Err: err}
+	}
+	return nil
+}
+
+// downloadRanges fetches an object of the given size into file with parallel ranged GETs.
+// Every range is pinned to etag, so an object replaced mid-download fails instead of mixing versions.
+func (s *Storage) downloadRanges(ctx context.Context, key string, etag *string, size int64, file *os.File) error {
+	if err := file.Truncate(size); err != nil {
+		return fmt.Errorf("failed to allocate %s: %w", file.Name(), err)
+	}
+	return s.forEachPart(ctx, size, s.partSize, func(ctx context.Context, index int, offset, length int64) error {
+		return retryPart(ctx, func() error {
+			result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+				Bucket:  aws.String(s.bucket),
+				Key:     aws.String(key),
+				Range:   aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+				IfMatch: etag,
+			})
+			if err != nil {
+				return fmt.Errorf("range %d: %w", index, err)
+			}
+			defer result.Body.Close()
+
+			// A retried range simply overwrites what the failed attempt wrote
+			n, err := io.Copy(io.NewOffsetWriter(file, offset), result.Body)
+			if err != nil {
+				return fmt.Errorf("range %d: %w", index, err)
+			}
+			if n != length {
+				return fmt.Errorf("range %d: got %d bytes, want %d", index, n, length)
+			}
+			return nil
+		})
+	})
+}
+
+// forEachPart calls fn for each partSize piece of size bytes, running up to the configured
+// concurrency at once. The first error cancels the remaining parts and is returned.
+func (s *Storage) forEachPart(ctx context.Context, size, partSize int64, fn func(ctx context.Context, index int, offset, length int64) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, s.concurrency)
+	for i := range partCount(size, partSize) {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			offset := int64(index) * partSize
+			if err := fn(ctx, index, offset, min(partSize, size-offset)); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// retryPart runs fn up to partAttempts times, backing off between attempts
+func retryPart(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 1; attempt <= partAttempts; attempt++ {
+		if err = fn(); err == nil || ctx.Err() != nil {
+			return err
+		}
+		if attempt == partAttempts {
+			break
+		}
+		select {
+		case <-time.After(time.Duration(attempt) * partRetryDelay):
+		case <-ctx.Done():
+			return err
+		}
+	}
+	return fmt.Errorf("after %d attempts: %w", partAttempts, err)
+}
+
+func partCount(size, partSize int64) int {
+	return int((size + partSize - 1) / partSize)
+}
+
+func (s *Storage) abortUpload(ctx context.Context, key string, uploadID *string) error {
+	_, err := s.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(key),
+		UploadId: uploadID,
+	})
+	return err
+}
+
+// AbortUploads aborts every incomplete multipart upload whose key starts with prefix, releasing
+// the parts of uploads a crashed or cancelled worker never finished
+func (s *Storage) AbortUploads(ctx context.Context, prefix string) error {
+	paginator := s3.NewListMultipartUploadsPaginator(s.client, &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return &Error{Op: "list multipart uploads under", Key: prefix, Err: err}
+		}
+		for _, upload := range page.Uploads {
+			if err := s.abortUpload(ctx, aws.ToString(upload.Key), upload.UploadId); err != nil {
+				return &Error{Op: "abort upload of", Key: aws.ToString(upload.Key), Err: err}
+			}
+		}
+	}
+	return nil
+}
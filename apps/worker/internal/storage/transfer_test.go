@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestPartCount(t *testing.T) {
+	tests := []struct {
+		size, partSize int64
+		want           int
+	}{
+		{size: 0, partSize: 16, want: 0},
+		{size: 1, partSize: 16, want: 1},
+		{size: 16, partSize: 16, want: 1},
+		{size: 17, partSize: 16, want: 2},
+		{size: 40, partSize: 16, want: 3},
+		{size: 48, partSize: 16, want: 3},
+	}
+	for _, tt := range tests {
+		if got := partCount(tt.size, tt.partSize); got != tt.want {
+			t.Errorf("partCount(%d, %d) = %d, want %d", tt.size, tt.partSize, got, tt.want)
+		}
+	}
+}
+
+// part is one piece forEachPart handed to its callback
+type part struct {
+	index          int
+	offset, length int64
+}
+
+func TestForEachPart(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int64
+		partSize int64
+		want     []part
+	}{
+		{name: "empty", size: 0, partSize: 16, want: nil},
+		{name: "smaller than a part", size: 10, partSize: 16, want: []part{{0, 0, 10}}},
+		{name: "exactly one part", size: 16, partSize: 16, want: []part{{0, 0, 16}}},
+		{name: "one byte over", size: 17, partSize: 16, want: []part{{0, 0, 16}, {1, 16, 1}}},
+		{name: "last part partial", size: 40, partSize: 16, want: []part{{0, 0, 16}, {1, 16, 16}, {2, 32, 8}}},
+		{name: "whole parts", size: 48, partSize: 16, want: []part{{0, 0, 16}, {1, 16, 16}, {2, 32, 16}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Storage{concurrency: 2}
+			var mu sync.Mutex
+			var got []part
+			err := s.forEachPart(context.Background(), tt.size, tt.partSize, func(ctx context.Context, index int, offset, length int64) error {
+				mu.Lock()
+				defer mu.Unlock()
+				got = append(got, part{index, offset, length})
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i].index < got[j].index })
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForEachPartStopsOnError(t *testing.T) {
+	s := &Storage{concurrency: 1}
+	errPart := errors.New("part failed")
+	var calls int
+	err := s.forEachPart(context.Background(), 100, 10, func(ctx context.Context, index int, offset, length int64) error {
+		calls++
+		if index == 2 {
+			return errPart
+		}
+		return nil
+	})
+	if !errors.Is(err, errPart) {
+		t.Errorf("forEachPart() = %v, want %v", err, errPart)
+	}
+	// One part runs at a time, so nothing after the failed part starts
+	if calls != 3 {
+		t.Errorf("callback ran %d times, want 3", calls)
+	}
+}
+
+func TestForEachPartCancelled(t *testing.T) {
+	s := &Storage{concurrency: 1}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var calls int
+	err := s.forEachPart(ctx, 100, 10, func(ctx context.Context, index int, offset, length int64) error {
+		calls++
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("forEachPart() = %v, want context.Canceled", err)
+	}
+	if calls > 1 {
+		t.Errorf("callback ran %d times after cancellation", calls)
+	}
+}
+
+func TestRetryPart(t *testing.T) {
+	errTransfer := errors.New("connection reset")
+
+	t.Run("succeeds after a failure", func(t *testing.T) {
+		attempts := 0
+		err := retryPart(context.Background(), func() error {
+			attempts++
+			if attempts == 1 {
+				return errTransfer
+			}
+			return nil
+		})
+		if err != nil || attempts != 2 {
+			t.Errorf("retryPart() = %v after %d attempts, want nil after 2", err, attempts)
+		}
+	})
+
+	t.Run("gives up after partAttempts", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("waits out the retry backoff")
+		}
+		attempts := 0
+		err := retryPart(context.Background(), func() error {
+			attempts++
+			return errTransfer
+		})
+		if !errors.Is(err, errTransfer) || attempts != partAttempts {
+			t.Errorf("retryPart() = %v after %d attempts, want %v after %d", err, attempts, errTransfer, partAttempts)
+		}
+	})
+
+	t.Run("stops when cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		attempts := 0
+		err := retryPart(ctx, func() error {
+			attempts++
+			cancel()
+			return errTransfer
+		})
+		if !errors.Is(err, errTransfer) || attempts != 1 {
+			t.Errorf("retryPart() = %v after %d attempts, want %v after 1", err, attempts, errTransfer)
+		}
+	})
+}
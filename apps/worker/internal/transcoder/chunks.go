@@ -0,0 +1,133 @@
+package transcoder
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Chunk is a keyframe-aligned piece of a source's video stream
+type Chunk struct {
+	Path            string
+	StartSeconds    float64 // Where the chunk starts in the source
+	DurationSeconds float64
+}
+
+// SplitChunks cuts the first video stream of inputPath into Matroska chunks of roughly
+// chunkSeconds each, written to outputDir. The stream is copied, so cuts land on the first
+// keyframe after each boundary and chunks decode on their own. Every chunk's timestamps
+// start at zero; audio and subtitles are left out and taken from the source when stitching.
+func SplitChunks(ctx context.Context, inputPath, outputDir string, chunkSeconds float64) ([]Chunk, error) {
+	if chunkSeconds <= 0 {
+		return nil, fmt.Errorf("invalid chunk duration %g", chunkSeconds)
+	}
+	listPath := filepath.Join(outputDir, "chunks.csv")
+	args := []string{
+		"-i", inputPath,
+		"-map", "0:v:0",
+		"-c", "copy",
+		"-an", "-sn", "-dn",
+		"-f", "segment",
+		"-segment_time", formatSeconds(chunkSeconds),
+		"-reset_timestamps", "1",
+		"-segment_list", listPath,
+		"-segment_list_type", "csv",
+		"-y", filepath.Join(outputDir, "chunk_%04d.mkv"),
+	}
+	if err := runEditCommand(ctx, "split", args); err != nil {
+		return nil, err
+	}
+
+	// Each line of the list is "file,start,end" with times in seconds
+	f, err := os.Open(listPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open chunk list: %w", err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk list: %w", err)
+	}
+
+	chunks := make([]Chunk, 0, len(records))
+	for _, rec := range records {
+		if len(rec) != 3 {
+			return nil, fmt.Errorf("unexpected chunk list entry %q", strings.Join(rec, ","))
+		}
+		start, err := strconv.ParseFloat(rec[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chunk start %q: %w", rec[1], err)
+		}
+		end, err := strconv.ParseFloat(rec[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chunk end %q: %w", rec[2], err)
+		}
+		chunks = append(chunks, Chunk{
+			Path:            filepath.Join(outputDir, rec[0]),
+			StartSeconds:    start,
+			DurationSeconds: end - start,
+		})
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("split produced no chunks")
+	}
+	return chunks, nil
+}
+
+// Stitch joins the video of encoded chunks, in order and without re-encoding, and muxes it
+// with the first audio stream of sourcePath encoded per the profile. opts.AudioFilter,
+// SubtitleStreams (carried from sourcePath), Threads and OnProgress apply as for a transcode.
+func Stitch(ctx context.Context, chunkPaths []string, sourcePath, outputPath string, profile Profile, opts Options) error {
+	if profile.AudioOnly {
+		return &ProfileError{Profile: profile.Name, Reason: "audio-only renditions are not stitched"}
+	}
+	if len(chunkPaths) == 0 {
+		return fmt.Errorf("no chunks to stitch")
+	}
+
+	// The concat demuxer reads its inputs from a list file next to the output
+	listPath := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + "_chunks.txt"
+	var list strings.Builder
+	for _, p := range chunkPaths {
+		fmt.Fprintf(&list, "file '%s'\n", strings.ReplaceAll(p, "'", `'\''`))
+	}
+	if err := os.WriteFile(listPath, []byte(list.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write chunk list: %w", err)
+	}
+	defer os.Remove(listPath)
+
+	args := []string{
+		"-f", "concat", "-safe", "0", "-i", listPath,
+		"-i", sourcePath,
+		"-map", "0:v:0", "-map", "1:a:0?",
+		"-c:v", "copy",
+	}
+	if profile.VideoCodec == "libx265" {
+		args = append(args, hevcTag(profile.Extension()[1:])...)
+	}
+	args = append(args, audioArgs(profile, opts)...)
+
+	if codec := subtitleCodec(profile.Extension()); codec != "" && len(opts.SubtitleStreams) > 0 {
+		for _, index := range opts.SubtitleStreams {
+			args = append(args, "-map", "1:"+strconv.Itoa(index))
+		}
+		args = append(args, "-c:s", codec)
+	}
+
+	if opts.Threads > 0 {
+		args = append(args, "-threads", strconv.Itoa(opts.Threads))
+	}
+	if opts.OnProgress != nil {
+		args = append(args, "-progress", "pipe:1", "-nostats")
+	}
+	args = append(args, "-y", outputPath)
+
+	if err := runFFmpeg(ctx, args, opts); err != nil {
+		return fmt.Errorf("stitch: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package transcoder
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/packages/codecs"
+)
+
+// videoCodec describes how to drive one FFmpeg video encoder
+type videoCodec struct {
+	// ReferenceCRF is a visually good quality level, used for complexity probes
+	// when the profile does not set its own CRF
+	ReferenceCRF int
+	// presetArgs translates a preset name into encoder speed arguments
+	presetArgs func(preset string) []string
+	// rateArgs returns the rate control arguments for the profile's rate control mode
+	rateArgs func(p Profile) []string
+	// passArgs selects pass 1 or 2 of a two-pass encode with stats written under logPrefix;
+	// nil when the encoder cannot run two-pass through FFmpeg (codecs.Video.TwoPass is false)
+	passArgs func(pass int, logPrefix string) []string
+	// gopArgs pins the GOP to gop frames and turns off scene-cut keyframes, which would
+	// otherwise add keyframes between the forced ones; gop is 0 when the frame rate is unknown
+	gopArgs func(gop int) []string
+	// extraArgs are appended for every encode, e.g. container tags or threading modes
+	extraArgs func(container string) []string
+}
+
+// videoCodecs holds the FFmpeg arguments of every encoder in codecs.VideoCodecs, which says
+// what each one accepts
+var videoCodecs = map[string]videoCodec{
+	"libx264": {
+		ReferenceCRF: 23,
+		presetArgs:   namedPreset,
+		rateArgs:     crfOrBitrate,
+		passArgs:     ffmpegPass,
+		gopArgs:      fixedGOP("-sc_threshold", "0"),
+	},
+	"libx265": {
+		ReferenceCRF: 26,
+		presetArgs:   namedPreset,
+		rateArgs:     crfOrBitrate,
+		// libx265 ignores -pass/-passlogfile; multi-pass is configured through x265-params
+		passArgs: func(pass int, logPrefix string) []string {
+			return []string{"-x265-params", fmt.Sprintf("pass=%d:stats=%s.log", pass, logPrefix)}
+		},
+		// x265 takes its GOP through x265-params too; videoArgs merges the two
+		gopArgs: func(gop int) []string {
+			params := "scenecut=0"
+			if gop > 0 {
+				params = fmt.Sprintf("keyint=%d:min-keyint=%d:%s", gop, gop, params)
+			}
+			return []string{"-x265-params", params}
+		},
+		extraArgs: hevcTag,
+	},
+	"libsvtav1": {
+		ReferenceCRF: 35,
+		// SVT-AV1 presets run 0 (slowest) to 13 (fastest); ultrafast..veryslow maps onto 12..2
+		presetArgs: func(preset string) []string {
+			return []string{"-preset", strconv.Itoa(12 - codecs.PresetSpeeds[preset]*10/8)}
+		},
+		rateArgs: crfOrBitrate,
+		// SVT-AV1 has no minimum keyframe distance; with scene change detection off it only
+		// places keyframes every -g frames
+		gopArgs: func(gop int) []string {
+			args := []string{"-svtav1-params", "scd=0"}
+			if gop > 0 {
+				args = append([]string{"-g", strconv.Itoa(gop)}, args...)
+			}
+			return args
+		},
+	},
+	"libaom-av1": {
+		ReferenceCRF: 32,
+		presetArgs:   cpuUsed(8),
+		rateArgs:     constrainedQuality,
+		passArgs:     ffmpegPass,
+		// libaom and libvpx only place scene-cut keyframes between the minimum and maximum
+		// distance, so equal distances leave none
+		gopArgs: fixedGOP(),
+		extraArgs: func(string) []string {
+			return []string{"-row-mt", "1"}
+		},
+	},
+	"libvpx-vp9": {
+		ReferenceCRF: 32,
+		presetArgs: func(preset string) []string {
+			return append([]string{"-deadline", "good"}, cpuUsed(5)(preset)...)
+		},
+		rateArgs: constrainedQuality,
+		passArgs: ffmpegPass,
+		gopArgs:  fixedGOP(),
+		extraArgs: func(string) []string {
+			return []string{"-row-mt", "1"}
+		},
+	},
+}
+
+// fixedGOP sets the minimum and maximum keyframe distance to the GOP length through FFmpeg's
+// generic options, followed by the encoder's own switch for scene-cut keyframes, if any
+func fixedGOP(sceneCut ...string) func(int) []string {
+	return func(gop int) []string {
+		var args []string
+		if gop > 0 {
+			args = []string{"-g", strconv.Itoa(gop), "-keyint_min", strconv.Itoa(gop)}
+		}
+		return append(args, sceneCut...)
+	}
+}
+
+// hevcTag tags HEVC as hvc1 in MP4/MOV; Apple players only decode it with that tag rather than hev1
+func hevcTag(container string) []string {
+	if container == "mp4" || container == "mov" {
+		return []string{"-tag:v", "hvc1"}
+	}
+	return nil
+}
+
+// namedPreset passes the preset through for encoders that use the x264 names
+func namedPreset(preset string) []string {
+	return []string{"-preset", preset}
+}
+
+// cpuUsed maps presets onto libaom/libvpx -cpu-used, where 0 is slowest and max is fastest
+func cpuUsed(max int) func(string) []string {
+	return func(preset string) []string {
+		return []string{"-cpu-used", strconv.Itoa(max - codecs.PresetSpeeds[preset]*max/8)}
+	}
+}
+
+// ffmpegPass uses FFmpeg's generic two-pass options
+func ffmpegPass(pass int, logPrefix string) []string {
+	return []string{"-pass", strconv.Itoa(pass), "-passlogfile", logPrefix}
+}
+
+// crfOrBitrate drives encoders with x264-style rate control: -crf for quality modes,
+// -b:v for bitrate modes, and a -maxrate/-bufsize VBV cap on either
+func crfOrBitrate(p Profile) []string {
+	var args []string
+	if p.RateControlMode() == RateControlCRF {
+		if p.CRF > 0 {
+			args = append(args, "-crf", strconv.Itoa(p.CRF))
+		}
+	} else {
+		args = append(args, "-b:v", p.VideoBitrate)
+	}
+	return append(args, vbvArgs(p)...)
+}
+
+// constrainedQuality handles libaom and libvpx, which only honour -crf as a pure
+// quality target when -b:v is explicitly zeroed. With a MaxRate, -b:v becomes the
+// ceiling instead (constrained quality mode).
+func constrainedQuality(p Profile) []string {
+	if p.RateControlMode() != RateControlCRF {
+		return append([]string{"-b:v", p.VideoBitrate}, vbvArgs(p)...)
+	}
+
+	crf := p.CRF
+	if crf == 0 {
+		crf = 32
+	}
+	ceiling := "0"
+	if p.MaxRate != "" {
+		ceiling = p.MaxRate
+	}
+	return []string{"-crf", strconv.Itoa(crf), "-b:v", ceiling}
+}
+
+// forceKeyFrames returns a -force_key_frames expression for a keyframe every interval seconds
+// of the output timeline. An input starting offset seconds into that timeline, such as a chunk,
+// gets its first forced keyframe at the next multiple of interval rather than interval seconds in,
+// so chunks encoded on their own stitch into the same keyframe grid as a whole-file encode.
+func forceKeyFrames(interval, offset float64) string {
+	// Chunk starts come from the split's list in microseconds; round off float error so a chunk
+	// starting on the grid doesn't get a keyframe one frame in
+	first := math.Round(math.Mod(interval-math.Mod(offset, interval), interval)*1000) / 1000
+	if first == 0 || first == interval {
+		return fmt.Sprintf("expr:gte(t,n_forced*%g)", interval)
+	}
+	return fmt.Sprintf("expr:gte(t,%g+n_forced*%g)", first, interval)
+}
+
+// vbvArgs caps the bitrate when the profile sets MaxRate. The buffer defaults to
+// one second at the max rate, tight enough to keep segment sizes predictable.
+func vbvArgs(p Profile) []string {
+	if p.MaxRate == "" {
+		return nil
+	}
+	bufSize := p.BufSize
+	if bufSize == "" {
+		bufSize = p.MaxRate
+	}
+	return []string{"-maxrate", p.MaxRate, "-bufsize", bufSize}
+}
+
+// Validate reports settings FFmpeg would reject or that would produce an unplayable file,
+// such as a codec the output container cannot carry
+func (p Profile) Validate() error {
+	container := p.Extension()[1:]
+
+	if p.AudioOnly {
+		if container == "webm" && !codecs.WebMAudioCodecs[p.AudioCodec] {
+			return &ProfileError{Profile: p.Name, Reason: fmt.Sprintf("webm requires libopus or libvorbis audio, got %s", p.AudioCodec)}
+		}
+		return nil
+	}
+
+	codec, ok := codecs.VideoCodecs[p.VideoCodec]
+	if !ok {
+		return &ProfileError{Profile: p.Name, Reason: fmt.Sprintf("unsupported video codec %s", p.VideoCodec)}
+	}
+	if !codec.Containers[container] {
+		return &ProfileError{Profile: p.Name, Reason: fmt.Sprintf("%s cannot be written to a %s container", p.VideoCodec, container)}
+	}
+	if _, ok := codecs.PresetSpeeds[p.Preset]; !ok {
+		return &ProfileError{Profile: p.Name, Reason: fmt.Sprintf("unknown preset %s", p.Preset)}
+	}
+	if p.CRF < 0 || p.CRF > codec.MaxCRF {
+		return &ProfileError{Profile: p.Name, Reason: fmt.Sprintf("crf for %s must be between 0 and %d", p.VideoCodec, codec.MaxCRF)}
+	}
+	switch p.RateControlMode() {
+	case RateControlCRF:
+	case RateControlVBR, RateControlTwoPass:
+		if p.VideoBitrate == "" {
+			return &ProfileError{Profile: p.Name, Reason: fmt.Sprintf("rate control %s requires a video bitrate", p.RateControl)}
+		}
+		if p.RateControlMode() == RateControlTwoPass && !codec.TwoPass {
+			return &ProfileError{Profile: p.Name, Reason: fmt.Sprintf("%s does not support two-pass encoding", p.VideoCodec)}
+		}
+	default:
+		return &ProfileError{Profile: p.Name, Reason: fmt.Sprintf("unknown rate control %s", p.RateControl)}
+	}
+	if container == "webm" && !codecs.WebMAudioCodecs[p.AudioCodec] {
+		return &ProfileError{Profile: p.Name, Reason: fmt.Sprintf("webm requires libopus or libvorbis audio, got %s", p.AudioCodec)}
+	}
+	return nil
+}
+
+// videoArgs returns the encoder, speed, rate control and keyframe arguments for the profile's
+// video codec. pass is 1 or 2 during a two-pass encode and 0 otherwise. opts.FrameRate sizes
+// the GOP and opts.KeyframeOffset places forced keyframes. The profile must have passed Validate.
+func videoArgs(p Profile, pass int, logPrefix string, opts Options) []string {
+	codec := videoCodecs[p.VideoCodec]
+	args := append([]string{"-c:v", p.VideoCodec}, codec.presetArgs(p.Preset)...)
+	args = append(args, codec.rateArgs(p)...)
+	if pass > 0 {
+		args = append(args, codec.passArgs(pass, logPrefix)...)
+	}
+	if p.KeyframeInterval > 0 {
+		// Keyframes on a fixed timeline, identical for every rendition, so HLS/DASH
+		// segments cut at the same instants across the ladder. The GOP matches the interval
+		// so the encoder adds no keyframes of its own in between.
+		args = append(args, "-force_key_frames", forceKeyFrames(p.KeyframeInterval, opts.KeyframeOffset))
+		args = append(args, codec.gopArgs(gopFrames(p, opts.FrameRate))...)
+	}
+	if codec.extraArgs != nil {
+		args = append(args, codec.extraArgs(p.Extension()[1:])...)
+	}
+	return mergeParams(args, "-x265-params")
+}
+
+// gopFrames returns the profile's keyframe interval in frames at the output frame rate
+// (the source rate capped at MaxFPS), or 0 when the source frame rate is unknown
+func gopFrames(p Profile, sourceFPS float64) int {
+	fps := sourceFPS
+	if p.MaxFPS > 0 && (fps == 0 || fps > float64(p.MaxFPS)) {
+		fps = float64(p.MaxFPS)
+	}
+	return int(math.Round(p.KeyframeInterval * fps))
+}
+
+// mergeParams joins repeated occurrences of an encoder parameter option, such as
+// -x265-params set for both two-pass and the GOP, into the first one; FFmpeg keeps only the last
+func mergeParams(args []string, option string) []string {
+	first := -1
+	merged := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if args[i] != option || i+1 == len(args) {
+			merged = append(merged, args[i])
+			continue
+		}
+		if first < 0 {
+			first = len(merged) + 1
+			merged = append(merged, args[i], args[i+1])
+		} else {
+			merged[first] += ":" + args[i+1]
+		}
+		i++
+	}
+	return merged
+}
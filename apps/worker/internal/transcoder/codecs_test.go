@@ -0,0 +1,167 @@
+package transcoder
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/packages/codecs"
+)
+
+// TestEncodersCoverCodecs checks that every codec profiles may use has FFmpeg arguments,
+// with two-pass arguments exactly when the codec allows two-pass encodes
+func TestEncodersCoverCodecs(t *testing.T) {
+	for name, codec := range codecs.VideoCodecs {
+		encoder, ok := videoCodecs[name]
+		if !ok {
+			t.Errorf("%s has no encoder arguments", name)
+			continue
+		}
+		if twoPass := encoder.passArgs != nil; twoPass != codec.TwoPass {
+			t.Errorf("%s: has pass arguments = %t, codec allows two-pass = %t", name, twoPass, codec.TwoPass)
+		}
+		if encoder.presetArgs == nil || encoder.rateArgs == nil || encoder.gopArgs == nil {
+			t.Errorf("%s: missing preset, rate control or GOP arguments", name)
+		}
+	}
+	for name := range videoCodecs {
+		if _, ok := codecs.VideoCodecs[name]; !ok {
+			t.Errorf("encoder %s is not in codecs.VideoCodecs, so profiles can't use it", name)
+		}
+	}
+}
+
+func TestForceKeyFrames(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval float64
+		offset   float64
+		want     string
+	}{
+		{name: "whole file", interval: 2, offset: 0, want: "expr:gte(t,n_forced*2)"},
+		{name: "chunk on the grid", interval: 2, offset: 60, want: "expr:gte(t,n_forced*2)"},
+		{name: "chunk off the grid", interval: 2, offset: 60.3, want: "expr:gte(t,1.7+n_forced*2)"},
+		{name: "chunk just before the grid", interval: 2, offset: 59.9999996, want: "expr:gte(t,n_forced*2)"},
+		{name: "chunk just after the grid", interval: 2, offset: 60.0000004, want: "expr:gte(t,n_forced*2)"},
+		{name: "fractional interval", interval: 1.5, offset: 10, want: "expr:gte(t,0.5+n_forced*1.5)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := forceKeyFrames(tt.interval, tt.offset); got != tt.want {
+				t.Errorf("forceKeyFrames(%g, %g) = %q, want %q", tt.interval, tt.offset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVideoArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Profile
+		pass    int
+		opts    Options
+		want    []string
+	}{
+		{
+			name:    "x264 crf with fixed gop",
+			profile: Profile{VideoCodec: "libx264", Preset: "fast", CRF: 23, KeyframeInterval: 2},
+			opts:    Options{FrameRate: 30},
+			want: []string{"-c:v", "libx264", "-preset", "fast", "-crf", "23",
+				"-force_key_frames", "expr:gte(t,n_forced*2)", "-g", "60", "-keyint_min", "60", "-sc_threshold", "0"},
+		},
+		{
+			name:    "x264 capped vbr",
+			profile: Profile{VideoCodec: "libx264", Preset: "medium", VideoBitrate: "2500k", MaxRate: "3000k", KeyframeInterval: 2},
+			opts:    Options{FrameRate: 25},
+			want: []string{"-c:v", "libx264", "-preset", "medium", "-b:v", "2500k", "-maxrate", "3000k", "-bufsize", "3000k",
+				"-force_key_frames", "expr:gte(t,n_forced*2)", "-g", "50", "-keyint_min", "50", "-sc_threshold", "0"},
+		},
+		{
+			name:    "x264 two-pass second pass",
+			profile: Profile{VideoCodec: "libx264", Preset: "slow", RateControl: RateControlTwoPass, VideoBitrate: "4M", KeyframeInterval: 2},
+			pass:    2,
+			opts:    Options{FrameRate: 24},
+			want: []string{"-c:v", "libx264", "-preset", "slow", "-b:v", "4M", "-pass", "2", "-passlogfile", "/tmp/stats",
+				"-force_key_frames", "expr:gte(t,n_forced*2)", "-g", "48", "-keyint_min", "48", "-sc_threshold", "0"},
+		},
+		{
+			name:    "x264 without keyframe interval",
+			profile: Profile{VideoCodec: "libx264", Preset: "fast"},
+			opts:    Options{FrameRate: 30},
+			want:    []string{"-c:v", "libx264", "-preset", "fast"},
+		},
+		{
+			name:    "x264 unknown frame rate",
+			profile: Profile{VideoCodec: "libx264", Preset: "fast", KeyframeInterval: 2},
+			want:    []string{"-c:v", "libx264", "-preset", "fast", "-force_key_frames", "expr:gte(t,n_forced*2)", "-sc_threshold", "0"},
+		},
+		{
+			name:    "max fps caps the gop",
+			profile: Profile{VideoCodec: "libx264", Preset: "fast", MaxFPS: 30, KeyframeInterval: 2},
+			opts:    Options{FrameRate: 59.94},
+			want: []string{"-c:v", "libx264", "-preset", "fast",
+				"-force_key_frames", "expr:gte(t,n_forced*2)", "-g", "60", "-keyint_min", "60", "-sc_threshold", "0"},
+		},
+		{
+			name:    "x265 two-pass merges x265-params",
+			profile: Profile{VideoCodec: "libx265", Preset: "medium", RateControl: RateControlTwoPass, VideoBitrate: "2M", KeyframeInterval: 2, Container: "mp4"},
+			pass:    1,
+			opts:    Options{FrameRate: 30},
+			want: []string{"-c:v", "libx265", "-preset", "medium", "-b:v", "2M",
+				"-x265-params", "pass=1:stats=/tmp/stats.log:keyint=60:min-keyint=60:scenecut=0",
+				"-force_key_frames", "expr:gte(t,n_forced*2)", "-tag:v", "hvc1"},
+		},
+		{
+			name:    "svt-av1 crf",
+			profile: Profile{VideoCodec: "libsvtav1", Preset: "medium", CRF: 35, KeyframeInterval: 2, Container: "mkv"},
+			opts:    Options{FrameRate: 30},
+			want: []string{"-c:v", "libsvtav1", "-preset", "6", "-crf", "35",
+				"-force_key_frames", "expr:gte(t,n_forced*2)", "-g", "60", "-svtav1-params", "scd=0"},
+		},
+		{
+			name:    "aom constrained quality",
+			profile: Profile{VideoCodec: "libaom-av1", Preset: "medium", CRF: 30, MaxRate: "3M", KeyframeInterval: 2, Container: "webm"},
+			opts:    Options{FrameRate: 30},
+			want: []string{"-c:v", "libaom-av1", "-cpu-used", "3", "-crf", "30", "-b:v", "3M",
+				"-force_key_frames", "expr:gte(t,n_forced*2)", "-g", "60", "-keyint_min", "60", "-row-mt", "1"},
+		},
+		{
+			name:    "vp9 chunk off the grid",
+			profile: Profile{VideoCodec: "libvpx-vp9", Preset: "fast", RateControl: RateControlVBR, VideoBitrate: "1500k", KeyframeInterval: 2, Container: "webm"},
+			opts:    Options{FrameRate: 30, KeyframeOffset: 60.5},
+			want: []string{"-c:v", "libvpx-vp9", "-deadline", "good", "-cpu-used", "3", "-b:v", "1500k",
+				"-force_key_frames", "expr:gte(t,1.5+n_forced*2)", "-g", "60", "-keyint_min", "60", "-row-mt", "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := videoArgs(tt.profile, tt.pass, "/tmp/stats", tt.opts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("videoArgs() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeParams(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{name: "none", args: []string{"-c:v", "libx265"}, want: []string{"-c:v", "libx265"}},
+		{name: "one", args: []string{"-x265-params", "a=1", "-y"}, want: []string{"-x265-params", "a=1", "-y"}},
+		{
+			name: "two",
+			args: []string{"-x265-params", "a=1", "-b:v", "2M", "-x265-params", "b=2"},
+			want: []string{"-x265-params", "a=1:b=2", "-b:v", "2M"},
+		},
+		{name: "trailing option", args: []string{"-x265-params"}, want: []string{"-x265-params"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeParams(tt.args, "-x265-params"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeParams() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package transcoder
+
+import (
+	"fmt"
+	"strings"
+)
+
+// avcProfiles maps ffprobe's H.264 profile names to profile_idc and the constraint flags
+// players expect for it, as the first two bytes of an avc1 codec string
+var avcProfiles = map[string]string{
+	"Constrained Baseline":  "42E0",
+	"Baseline":              "4200",
+	"Main":                  "4D40",
+	"High":                  "6400",
+	"High 10":               "6E00",
+	"High 4:2:2":            "7A00",
+	"High 4:4:4 Predictive": "F400",
+}
+
+// hevcProfiles maps ffprobe's HEVC profile names to general_profile_idc and the
+// compatibility flags, as the second and third parts of an hvc1 codec string
+var hevcProfiles = map[string]string{
+	"Main":               "1.6",
+	"Main 10":            "2.4",
+	"Main Still Picture": "3.8",
+	"Rext":               "4.10",
+}
+
+// av1Profiles maps ffprobe's AV1 profile names to seq_profile
+var av1Profiles = map[string]int{"Main": 0, "High": 1, "Professional": 2}
+
+// vp9Profiles maps ffprobe's VP9 profile names to the profile number
+var vp9Profiles = map[string]int{"Profile 0": 0, "Profile 1": 1, "Profile 2": 2, "Profile 3": 3}
+
+// codecLevel is a level of a codec whose level ffprobe doesn't report (VP9, AV1),
+// with the largest picture and luma sample rate it allows
+type codecLevel struct {
+	id         int
+	pictureMax int64
+	sampleRate int64
+}
+
+// vp9Levels lists VP9 levels in ascending order; the id is the level times ten
+var vp9Levels = []codecLevel{
+	{10, 36864, 829440},
+	{11, 73728, 2764800},
+	{20, 122880, 4608000},
+	{21, 245760, 9216000},
+	{30, 552960, 20736000},
+	{31, 983040, 36864000},
+	{40, 2228224, 83558400},
+	{41, 2228224, 160432128},
+	{50, 8912896, 311951360},
+	{51, 8912896, 588251136},
+	{52, 8912896, 1176502272},
+	{60, 35651584, 1176502272},
+	{61, 35651584, 2353004544},
+	{62, 35651584, 4706009088},
+}
+
+// av1Levels lists AV1 levels in ascending order; the id is seq_level_idx
+var av1Levels = []codecLevel{
+	{0, 147456, 4423680},
+	{1, 278784, 8363520},
+	{4, 665856, 19975680},
+	{5, 1065024, 31950720},
+	{8, 2359296, 70778880},
+	{9, 2359296, 141557760},
+	{12, 8912896, 267386880},
+	{13, 8912896, 534773760},
+	{14, 8912896, 1069547520},
+	{15, 8912896, 1069547520},
+	{16, 35651584, 1069547520},
+	{17, 35651584, 2139095040},
+	{18, 35651584, 4278190080},
+}
+
+// codecsAttribute returns the RFC 6381 codec strings of an encoded rendition's video and audio
+// streams, joined for an HLS CODECS attribute. It returns "" if a stream's codec, profile or
+// level can't be identified, since a wrong CODECS value makes players skip the variant.
+func codecsAttribute(info *MediaInfo) string {
+	var codecs []string
+	if info.HasVideo() {
+		video := videoCodecString(info)
+		if video == "" {
+			return ""
+		}
+		codecs = append(codecs, video)
+	}
+	if info.HasAudio() {
+		audio := audioCodecString(info)
+		if audio == "" {
+			return ""
+		}
+		codecs = append(codecs, audio)
+	}
+	return strings.Join(codecs, ",")
+}
+
+// videoCodecString returns the RFC 6381 codec string of the video stream, or "" if unknown
+func videoCodecString(info *MediaInfo) string {
+	switch info.VideoCodec {
+	case "h264":
+		profile, ok := avcProfiles[info.VideoProfile]
+		if !ok || info.VideoLevel <= 0 {
+			return ""
+		}
+		return fmt.Sprintf("avc1.%s%02X", profile, info.VideoLevel)
+	case "hevc":
+		profile, ok := hevcProfiles[info.VideoProfile]
+		if !ok || info.VideoLevel <= 0 {
+			return ""
+		}
+		// Main tier; ffprobe reports general_level_idc, e.g. 93 for level 3.1
+		return fmt.Sprintf("hvc1.%s.L%d.B0", profile, info.VideoLevel)
+	case "vp9":
+		profile, ok := vp9Profiles[info.VideoProfile]
+		level := levelFor(vp9Levels, info)
+		if !ok || level < 0 {
+			return ""
+		}
+		return fmt.Sprintf("vp09.%02d.%02d.%02d", profile, level, bitDepth(info.PixelFormat))
+	case "av1":
+		profile, ok := av1Profiles[info.VideoProfile]
+		level := levelFor(av1Levels, info)
+		if !ok || level < 0 {
+			return ""
+		}
+		return fmt.Sprintf("av01.%d.%02dM.%02d", profile, level, bitDepth(info.PixelFormat))
+	}
+	return ""
+}
+
+// audioCodecString returns the RFC 6381 codec string of the audio stream, or "" if unknown
+func audioCodecString(info *MediaInfo) string {
+	switch info.AudioCodec {
+	case "aac":
+		switch info.AudioProfile {
+		case "HE-AAC":
+			return "mp4a.40.5"
+		case "HE-AACv2":
+			return "mp4a.40.29"
+		default:
+			return "mp4a.40.2"
+		}
+	case "mp3":
+		return "mp4a.40.34"
+	case "ac3":
+		return "ac-3"
+	case "eac3":
+		return "ec-3"
+	case "opus":
+		return "opus"
+	case "flac":
+		return "fLaC"
+	}
+	return ""
+}
+
+// levelFor returns the lowest level in levels that allows the stream's picture size and
+// luma sample rate, or -1 if none does or the stream's size is unknown
+func levelFor(levels []codecLevel, info *MediaInfo) int {
+	picture := int64(info.Width) * int64(info.Height)
+	if picture == 0 {
+		return -1
+	}
+	fps := info.FrameRate
+	if fps <= 0 {
+		fps = 30
+	}
+	rate := int64(float64(picture) * fps)
+	for _, l := range levels {
+		if picture <= l.pictureMax && rate <= l.sampleRate {
+			return l.id
+		}
+	}
+	return -1
+}
+
+// bitDepth returns the luma bit depth of a pixel format, e.g. 10 for "yuv420p10le"
+func bitDepth(pixelFormat string) int {
+	switch {
+	case strings.Contains(pixelFormat, "p10"):
+		return 10
+	case strings.Contains(pixelFormat, "p12"):
+		return 12
+	default:
+		return 8
+	}
+}
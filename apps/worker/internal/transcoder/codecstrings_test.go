@@ -0,0 +1,74 @@
+package transcoder
+
+import "testing"
+
+func TestCodecsAttribute(t *testing.T) {
+	tests := []struct {
+		name string
+		info MediaInfo
+		want string
+	}{
+		{
+			name: "h264 high with aac",
+			info: MediaInfo{VideoCodec: "h264", VideoProfile: "High", VideoLevel: 40, AudioCodec: "aac", AudioProfile: "LC"},
+			want: "avc1.640028,mp4a.40.2",
+		},
+		{
+			name: "h264 constrained baseline",
+			info: MediaInfo{VideoCodec: "h264", VideoProfile: "Constrained Baseline", VideoLevel: 30},
+			want: "avc1.42E01E",
+		},
+		{
+			name: "hevc main 10 with he-aac",
+			info: MediaInfo{VideoCodec: "hevc", VideoProfile: "Main 10", VideoLevel: 120, AudioCodec: "aac", AudioProfile: "HE-AAC"},
+			want: "hvc1.2.4.L120.B0,mp4a.40.5",
+		},
+		{
+			name: "vp9 1080p30 with opus",
+			info: MediaInfo{VideoCodec: "vp9", VideoProfile: "Profile 0", Width: 1920, Height: 1080, FrameRate: 30, PixelFormat: "yuv420p", AudioCodec: "opus"},
+			want: "vp09.00.40.08,opus",
+		},
+		{
+			name: "vp9 1080p60 needs a higher level",
+			info: MediaInfo{VideoCodec: "vp9", VideoProfile: "Profile 0", Width: 1920, Height: 1080, FrameRate: 60, PixelFormat: "yuv420p"},
+			want: "vp09.00.41.08",
+		},
+		{
+			name: "av1 720p30 10-bit",
+			info: MediaInfo{VideoCodec: "av1", VideoProfile: "Main", Width: 1280, Height: 720, FrameRate: 30, PixelFormat: "yuv420p10le"},
+			want: "av01.0.05M.10",
+		},
+		{
+			name: "av1 1080p30",
+			info: MediaInfo{VideoCodec: "av1", VideoProfile: "Main", Width: 1920, Height: 1080, FrameRate: 30, PixelFormat: "yuv420p"},
+			want: "av01.0.08M.08",
+		},
+		{
+			name: "audio only mp3",
+			info: MediaInfo{AudioCodec: "mp3"},
+			want: "mp4a.40.34",
+		},
+		{
+			name: "unknown h264 level",
+			info: MediaInfo{VideoCodec: "h264", VideoProfile: "High", VideoLevel: -99, AudioCodec: "aac"},
+			want: "",
+		},
+		{
+			name: "unknown video codec",
+			info: MediaInfo{VideoCodec: "mpeg2video", AudioCodec: "aac"},
+			want: "",
+		},
+		{
+			name: "unknown audio codec",
+			info: MediaInfo{VideoCodec: "h264", VideoProfile: "Main", VideoLevel: 31, AudioCodec: "vorbis"},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := codecsAttribute(&tt.info); got != tt.want {
+				t.Errorf("codecsAttribute() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
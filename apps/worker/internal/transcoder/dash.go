@@ -0,0 +1,113 @@
+package transcoder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DASHSegmentDuration is the target CMAF segment length in seconds
+	DASHSegmentDuration = 6
+	// DASHManifestName is the file name of the generated MPD manifest
+	DASHManifestName = "manifest.mpd"
+)
+
+// PackageDASH remuxes encoded renditions into fragmented-MP4 (CMAF) segments described by a single MPD.
+// The video stream of every input becomes one representation. Representations of the same codec
+// share an adaptation set, since players only switch within a set, so a ladder mixing, say, H.264
+// and AV1 gets one set per codec. The audio stream of the first input (if any) becomes a separate
+// audio adaptation set. Streams are copied, not re-encoded.
+// Returns the manifest path and the representation ID assigned to each input, in input order.
+func PackageDASH(ctx context.Context, inputPaths []string, outputDir string) (string, []string, error) {
+	if len(inputPaths) == 0 {
+		return "", nil, fmt.Errorf("no renditions to package")
+	}
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return "", nil, fmt.Errorf("failed to create DASH output dir: %w", err)
+	}
+
+	videoCodecs := make([]string, len(inputPaths))
+	var hasAudio bool
+	for i, p := range inputPaths {
+		info, err := Probe(ctx, p)
+		if err != nil {
+			return "", nil, err
+		}
+		videoCodecs[i] = info.VideoCodec
+		if i == 0 {
+			hasAudio = info.HasAudio()
+		}
+	}
+
+	var args []string
+	for _, p := range inputPaths {
+		args = append(args, "-i", p)
+	}
+
+	// Output stream indexes double as representation IDs in the MPD
+	representationIDs := make([]string, len(inputPaths))
+	for i := range inputPaths {
+		args = append(args, "-map", fmt.Sprintf("%d:v:0", i))
+		if videoCodecs[i] == "hevc" {
+			// Tag only this stream; hevcTag's plain -tag:v would retag every video stream
+			args = append(args, fmt.Sprintf("-tag:v:%d", i), "hvc1")
+		}
+		representationIDs[i] = strconv.Itoa(i)
+	}
+	if hasAudio {
+		args = append(args, "-map", "0:a:0")
+	}
+
+	manifestPath := filepath.Join(outputDir, DASHManifestName)
+	args = append(args,
+		"-c", "copy",
+		"-f", "dash",
+		"-dash_segment_type", "mp4",
+		"-seg_duration", strconv.Itoa(DASHSegmentDuration),
+		"-use_template", "1",
+		"-use_timeline", "1",
+		"-init_seg_name", "init-$RepresentationID$.m4s",
+		"-media_seg_name", "chunk-$RepresentationID$-$Number%05d$.m4s",
+		"-adaptation_sets", adaptationSets(videoCodecs, hasAudio),
+		"-y",
+		manifestPath,
+	)
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", nil, &CommandError{Op: "ffmpeg DASH packaging", Err: err, Output: stderr.String()}
+	}
+
+	return manifestPath, representationIDs, nil
+}
+
+// adaptationSets builds the -adaptation_sets value for PackageDASH: one set per video codec,
+// in order of first appearance, listing the output streams of that codec, plus an audio set.
+// videoCodecs holds the codec of each video output stream, which are numbered from 0.
+func adaptationSets(videoCodecs []string, hasAudio bool) string {
+	var order []string
+	streams := make(map[string][]string)
+	for i, codec := range videoCodecs {
+		if _, ok := streams[codec]; !ok {
+			order = append(order, codec)
+		}
+		streams[codec] = append(streams[codec], strconv.Itoa(i))
+	}
+
+	sets := make([]string, 0, len(order)+1)
+	for id, codec := range order {
+		sets = append(sets, fmt.Sprintf("id=%d,streams=%s", id, strings.Join(streams[codec], ",")))
+	}
+	if hasAudio {
+		sets = append(sets, fmt.Sprintf("id=%d,streams=a", len(order)))
+	}
+	return strings.Join(sets, " ")
+}
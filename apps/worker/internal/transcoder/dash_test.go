@@ -0,0 +1,23 @@
+package transcoder
+
+import "testing"
+
+func TestAdaptationSets(t *testing.T) {
+	tests := []struct {
+		name     string
+		codecs   []string
+		hasAudio bool
+		want     string
+	}{
+		{"single codec with audio", []string{"h264", "h264", "h264"}, true, "id=0,streams=0,1,2 id=1,streams=a"},
+		{"single codec without audio", []string{"h264", "h264"}, false, "id=0,streams=0,1"},
+		{"mixed codecs", []string{"h264", "vp9", "h264", "av1"}, true, "id=0,streams=0,2 id=1,streams=1 id=2,streams=3 id=3,streams=a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := adaptationSets(tt.codecs, tt.hasAudio); got != tt.want {
+				t.Errorf("adaptationSets() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package transcoder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	// trimSeekMargin is how far before the cut point the input is fast-seeked.
+	// The rest is decoded, so the cut lands on the exact frame rather than the previous keyframe.
+	trimSeekMargin = 10.0
+
+	// concatSampleRate and concatChannelLayout are the audio format every concat segment is resampled to
+	concatSampleRate    = 48000
+	concatChannelLayout = "stereo"
+)
+
+// mezzanineArgs encode an edited source at near-lossless quality. The result is only an
+// intermediate for the rendition ladder, so encode speed matters more than its size.
+var mezzanineArgs = []string{
+	"-c:v", "libx264", "-preset", "veryfast", "-crf", "10",
+	"-c:a", "flac",
+}
+
+// ConcatSegment is one input of a concatenation
+type ConcatSegment struct {
+	Path            string
+	DurationSeconds float64
+	HasVideo        bool
+	HasAudio        bool
+}
+
+// Trim cuts inputPath to [startSeconds, endSeconds) and writes a Matroska mezzanine to outputPath.
+// An endSeconds of zero keeps everything after startSeconds.
+// The video is re-encoded so the cut is frame accurate. The given text subtitle tracks are
+// retimed along with it and stored as ASS, which every text format converts to losslessly.
+func Trim(ctx context.Context, inputPath, outputPath string, startSeconds, endSeconds float64, subtitles []SubtitleStream) error {
+	if startSeconds < 0 || (endSeconds > 0 && endSeconds <= startSeconds) {
+		return fmt.Errorf("invalid trim range %.3f-%.3f", startSeconds, endSeconds)
+	}
+
+	// Fast input seek to shortly before the cut, then an accurate output seek for the remainder
+	seek := max(0, startSeconds-trimSeekMargin)
+	args := []string{
+		"-ss", formatSeconds(seek),
+		"-i", inputPath,
+		"-ss", formatSeconds(startSeconds - seek),
+	}
+	if endSeconds > 0 {
+		args = append(args, "-t", formatSeconds(endSeconds-startSeconds))
+	}
+	args = append(args, "-map", "0:v:0?", "-map", "0:a?")
+	for _, s := range subtitles {
+		if s.IsText() {
+			args = append(args, "-map", "0:"+strconv.Itoa(s.Index))
+		}
+	}
+	args = append(args, mezzanineArgs...)
+	args = append(args, "-c:s", "ass", "-f", "matroska", "-y", outputPath)
+
+	return runEditCommand(ctx, "trim", args)
+}
+
+// Concat joins segments in order and writes a Matroska mezzanine to outputPath.
+// Every segment is scaled and padded to width x height at frameRate, and its audio is
+// resampled to 48kHz stereo, so inputs of different shapes can be joined. Segments without
+// an audio track contribute silence for their duration. Subtitles are not carried.
+func Concat(ctx context.Context, segments []ConcatSegment, outputPath string, width, height int, frameRate float64) error {
+	if len(segments) < 2 {
+		return fmt.Errorf("concat needs at least two inputs, got %d", len(segments))
+	}
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("concat needs an output size")
+	}
+	if frameRate <= 0 {
+		frameRate = 30
+	}
+	width, height = width&^1, height&^1
+
+	var args []string
+	for _, s := range segments {
+		args = append(args, "-i", s.Path)
+	}
+
+	var filters []string
+	var concatInputs strings.Builder
+	silence := len(segments) // Input index of the next generated silence source
+	for i, s := range segments {
+		if !s.HasVideo {
+			return fmt.Errorf("concat input %d: %w", i+1, ErrNoVideoStream)
+		}
+		filters = append(filters, fmt.Sprintf(
+			"[%d:v:0]scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=%g,format=yuv420p,setpts=PTS-STARTPTS[v%d]",
+			i, width, height, width, height, frameRate, i))
+
+		audio := fmt.Sprintf("%d:a:0", i)
+		if !s.HasAudio {
+			args = append(args,
+				"-f", "lavfi",
+				"-t", formatSeconds(s.DurationSeconds),
+				"-i", fmt.Sprintf("anullsrc=r=%d:cl=%s", concatSampleRate, concatChannelLayout))
+			audio = fmt.Sprintf("%d:a:0", silence)
+			silence++
+		}
+		filters = append(filters, fmt.Sprintf(
+			"[%s]aresample=%d,aformat=sample_fmts=fltp:channel_layouts=%s,asetpts=PTS-STARTPTS[a%d]",
+			audio, concatSampleRate, concatChannelLayout, i))
+
+		fmt.Fprintf(&concatInputs, "[v%d][a%d]", i, i)
+	}
+	filters = append(filters, fmt.Sprintf("%sconcat=n=%d:v=1:a=1[v][a]", concatInputs.String(), len(segments)))
+
+	args = append(args,
+		"-filter_complex", strings.Join(filters, ";"),
+		"-map", "[v]", "-map", "[a]",
+	)
+	args = append(args, mezzanineArgs...)
+	args = append(args, "-f", "matroska", "-y", outputPath)
+
+	return runEditCommand(ctx, "concat", args)
+}
+
+// formatSeconds formats a time offset for FFmpeg's -ss/-t options with millisecond precision
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 3, 64)
+}
+
+func runEditCommand(ctx context.Context, operation string, args []string) error {
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return &CommandError{Op: "ffmpeg " + operation, Err: err, Output: stderr.String()}
+	}
+	return nil
+}
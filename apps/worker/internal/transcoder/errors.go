@@ -0,0 +1,77 @@
+package transcoder
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors for inputs and profiles that no retry can fix
+var (
+	ErrUnknownProfile = errors.New("unknown resolution profile")
+	ErrNoStreams      = errors.New("no audio or video streams found")
+	ErrNoVideoStream  = errors.New("no video stream")
+	ErrNoDuration     = errors.New("source has no duration")
+	ErrSilentAudio    = errors.New("audio is silent, so its loudness cannot be normalized")
+)
+
+// invalidInputMessages are FFmpeg/ffprobe messages meaning the input itself is broken or empty
+var invalidInputMessages = []string{
+	"Invalid data found when processing input",
+	"moov atom not found",
+	"EBML header parsing failed",
+	"matches no streams",
+	"does not contain any stream",
+}
+
+// unsupportedMessages are FFmpeg messages meaning this build lacks something the profile needs
+var unsupportedMessages = []string{
+	"Unknown encoder",
+	"No such filter",
+	"Unrecognized option",
+}
+
+// CommandError is returned when an FFmpeg or ffprobe run fails. Output holds the tool's
+// stderr, which tells a broken input apart from a run that failed for outside reasons.
+type CommandError struct {
+	Op     string // What was run, e.g., "ffmpeg" or "ffmpeg HLS packaging"
+	Err    error
+	Output string
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("%s failed: %v\nOutput: %s", e.Op, e.Err, e.Output)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidInput reports whether the run failed because its input can't be read as media
+func (e *CommandError) InvalidInput() bool {
+	return outputContains(e.Output, invalidInputMessages)
+}
+
+// Unsupported reports whether the run failed because FFmpeg lacks an encoder, filter or option
+func (e *CommandError) Unsupported() bool {
+	return outputContains(e.Output, unsupportedMessages)
+}
+
+func outputContains(output string, messages []string) bool {
+	for _, m := range messages {
+		if strings.Contains(output, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProfileError reports a profile that can't be encoded as configured
+type ProfileError struct {
+	Profile string
+	Reason  string
+}
+
+func (e *ProfileError) Error() string {
+	return fmt.Sprintf("profile %s: %s", e.Profile, e.Reason)
+}
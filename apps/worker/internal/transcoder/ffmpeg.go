@@ -4,45 +4,142 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate control modes for video profiles
+const (
+	// RateControlCRF encodes at constant quality, capped by MaxRate/BufSize when MaxRate is set
+	RateControlCRF = "crf"
+	// RateControlVBR targets VideoBitrate in a single pass, constrained by MaxRate/BufSize when set
+	RateControlVBR = "vbr"
+	// RateControlTwoPass targets VideoBitrate as an average over two passes for more even quality
+	RateControlTwoPass = "abr_2pass"
 )
 
 // Profile defines encoding settings - extensible for future options
 type Profile struct {
-	Name       string // "480p", "720p", "1080p"
-	Scale      string // FFmpeg scale filter: "-2:480"
-	VideoCodec string // "libx264", "libx265", etc.
-	AudioCodec string // "aac", "copy", etc.
-	Preset     string // "fast", "medium", "slow"
-	// Other fields, not currently used
-	// Bitrate     string
-	// CRF         int
-	// Deinterlace bool
+	Name         string // "480p", "720p", "1080p"
+	Scale        string // FFmpeg scale filter: "-2:480"
+	VideoCodec   string // "libx264", "libx265", "libsvtav1", "libaom-av1" or "libvpx-vp9"
+	AudioCodec   string // "aac", "copy", etc.
+	Preset       string // x264-style speed name ("fast", "medium", "slow"), mapped per codec
+	RateControl  string // RateControlCRF, RateControlVBR or RateControlTwoPass; empty derives it from VideoBitrate
+	CRF          int    // Constant quality for RateControlCRF, 0 for the encoder default
+	VideoBitrate string // Target video bitrate for the bitrate modes: "2500k"
+	MaxRate      string // Peak video bitrate cap: "3000k", empty for no cap
+	BufSize      string // Rate control buffer for MaxRate: "6000k", empty for one second at MaxRate
+	AudioBitrate string // Target audio bitrate: "128k", empty for the encoder default
+	MaxFPS       int    // Frame rate cap, 0 to keep the source rate
+	// Seconds between forced keyframes, 0 for the encoder's own GOP; a divisor of
+	// the HLS/DASH segment duration keeps segments aligned across renditions
+	KeyframeInterval float64
+	Container        string // Output container and file extension: "mp4", "mkv", "m4a", etc. Empty means mp4
+
+	// Audio-only profiles drop the video stream; Scale, VideoCodec, Preset, CRF,
+	// VideoBitrate and MaxFPS are ignored
+	AudioOnly       bool
+	AudioSampleRate int // Output sample rate in Hz, 0 to keep the source rate
+	AudioChannels   int // Output channel count, 0 to keep the source layout
+
+	// MinVMAF fails renditions whose mean VMAF against the source is lower; 0 disables the check
+	MinVMAF float64
+
+	// LoudnessTarget normalizes the audio to this integrated loudness in LUFS (e.g. -23)
+	// with two-pass loudnorm; 0 leaves the level untouched
+	LoudnessTarget float64
+	TruePeakLimit  float64 // True peak ceiling in dBTP when normalizing, 0 for DefaultTruePeakLimit
+
+	// Deinterlace and ToneMap are CorrectionAuto, CorrectionForce or CorrectionOff; empty means auto
+	Deinterlace string
+	ToneMap     string // HDR (PQ/HLG) to BT.709 SDR
+}
+
+// Extension returns the output file extension for the profile's container, including the dot
+func (p Profile) Extension() string {
+	if p.Container == "" {
+		return ".mp4"
+	}
+	return "." + p.Container
+}
+
+// RateControlMode returns the profile's rate control mode. Profiles without an explicit
+// mode encode at constant quality unless they set a target bitrate.
+func (p Profile) RateControlMode() string {
+	if p.RateControl != "" {
+		return p.RateControl
+	}
+	if p.VideoBitrate != "" {
+		return RateControlVBR
+	}
+	return RateControlCRF
+}
+
+// Options controls optional behaviour of a transcode
+type Options struct {
+	// Duration of the source, used to compute percent complete and ETA
+	Duration time.Duration
+	// OnProgress, if set, is called for every progress report FFmpeg emits (roughly twice a second)
+	OnProgress func(Progress)
+	// Threads caps the encoder's thread count; zero lets FFmpeg decide
+	Threads int
+	// SubtitleStreams lists source text subtitle streams (stream indexes) to carry into the
+	// output as soft subtitles; containers without text subtitle support drop them
+	SubtitleStreams []int
+	// BurnSubtitles is a subtitles filter (see BurnInFilter) rendered into the picture before scaling
+	BurnSubtitles string
+	// Watermark is a logo and/or text overlay drawn on the scaled picture; nil for none
+	Watermark *Watermark
+	// AudioFilter is applied to the audio before encoding, e.g. a second-pass LoudnormFilter
+	AudioFilter string
+	// Deinterlace and ToneMap add bwdif/yadif and HDR-to-SDR tone mapping ahead of the other
+	// filters (see Profile.Corrections)
+	Deinterlace bool
+	ToneMap     bool
+	// VideoOnly drops the audio, for chunk encodes whose audio is added when the chunks are stitched
+	VideoOnly bool
+	// FrameRate is the source's frame rate, which sizes the GOP of profiles with a keyframe
+	// interval; zero leaves the GOP length to the encoder
+	FrameRate float64
+	// KeyframeOffset is where the input starts on the output's timeline, in seconds. Chunk encodes
+	// set it to the chunk's start so forced keyframes land on the whole file's keyframe grid.
+	KeyframeOffset float64
 }
 
 // DefaultProfiles contains the standard transcoding profiles for MVP
 // This map is extensible - add new profiles or modify existing ones as needed
 var DefaultProfiles = map[string]Profile{
 	"480p": {
-		Name:       "480p",
-		Scale:      "-2:480",
-		VideoCodec: "libx264",
-		AudioCodec: "aac",
-		Preset:     "fast",
+		Name:             "480p",
+		Scale:            "-2:480",
+		VideoCodec:       "libx264",
+		AudioCodec:       "aac",
+		Preset:           "fast",
+		Container:        "mp4",
+		KeyframeInterval: 2,
 	},
 	"720p": {
-		Name:       "720p",
-		Scale:      "-2:720",
-		VideoCodec: "libx264",
-		AudioCodec: "aac",
-		Preset:     "fast",
+		Name:             "720p",
+		Scale:            "-2:720",
+		VideoCodec:       "libx264",
+		AudioCodec:       "aac",
+		Preset:           "fast",
+		Container:        "mp4",
+		KeyframeInterval: 2,
 	},
 	"1080p": {
-		Name:       "1080p",
-		Scale:      "-2:1080",
-		VideoCodec: "libx264",
-		AudioCodec: "aac",
-		Preset:     "fast",
+		Name:             "1080p",
+		Scale:            "-2:1080",
+		VideoCodec:       "libx264",
+		AudioCodec:       "aac",
+		Preset:           "fast",
+		Container:        "mp4",
+		KeyframeInterval: 2,
 	},
 }
 
@@ -51,36 +148,63 @@ var DefaultProfiles = map[string]Profile{
 func GetProfile(resolution string) (Profile, error) {
 	profile, ok := DefaultProfiles[resolution]
 	if !ok {
-		return Profile{}, fmt.Errorf("unknown resolution profile: %s", resolution)
+		return Profile{}, fmt.Errorf("%w: %s", ErrUnknownProfile, resolution)
 	}
 	return profile, nil
 }
 
 // Transcode executes FFmpeg to transcode the input file to the specified resolution
 // It uses the default profile for the given resolution
-func Transcode(ctx context.Context, inputPath, outputPath, resolution string) error {
+func Transcode(ctx context.Context, inputPath, outputPath, resolution string, opts Options) error {
 	profile, err := GetProfile(resolution)
 	if err != nil {
 		return err
 	}
 
-	return TranscodeWithProfile(ctx, inputPath, outputPath, profile)
+	return TranscodeWithProfile(ctx, inputPath, outputPath, profile, opts)
 }
 
 // TranscodeWithProfile executes FFmpeg with the given profile settings
 // This allows for custom profiles beyond the defaults
-func TranscodeWithProfile(ctx context.Context, inputPath, outputPath string, profile Profile) error {
-	// Build FFmpeg arguments
-	args := []string{
-		"-i", inputPath,
-		"-vf", fmt.Sprintf("scale=%s", profile.Scale),
-		"-c:v", profile.VideoCodec,
-		"-preset", profile.Preset,
-		"-c:a", profile.AudioCodec,
-		"-y",
-		outputPath,
+func TranscodeWithProfile(ctx context.Context, inputPath, outputPath string, profile Profile, opts Options) error {
+	if err := profile.Validate(); err != nil {
+		return err
 	}
 
+	if profile.AudioOnly || profile.RateControlMode() != RateControlTwoPass {
+		return runFFmpeg(ctx, buildArgs(inputPath, outputPath, profile, opts, 0, ""), opts)
+	}
+
+	// Two-pass: the stats from pass 1 live next to the output in the job's temp dir,
+	// so they are removed with it. Progress is reported as 0-50% for pass 1 and 50-100% for pass 2.
+	logPrefix := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + "_passlog"
+	firstPass := opts
+	firstPass.OnProgress = scaleProgress(opts.OnProgress, 0)
+	if err := runFFmpeg(ctx, buildArgs(inputPath, os.DevNull, profile, firstPass, 1, logPrefix), firstPass); err != nil {
+		return fmt.Errorf("first pass: %w", err)
+	}
+	secondPass := opts
+	secondPass.OnProgress = scaleProgress(opts.OnProgress, 50)
+	return runFFmpeg(ctx, buildArgs(inputPath, outputPath, profile, secondPass, 2, logPrefix), secondPass)
+}
+
+// scaleProgress maps one pass of a two-pass encode onto half of the overall progress,
+// starting at offset percent. Only the second pass's final report is marked done.
+func scaleProgress(onProgress func(Progress), offset float64) func(Progress) {
+	if onProgress == nil {
+		return nil
+	}
+	return func(p Progress) {
+		p.Percent = offset + p.Percent/2
+		if offset == 0 {
+			p.Done = false
+		}
+		onProgress(p)
+	}
+}
+
+// runFFmpeg runs one FFmpeg invocation, streaming progress to opts.OnProgress when set
+func runFFmpeg(ctx context.Context, args []string, opts Options) error {
 	// Create command with context for cancellation support
 	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
 
@@ -88,11 +212,133 @@ func TranscodeWithProfile(ctx context.Context, inputPath, outputPath string, pro
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
-	// Run the command
-	if err := cmd.Run(); err != nil {
-		// Include FFmpeg's stderr output in the error for debugging
-		return fmt.Errorf("ffmpeg failed: %w\nOutput: %s", err, stderr.String())
+	if opts.OnProgress == nil {
+		// Run the command
+		if err := cmd.Run(); err != nil {
+			// Include FFmpeg's stderr output in the error for debugging
+			return &CommandError{Op: "ffmpeg", Err: err, Output: stderr.String()}
+		}
+		return nil
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to attach to ffmpeg stdout: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	// Consume progress until FFmpeg closes stdout, then collect its exit status
+	readProgress(stdout, opts.Duration, opts.OnProgress)
+	if err := cmd.Wait(); err != nil {
+		return &CommandError{Op: "ffmpeg", Err: err, Output: stderr.String()}
 	}
 
 	return nil
 }
+
+// buildArgs assembles the FFmpeg command line for encoding one rendition.
+// pass is 1 or 2 during a two-pass encode (stats under logPrefix) and 0 otherwise;
+// pass 1 only analyses the video, so audio is dropped and the output discarded.
+func buildArgs(inputPath, outputPath string, profile Profile, opts Options, pass int, logPrefix string) []string {
+	args := []string{"-i", inputPath}
+
+	// videoMap is the stream or filtergraph output to encode, when it must be mapped explicitly
+	videoMap := ""
+	if profile.AudioOnly {
+		// -vn also drops attached cover art, which .mp3/.m4a muxers would otherwise keep as a video stream
+		args = append(args, "-vn")
+	} else {
+		// Corrections run on the source frames first; subtitles are burned in at source
+		// resolution so the text is scaled with the picture
+		var filters []string
+		if corrections := CorrectionFilters(opts.Deinterlace, opts.ToneMap); corrections != "" {
+			filters = append(filters, corrections)
+		}
+		if opts.BurnSubtitles != "" {
+			filters = append(filters, opts.BurnSubtitles)
+		}
+		filters = append(filters, fmt.Sprintf("scale=%s", profile.Scale))
+		if w := opts.Watermark; w != nil && w.Text != "" {
+			filters = append(filters, w.textFilter())
+		}
+		if w := opts.Watermark; w != nil && w.ImagePath != "" {
+			// The logo is a second input, so the chain becomes a filtergraph with a labelled output
+			args = append(args, "-loop", "1", "-i", w.ImagePath)
+			args = append(args, "-filter_complex", w.overlayGraph(strings.Join(filters, ",")))
+			videoMap = "[vout]"
+		} else {
+			args = append(args, "-vf", strings.Join(filters, ","))
+		}
+		// Encoder, preset and rate control flags differ per codec
+		args = append(args, videoArgs(profile, pass, logPrefix, opts)...)
+		if opts.ToneMap {
+			// Tag the output as SDR so players do not treat it as HDR
+			args = append(args, "-color_primaries", "bt709", "-color_trc", "bt709", "-colorspace", "bt709")
+		}
+		if profile.MaxFPS > 0 {
+			// -fpsmax only lowers the rate of sources above the cap
+			args = append(args, "-fpsmax", strconv.Itoa(profile.MaxFPS))
+		}
+	}
+
+	if pass == 1 || opts.VideoOnly {
+		args = append(args, "-an")
+	} else {
+		args = append(args, audioArgs(profile, opts)...)
+	}
+
+	codec := subtitleCodec(profile.Extension())
+	carrySubtitles := codec != "" && len(opts.SubtitleStreams) > 0 && !profile.AudioOnly && pass != 1
+	if videoMap != "" || carrySubtitles {
+		// Explicit maps replace FFmpeg's default stream selection, so video and audio are mapped too
+		if videoMap == "" {
+			videoMap = "0:v:0"
+		}
+		args = append(args, "-map", videoMap, "-map", "0:a:0?")
+	}
+	if carrySubtitles {
+		for _, index := range opts.SubtitleStreams {
+			args = append(args, "-map", "0:"+strconv.Itoa(index))
+		}
+		args = append(args, "-c:s", codec)
+	} else {
+		// Without -sn FFmpeg picks a subtitle stream on its own, which fails for bitmap tracks
+		args = append(args, "-sn")
+	}
+
+	if opts.Threads > 0 {
+		args = append(args, "-threads", strconv.Itoa(opts.Threads))
+	}
+	if opts.OnProgress != nil {
+		// Machine-readable progress on stdout; -nostats keeps stderr free of the interactive status line
+		args = append(args, "-progress", "pipe:1", "-nostats")
+	}
+
+	if pass == 1 {
+		return append(args, "-f", "null", "-y", outputPath)
+	}
+	return append(args, "-y", outputPath)
+}
+
+// audioArgs returns the audio encoder and format arguments for the profile
+func audioArgs(profile Profile, opts Options) []string {
+	args := []string{"-c:a", profile.AudioCodec}
+	if profile.AudioBitrate != "" {
+		args = append(args, "-b:a", profile.AudioBitrate)
+	}
+	if opts.AudioFilter != "" {
+		args = append(args, "-af", opts.AudioFilter)
+	}
+	if profile.AudioSampleRate > 0 {
+		args = append(args, "-ar", strconv.Itoa(profile.AudioSampleRate))
+	} else if opts.AudioFilter != "" {
+		// loudnorm resamples to 192kHz, so the source rate is not kept anyway
+		args = append(args, "-ar", strconv.Itoa(loudnormSampleRate))
+	}
+	if profile.AudioChannels > 0 {
+		args = append(args, "-ac", strconv.Itoa(profile.AudioChannels))
+	}
+	return args
+}
@@ -0,0 +1,66 @@
+package transcoder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildArgsAudioOnly(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Profile
+		opts    Options
+		want    []string
+	}{
+		{
+			name:    "aac in m4a",
+			profile: Profile{Name: "podcast", AudioCodec: "aac", AudioBitrate: "96k", Container: "m4a", AudioOnly: true},
+			want:    []string{"-i", "in.mp4", "-vn", "-c:a", "aac", "-b:a", "96k", "-sn", "-y", "out.m4a"},
+		},
+		{
+			name: "mp3 resampled to stereo",
+			profile: Profile{
+				Name: "mp3", AudioCodec: "libmp3lame", AudioBitrate: "128k", Container: "mp3", AudioOnly: true,
+				AudioSampleRate: 44100, AudioChannels: 2,
+			},
+			want: []string{"-i", "in.mp4", "-vn", "-c:a", "libmp3lame", "-b:a", "128k", "-ar", "44100", "-ac", "2", "-sn", "-y", "out.mp3"},
+		},
+		{
+			// Video settings, subtitles and watermarks have nothing to apply to
+			name:    "video options ignored",
+			profile: Profile{Name: "opus", Scale: "-2:720", VideoCodec: "libx264", MaxFPS: 30, AudioCodec: "libopus", Container: "opus", AudioOnly: true},
+			opts:    Options{SubtitleStreams: []int{2}, Watermark: &Watermark{Text: "draft"}, Deinterlace: true, ToneMap: true},
+			want:    []string{"-i", "in.mp4", "-vn", "-c:a", "libopus", "-sn", "-y", "out.opus"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildArgs("in.mp4", "out"+tt.profile.Extension(), tt.profile, tt.opts, 0, "")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateAudioOnly(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Profile
+		wantErr bool
+	}{
+		{name: "no video settings", profile: Profile{Name: "m4a", AudioCodec: "aac", Container: "m4a", AudioOnly: true}},
+		{name: "video settings ignored", profile: Profile{Name: "mp3", VideoCodec: "mpeg2video", Preset: "turbo", AudioCodec: "libmp3lame", Container: "mp3", AudioOnly: true}},
+		{name: "webm with opus", profile: Profile{Name: "webm", AudioCodec: "libopus", Container: "webm", AudioOnly: true}},
+		{name: "webm with aac", profile: Profile{Name: "webm", AudioCodec: "aac", Container: "webm", AudioOnly: true}, wantErr: true},
+		{name: "video profile without codec", profile: Profile{Name: "720p", AudioCodec: "aac", Container: "m4a"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.profile.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package transcoder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// HLSSegmentDuration is the target HLS segment length in seconds
+	HLSSegmentDuration = 6
+	// HLSMediaPlaylistName is the file name of each rendition's media playlist
+	HLSMediaPlaylistName = "index.m3u8"
+	// HLSMasterPlaylistName is the file name of the master playlist listing all variants
+	HLSMasterPlaylistName = "master.m3u8"
+	// HLSInitSegmentName is the file name of the init segment of fragmented-MP4 renditions
+	HLSInitSegmentName = "init.mp4"
+)
+
+// MPEG-TS segments carry H.264 with these audio codecs; every other rendition is segmented as
+// fragmented MP4, which also carries HEVC, VP9, AV1 and Opus (Vorbis fits in neither)
+var (
+	tsVideoCodecs = map[string]bool{"h264": true}
+	tsAudioCodecs = map[string]bool{"aac": true, "mp3": true, "ac3": true, "eac3": true}
+)
+
+// Variant describes one rendition entry in an HLS master playlist
+type Variant struct {
+	URI              string // Media playlist path relative to the master playlist
+	Bandwidth        int64  // Peak segment bitrate in bits per second
+	AverageBandwidth int64  // Average bitrate across all segments in bits per second
+	Width            int
+	Height           int
+	Codecs           string // RFC 6381 codec strings, e.g. "avc1.64001F,mp4a.40.2"; empty if unknown
+	SegmentType      string // "mpegts" or "fmp4" (see hlsSegmentType)
+}
+
+// PackageHLS remuxes an encoded rendition into segments plus a media playlist. H.264 with
+// AAC, MP3 or AC-3 audio goes into MPEG-TS segments for the widest player support; other
+// codecs go into fragmented-MP4 segments with an init segment (see hlsSegmentType).
+// The streams are copied, not re-encoded, so segments are cut on the rendition's keyframes.
+// info is the probe of the rendition. Returns the path of the generated media playlist inside outputDir.
+func PackageHLS(ctx context.Context, inputPath, outputDir string, info *MediaInfo) (string, error) {
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create HLS output dir: %w", err)
+	}
+
+	playlistPath := filepath.Join(outputDir, HLSMediaPlaylistName)
+	args := []string{
+		"-i", inputPath,
+		"-c", "copy",
+		"-sn", // Subtitles are published as WebVTT sidecars; neither segment type carries mov_text
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(HLSSegmentDuration),
+		"-hls_playlist_type", "vod",
+	}
+	if hlsSegmentType(info) == "fmp4" {
+		if info.VideoCodec == "hevc" {
+			// Apple players only decode HEVC tagged hvc1, which a stream copy from MKV doesn't carry
+			args = append(args, hevcTag("mp4")...)
+		}
+		args = append(args,
+			"-hls_segment_type", "fmp4",
+			"-hls_fmp4_init_filename", HLSInitSegmentName,
+			"-hls_segment_filename", filepath.Join(outputDir, "segment_%04d.m4s"),
+		)
+	} else {
+		args = append(args, "-hls_segment_filename", filepath.Join(outputDir, "segment_%04d.ts"))
+	}
+	args = append(args, "-y", playlistPath)
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", &CommandError{Op: "ffmpeg HLS packaging", Err: err, Output: stderr.String()}
+	}
+
+	return playlistPath, nil
+}
+
+// hlsSegmentType returns "mpegts" when MPEG-TS can carry every stream of the rendition, "fmp4" otherwise
+func hlsSegmentType(info *MediaInfo) string {
+	if info.HasVideo() && !tsVideoCodecs[info.VideoCodec] {
+		return "fmp4"
+	}
+	if info.HasAudio() && !tsAudioCodecs[info.AudioCodec] {
+		return "fmp4"
+	}
+	return "mpegts"
+}
+
+// NewVariant builds the master playlist entry for a packaged rendition.
+// Bandwidth figures are measured from the segments listed in the media playlist;
+// the resolution and codecs come from info, the probe of the encoded rendition.
+func NewVariant(info *MediaInfo, playlistPath, uri string) (Variant, error) {
+	peak, average, err := measureSegmentBitrates(playlistPath)
+	if err != nil {
+		return Variant{}, err
+	}
+
+	return Variant{
+		URI:              uri,
+		Bandwidth:        peak,
+		AverageBandwidth: average,
+		Width:            info.Width,
+		Height:           info.Height,
+		Codecs:           codecsAttribute(info),
+		SegmentType:      hlsSegmentType(info),
+	}, nil
+}
+
+// WriteMasterPlaylist writes an HLS master playlist listing the variants in ascending bandwidth order
+func WriteMasterPlaylist(path string, variants []Variant) error {
+	sorted := make([]Variant, len(variants))
+	copy(sorted, variants)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Bandwidth < sorted[j].Bandwidth })
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	fmt.Fprintf(&b, "#EXT-X-VERSION:%d\n", playlistVersion(sorted))
+	for _, v := range sorted {
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d,AVERAGE-BANDWIDTH=%d,RESOLUTION=%dx%d",
+			v.Bandwidth, v.AverageBandwidth, v.Width, v.Height)
+		if v.Codecs != "" {
+			fmt.Fprintf(&b, ",CODECS=\"%s\"", v.Codecs)
+		}
+		b.WriteString("\n" + v.URI + "\n")
+	}
+
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write master playlist: %w", err)
+	}
+	return nil
+}
+
+// playlistVersion returns the HLS protocol version a master playlist needs: 7 once any variant
+// is segmented as fragmented MP4, whose media playlists use EXT-X-MAP, and 3 otherwise
+func playlistVersion(variants []Variant) int {
+	for _, v := range variants {
+		if v.SegmentType == "fmp4" {
+			return 7
+		}
+	}
+	return 3
+}
+
+// measureSegmentBitrates reads a media playlist and computes peak and average
+// bitrates from each segment's size and #EXTINF duration
+func measureSegmentBitrates(playlistPath string) (peak int64, average int64, err error) {
+	file, err := os.Open(playlistPath)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open playlist %s: %w", playlistPath, err)
+	}
+	defer file.Close()
+
+	dir := filepath.Dir(playlistPath)
+	var totalBits, totalSeconds float64
+	var segmentDuration float64
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "#EXTINF:"):
+			value := strings.SplitN(strings.TrimPrefix(line, "#EXTINF:"), ",", 2)[0]
+			segmentDuration, err = strconv.ParseFloat(value, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid segment duration %q: %w", line, err)
+			}
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		default:
+			info, err := os.Stat(filepath.Join(dir, line))
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to stat segment %s: %w", line, err)
+			}
+			bits := float64(info.Size() * 8)
+			if segmentDuration > 0 {
+				if rate := int64(bits / segmentDuration); rate > peak {
+					peak = rate
+				}
+			}
+			totalBits += bits
+			totalSeconds += segmentDuration
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, fmt.Errorf("failed to read playlist %s: %w", playlistPath, err)
+	}
+	if totalSeconds == 0 {
+		return 0, 0, fmt.Errorf("playlist %s has no segments", playlistPath)
+	}
+
+	return peak, int64(totalBits / totalSeconds), nil
+}
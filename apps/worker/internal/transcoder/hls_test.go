@@ -0,0 +1,73 @@
+package transcoder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHLSSegmentType(t *testing.T) {
+	tests := []struct {
+		name string
+		info MediaInfo
+		want string
+	}{
+		{"h264 aac", MediaInfo{VideoCodec: "h264", AudioCodec: "aac"}, "mpegts"},
+		{"h264 without audio", MediaInfo{VideoCodec: "h264"}, "mpegts"},
+		{"h264 opus", MediaInfo{VideoCodec: "h264", AudioCodec: "opus"}, "fmp4"},
+		{"hevc aac", MediaInfo{VideoCodec: "hevc", AudioCodec: "aac"}, "fmp4"},
+		{"vp9 opus", MediaInfo{VideoCodec: "vp9", AudioCodec: "opus"}, "fmp4"},
+		{"av1 aac", MediaInfo{VideoCodec: "av1", AudioCodec: "aac"}, "fmp4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hlsSegmentType(&tt.info); got != tt.want {
+				t.Errorf("hlsSegmentType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteMasterPlaylist(t *testing.T) {
+	tests := []struct {
+		name     string
+		variants []Variant
+		want     string
+	}{
+		{
+			name: "mpegts only",
+			variants: []Variant{
+				{URI: "720p/index.m3u8", Bandwidth: 3000000, AverageBandwidth: 2500000, Width: 1280, Height: 720, Codecs: "avc1.64001F,mp4a.40.2", SegmentType: "mpegts"},
+				{URI: "480p/index.m3u8", Bandwidth: 1500000, AverageBandwidth: 1200000, Width: 854, Height: 480, SegmentType: "mpegts"},
+			},
+			want: "#EXTM3U\n#EXT-X-VERSION:3\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=1500000,AVERAGE-BANDWIDTH=1200000,RESOLUTION=854x480\n480p/index.m3u8\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=3000000,AVERAGE-BANDWIDTH=2500000,RESOLUTION=1280x720,CODECS=\"avc1.64001F,mp4a.40.2\"\n720p/index.m3u8\n",
+		},
+		{
+			name: "fmp4 variant raises the version",
+			variants: []Variant{
+				{URI: "720p/index.m3u8", Bandwidth: 3000000, AverageBandwidth: 2500000, Width: 1280, Height: 720, SegmentType: "mpegts"},
+				{URI: "av1/index.m3u8", Bandwidth: 2000000, AverageBandwidth: 1600000, Width: 1280, Height: 720, Codecs: "av01.0.05M.08,opus", SegmentType: "fmp4"},
+			},
+			want: "#EXTM3U\n#EXT-X-VERSION:7\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=2000000,AVERAGE-BANDWIDTH=1600000,RESOLUTION=1280x720,CODECS=\"av01.0.05M.08,opus\"\nav1/index.m3u8\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=3000000,AVERAGE-BANDWIDTH=2500000,RESOLUTION=1280x720\n720p/index.m3u8\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), HLSMasterPlaylistName)
+			if err := WriteMasterPlaylist(path, tt.variants); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("master playlist:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package transcoder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultTruePeakLimit is the true peak ceiling in dBTP used when a profile sets none (EBU R128)
+	DefaultTruePeakLimit = -1.0
+	// loudnessRangeTarget is the loudness range (LU) loudnorm aims for; it only matters in dynamic mode
+	loudnessRangeTarget = 11.0
+	// loudnormSampleRate is the output rate when the profile keeps the source rate, since loudnorm
+	// resamples to 192kHz internally
+	loudnormSampleRate = 48000
+)
+
+// Loudness is an EBU R128 loudness measurement
+type Loudness struct {
+	Integrated float64 // Integrated loudness in LUFS
+	TruePeak   float64 // True peak in dBTP
+	Range      float64 // Loudness range in LU
+	Threshold  float64 // Gating threshold in LUFS, needed by the second loudnorm pass
+	Offset     float64 // Gain offset loudnorm suggests for the second pass, in LU
+}
+
+// TruePeak returns the profile's true peak ceiling for loudness normalization
+func (p Profile) TruePeak() float64 {
+	if p.TruePeakLimit == 0 {
+		return DefaultTruePeakLimit
+	}
+	return p.TruePeakLimit
+}
+
+// loudnormReport is the JSON block loudnorm prints with print_format=json; values are strings
+type loudnormReport struct {
+	InputI       string `json:"input_i"`
+	InputTP      string `json:"input_tp"`
+	InputLRA     string `json:"input_lra"`
+	InputThresh  string `json:"input_thresh"`
+	TargetOffset string `json:"target_offset"`
+}
+
+// MeasureLoudness runs loudnorm's analysis pass over a file's first audio stream.
+// target and truePeak are the normalization goal; they only affect the reported Offset.
+func MeasureLoudness(ctx context.Context, path string, target, truePeak float64) (*Loudness, error) {
+	args := []string{
+		"-i", path,
+		"-map", "0:a:0",
+		"-af", fmt.Sprintf("loudnorm=I=%g:TP=%g:LRA=%g:print_format=json", target, truePeak, loudnessRangeTarget),
+		"-f", "null", "-",
+	}
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, &CommandError{Op: "ffmpeg loudness measurement", Err: err, Output: stderr.String()}
+	}
+	return parseLoudnormReport(stderr.String())
+}
+
+// parseLoudnormReport reads the measurement from the stderr of a loudnorm analysis pass.
+// The report is the last JSON object FFmpeg writes there.
+func parseLoudnormReport(output string) (*Loudness, error) {
+	start, end := strings.LastIndex(output, "{"), strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("loudnorm printed no measurement")
+	}
+	var report loudnormReport
+	if err := json.Unmarshal([]byte(output[start:end+1]), &report); err != nil {
+		return nil, fmt.Errorf("failed to parse loudnorm measurement: %w", err)
+	}
+
+	var l Loudness
+	for _, f := range []struct {
+		value string
+		dst   *float64
+	}{
+		{report.InputI, &l.Integrated},
+		{report.InputTP, &l.TruePeak},
+		{report.InputLRA, &l.Range},
+		{report.InputThresh, &l.Threshold},
+		{report.TargetOffset, &l.Offset},
+	} {
+		v, err := strconv.ParseFloat(f.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid loudnorm value %q: %w", f.value, err)
+		}
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			// Silent audio measures as -inf
+			return nil, fmt.Errorf("loudnorm measured %s: %w", f.value, ErrSilentAudio)
+		}
+		*f.dst = v
+	}
+	return &l, nil
+}
+
+// LoudnormFilter returns the second-pass loudnorm filter that brings audio with the measured
+// loudness to target LUFS under the truePeak ceiling. Linear mode applies one constant gain,
+// so dynamics are kept; loudnorm falls back to dynamic mode if the peak would be exceeded.
+// The range target is raised to the measured range, which would otherwise also force dynamic mode.
+func LoudnormFilter(measured *Loudness, target, truePeak float64) string {
+	lra := min(max(loudnessRangeTarget, measured.Range), 50)
+	return fmt.Sprintf(
+		"loudnorm=I=%g:TP=%g:LRA=%g:measured_I=%g:measured_TP=%g:measured_LRA=%g:measured_thresh=%g:offset=%g:linear=true:print_format=none",
+		target, truePeak, lra,
+		measured.Integrated, measured.TruePeak, measured.Range, measured.Threshold, measured.Offset)
+}
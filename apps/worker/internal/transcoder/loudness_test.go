@@ -0,0 +1,124 @@
+package transcoder
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// loudnormOutput is the tail of FFmpeg's stderr after a loudnorm analysis pass
+const loudnormOutput = `Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'in.mp4':
+  Stream #0:1[0x2](und): Audio: aac (LC) (mp4a / 0x6134706D), 48000 Hz, stereo, fltp, 128 kb/s
+size=N/A time=00:01:00.00 bitrate=N/A speed= 412x
+[Parsed_loudnorm_0 @ 0x55d0c3a4c2c0] 
+{
+	"input_i" : "-27.61",
+	"input_tp" : "-4.47",
+	"input_lra" : "18.06",
+	"input_thresh" : "-39.20",
+	"output_i" : "-16.58",
+	"output_tp" : "-1.00",
+	"output_lra" : "14.78",
+	"output_thresh" : "-27.71",
+	"normalization_type" : "dynamic",
+	"target_offset" : "0.58"
+}
+`
+
+func TestParseLoudnormReport(t *testing.T) {
+	got, err := parseLoudnormReport(loudnormOutput)
+	if err != nil {
+		t.Fatalf("parseLoudnormReport() = %v", err)
+	}
+	want := Loudness{Integrated: -27.61, TruePeak: -4.47, Range: 18.06, Threshold: -39.20, Offset: 0.58}
+	if *got != want {
+		t.Errorf("parseLoudnormReport() = %+v, want %+v", *got, want)
+	}
+}
+
+func TestParseLoudnormReportErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+	}{
+		{name: "no report", output: "size=N/A time=00:01:00.00 bitrate=N/A\n"},
+		{name: "truncated report", output: "[Parsed_loudnorm_0 @ 0x1] \n{\n\t\"input_i\" : \"-27.61\",\n"},
+		{name: "not json", output: "{ input_i: -27.61 }"},
+		{name: "missing field", output: `{"input_i": "-27.61", "input_tp": "-4.47", "input_lra": "18.06", "input_thresh": "-39.20"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := parseLoudnormReport(tt.output); err == nil {
+				t.Errorf("parseLoudnormReport() = %+v, want an error", *got)
+			}
+		})
+	}
+}
+
+func TestParseLoudnormReportSilence(t *testing.T) {
+	// Silent audio has no measurable loudness, which no retry changes
+	output := `{"input_i": "-inf", "input_tp": "-inf", "input_lra": "0.00", "input_thresh": "-70.00", "target_offset": "inf"}`
+	if _, err := parseLoudnormReport(output); !errors.Is(err, ErrSilentAudio) {
+		t.Errorf("parseLoudnormReport() = %v, want %v", err, ErrSilentAudio)
+	}
+}
+
+func TestLoudnormFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		measured Loudness
+		want     string
+	}{
+		{
+			name:     "narrow range keeps the default range target",
+			measured: Loudness{Integrated: -27.61, TruePeak: -4.47, Range: 6.5, Threshold: -39.2, Offset: 0.58},
+			want:     "loudnorm=I=-23:TP=-1:LRA=11:measured_I=-27.61:measured_TP=-4.47:measured_LRA=6.5:measured_thresh=-39.2:offset=0.58:linear=true:print_format=none",
+		},
+		{
+			name:     "wide range raises the range target",
+			measured: Loudness{Integrated: -27.61, TruePeak: -4.47, Range: 18.06, Threshold: -39.2, Offset: 0.58},
+			want:     "loudnorm=I=-23:TP=-1:LRA=18.06:measured_I=-27.61:measured_TP=-4.47:measured_LRA=18.06:measured_thresh=-39.2:offset=0.58:linear=true:print_format=none",
+		},
+		{
+			name:     "range target capped at loudnorm's maximum",
+			measured: Loudness{Integrated: -30, TruePeak: -2, Range: 62, Threshold: -42, Offset: 1},
+			want:     "loudnorm=I=-23:TP=-1:LRA=50:measured_I=-30:measured_TP=-2:measured_LRA=62:measured_thresh=-42:offset=1:linear=true:print_format=none",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LoudnormFilter(&tt.measured, -23, -1); got != tt.want {
+				t.Errorf("LoudnormFilter() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruePeak(t *testing.T) {
+	if got := (Profile{}).TruePeak(); got != DefaultTruePeakLimit {
+		t.Errorf("TruePeak() without a limit = %g, want %g", got, DefaultTruePeakLimit)
+	}
+	if got := (Profile{TruePeakLimit: -2}).TruePeak(); got != -2 {
+		t.Errorf("TruePeak() = %g, want -2", got)
+	}
+}
+
+func TestAudioArgsLoudnorm(t *testing.T) {
+	filter := "loudnorm=I=-23"
+	tests := []struct {
+		name    string
+		profile Profile
+		want    []string
+	}{
+		// loudnorm resamples to 192kHz, so the output rate is set explicitly
+		{name: "source rate", profile: Profile{AudioCodec: "aac"}, want: []string{"-c:a", "aac", "-af", filter, "-ar", "48000"}},
+		{name: "profile rate", profile: Profile{AudioCodec: "aac", AudioSampleRate: 44100}, want: []string{"-c:a", "aac", "-af", filter, "-ar", "44100"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := audioArgs(tt.profile, Options{AudioFilter: filter}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("audioArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package transcoder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// complexitySamples is how many evenly spaced excerpts of the source a complexity probe encodes
+	complexitySamples = 3
+	// complexitySampleSeconds is the length of each excerpt
+	complexitySampleSeconds = 4.0
+)
+
+// Height returns the output height requested by the profile's scale filter,
+// or 0 when it is derived from the width (e.g. "1280:-2") or the profile is audio-only
+func (p Profile) Height() int {
+	if p.AudioOnly {
+		return 0
+	}
+	_, h, ok := strings.Cut(p.Scale, ":")
+	if !ok {
+		return 0
+	}
+	height, err := strconv.Atoi(h)
+	if err != nil || height <= 0 {
+		return 0
+	}
+	return height
+}
+
+// ProbeBitrate measures how hard the source is to encode with the given profile.
+// It encodes a few short excerpts at the profile's scale and codec in constant quality mode
+// with a fast preset, and returns the average video bitrate in kbit/s those excerpts needed.
+// Simple content (slides, animation) yields low figures, high-motion content high ones.
+func ProbeBitrate(ctx context.Context, inputPath, workDir string, profile Profile, durationSeconds float64, threads int) (int, error) {
+	if durationSeconds <= 0 {
+		return 0, fmt.Errorf("cannot probe complexity: %w", ErrNoDuration)
+	}
+	if err := profile.Validate(); err != nil {
+		return 0, err
+	}
+
+	probe := profile
+	probe.RateControl = RateControlCRF
+	probe.VideoBitrate, probe.MaxRate, probe.BufSize = "", "", ""
+	probe.Preset = "veryfast"
+	probe.Container = "mkv" // Carries every supported codec
+	if probe.CRF == 0 {
+		probe.CRF = videoCodecs[probe.VideoCodec].ReferenceCRF
+	}
+
+	// Short sources are encoded whole; longer ones are sampled at evenly spaced points
+	samples := complexitySamples
+	length := complexitySampleSeconds
+	if durationSeconds < complexitySamples*complexitySampleSeconds*2 {
+		samples, length = 1, durationSeconds
+	}
+
+	var totalBits, totalSeconds float64
+	for i := 0; i < samples; i++ {
+		start := durationSeconds * float64(i+1) / float64(samples+1)
+		if samples == 1 {
+			start = 0
+		}
+		seconds := math.Min(length, durationSeconds-start)
+
+		samplePath := filepath.Join(workDir, fmt.Sprintf("complexity_%s_%d.mkv", profile.Name, i))
+		args := []string{
+			"-ss", strconv.FormatFloat(start, 'f', 3, 64),
+			"-t", strconv.FormatFloat(seconds, 'f', 3, 64),
+			"-i", inputPath,
+			"-vf", fmt.Sprintf("scale=%s", probe.Scale),
+		}
+		args = append(args, videoArgs(probe, 0, "", Options{})...)
+		if threads > 0 {
+			args = append(args, "-threads", strconv.Itoa(threads))
+		}
+		args = append(args, "-an", "-sn", "-y", samplePath)
+
+		cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return 0, &CommandError{Op: "ffmpeg complexity probe", Err: err, Output: stderr.String()}
+		}
+
+		info, err := os.Stat(samplePath)
+		if err != nil {
+			return 0, fmt.Errorf("failed to stat complexity sample: %w", err)
+		}
+		os.Remove(samplePath)
+
+		totalBits += float64(info.Size() * 8)
+		totalSeconds += seconds
+	}
+
+	return int(totalBits / totalSeconds / 1000), nil
+}
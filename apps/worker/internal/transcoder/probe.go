@@ -0,0 +1,185 @@
+package transcoder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// MediaInfo holds the source properties reported by ffprobe
+type MediaInfo struct {
+	DurationSeconds    float64
+	Container          string // ffprobe format name, e.g. "mov,mp4,m4a,3gp,3g2,mj2"
+	VideoCodec         string // Empty if the file has no video stream
+	AudioCodec         string // Empty if the file has no audio stream
+	Width              int
+	Height             int
+	FrameRate          float64 // Average frames per second
+	VideoProfile       string  // Codec profile name, e.g. "High", "Main 10", "Profile 0"
+	VideoLevel         int     // Codec level as ffprobe reports it (e.g. 31 for H.264 3.1), 0 or less if unknown
+	PixelFormat        string  // e.g. "yuv420p", "yuv420p10le"
+	BitRate            int64   // Overall bitrate in bits per second
+	Rotation           int     // Display rotation in degrees, normalized to 0-359
+	AudioProfile       string  // e.g. "LC" or "HE-AAC" for AAC
+	AudioChannels      int
+	AudioChannelLayout string // e.g. "stereo", "5.1"
+	Subtitles          []SubtitleStream
+	ClosedCaptions     bool   // The video stream carries embedded EIA-608/708 captions
+	ColorTransfer      string // Transfer characteristics, e.g. "bt709", "smpte2084" (PQ), "arib-std-b67" (HLG)
+	ColorPrimaries     string // e.g. "bt709", "bt2020"
+	FieldOrder         string // Scan type: FieldOrderProgressive, FieldOrderTFF or FieldOrderBFF; empty until detected
+}
+
+// HasVideo reports whether the source contains a video stream
+func (m *MediaInfo) HasVideo() bool {
+	return m.VideoCodec != ""
+}
+
+// HasAudio reports whether the source contains an audio stream
+func (m *MediaInfo) HasAudio() bool {
+	return m.AudioCodec != ""
+}
+
+// ffprobeOutput mirrors the parts of `ffprobe -show_format -show_streams -of json` we read
+type ffprobeOutput struct {
+	Format struct {
+		FormatName string `json:"format_name"`
+		Duration   string `json:"duration"`
+		BitRate    string `json:"bit_rate"`
+	} `json:"format"`
+	Streams []struct {
+		Index          int               `json:"index"`
+		CodecType      string            `json:"codec_type"`
+		CodecName      string            `json:"codec_name"`
+		Profile        string            `json:"profile"`
+		Level          int               `json:"level"`
+		PixFmt         string            `json:"pix_fmt"`
+		Width          int               `json:"width"`
+		Height         int               `json:"height"`
+		AvgFrameRate   string            `json:"avg_frame_rate"`
+		RFrameRate     string            `json:"r_frame_rate"`
+		Channels       int               `json:"channels"`
+		ChannelLayout  string            `json:"channel_layout"`
+		ClosedCaptions int               `json:"closed_captions"`
+		ColorTransfer  string            `json:"color_transfer"`
+		ColorPrimaries string            `json:"color_primaries"`
+		Tags           map[string]string `json:"tags"`
+		SideDataList   []struct {
+			Rotation float64 `json:"rotation"`
+		} `json:"side_data_list"`
+	} `json:"streams"`
+}
+
+// Probe runs ffprobe on a media file and returns its source properties.
+// Only the first video and first audio stream are inspected; every subtitle stream is listed.
+func Probe(ctx context.Context, path string) (*MediaInfo, error) {
+	cmd := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-show_format",
+		"-show_streams",
+		"-of", "json",
+		path,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, &CommandError{Op: "ffprobe", Err: err, Output: stderr.String()}
+	}
+
+	var raw ffprobeOutput
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+
+	info := &MediaInfo{
+		Container: raw.Format.FormatName,
+	}
+	info.DurationSeconds, _ = strconv.ParseFloat(raw.Format.Duration, 64)
+	info.BitRate, _ = strconv.ParseInt(raw.Format.BitRate, 10, 64)
+
+	for _, s := range raw.Streams {
+		switch s.CodecType {
+		case "video":
+			if info.HasVideo() {
+				continue
+			}
+			info.VideoCodec = s.CodecName
+			info.VideoProfile = s.Profile
+			info.VideoLevel = s.Level
+			info.PixelFormat = s.PixFmt
+			info.ClosedCaptions = s.ClosedCaptions == 1
+			info.ColorTransfer = s.ColorTransfer
+			info.ColorPrimaries = s.ColorPrimaries
+			info.Width = s.Width
+			info.Height = s.Height
+			info.FrameRate = parseFrameRate(s.AvgFrameRate)
+			if info.FrameRate == 0 {
+				info.FrameRate = parseFrameRate(s.RFrameRate)
+			}
+
+			// Rotation is a "rotate" tag on older muxers and a display matrix on newer ones
+			if v, ok := s.Tags["rotate"]; ok {
+				deg, _ := strconv.Atoi(v)
+				info.Rotation = normalizeRotation(float64(deg))
+			}
+			for _, sd := range s.SideDataList {
+				if sd.Rotation != 0 {
+					info.Rotation = normalizeRotation(sd.Rotation)
+				}
+			}
+		case "audio":
+			if info.HasAudio() {
+				continue
+			}
+			info.AudioCodec = s.CodecName
+			info.AudioProfile = s.Profile
+			info.AudioChannels = s.Channels
+			info.AudioChannelLayout = s.ChannelLayout
+		case "subtitle":
+			info.Subtitles = append(info.Subtitles, SubtitleStream{
+				Index:    s.Index,
+				Position: len(info.Subtitles),
+				Codec:    s.CodecName,
+				Language: s.Tags["language"],
+			})
+		}
+	}
+
+	if !info.HasVideo() && !info.HasAudio() {
+		return nil, fmt.Errorf("%w in %s", ErrNoStreams, path)
+	}
+
+	return info, nil
+}
+
+// parseFrameRate converts an ffprobe rational such as "30000/1001" to frames per second
+func parseFrameRate(rate string) float64 {
+	num, den, found := strings.Cut(rate, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+	if !found {
+		return n
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d == 0 {
+		return 0
+	}
+	return math.Round(n/d*1000) / 1000
+}
+
+// normalizeRotation maps a rotation in degrees (possibly negative) onto 0-359
+func normalizeRotation(deg float64) int {
+	r := int(math.Round(deg)) % 360
+	if r < 0 {
+		r += 360
+	}
+	return r
+}
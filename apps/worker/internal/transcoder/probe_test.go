@@ -0,0 +1,47 @@
+package transcoder
+
+import "testing"
+
+func TestParseFrameRate(t *testing.T) {
+	tests := []struct {
+		rate string
+		want float64
+	}{
+		{"30/1", 30},
+		{"30000/1001", 29.97},
+		{"24000/1001", 23.976},
+		{"25", 25},
+		{"0/0", 0},
+		{"30/0", 0},
+		{"", 0},
+		{"abc/1", 0},
+		{"30/abc", 0},
+	}
+	for _, tt := range tests {
+		if got := parseFrameRate(tt.rate); got != tt.want {
+			t.Errorf("parseFrameRate(%q) = %g, want %g", tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeRotation(t *testing.T) {
+	tests := []struct {
+		deg  float64
+		want int
+	}{
+		{0, 0},
+		{90, 90},
+		{-90, 270},
+		{180, 180},
+		{-180, 180},
+		{270, 270},
+		{360, 0},
+		{450, 90},
+		{-90.00000000000001, 270},
+	}
+	for _, tt := range tests {
+		if got := normalizeRotation(tt.deg); got != tt.want {
+			t.Errorf("normalizeRotation(%g) = %d, want %d", tt.deg, got, tt.want)
+		}
+	}
+}
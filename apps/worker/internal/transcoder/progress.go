@@ -0,0 +1,73 @@
+package transcoder
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Progress is a snapshot of a running FFmpeg encode
+type Progress struct {
+	Percent float64       // 0-100, zero if the source duration is unknown
+	Speed   float64       // Encode speed as a multiple of realtime, e.g. 2.5 for "2.5x"
+	OutTime time.Duration // Position of the encoder in the output timeline
+	ETA     time.Duration // Estimated time remaining, zero if it cannot be estimated
+	Done    bool          // True on the final report once FFmpeg finishes
+}
+
+// readProgress parses the key=value blocks written by `ffmpeg -progress pipe:1`
+// and invokes onProgress once per block. Each block ends with a "progress=continue"
+// or "progress=end" line.
+func readProgress(r io.Reader, duration time.Duration, onProgress func(Progress)) {
+	var p Progress
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "out_time_us":
+			// "N/A" before the first frame is muxed
+			if us, err := strconv.ParseInt(value, 10, 64); err == nil && us >= 0 {
+				p.OutTime = time.Duration(us) * time.Microsecond
+			}
+		case "speed":
+			if s, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "x"), 64); err == nil {
+				p.Speed = s
+			}
+		case "progress":
+			p.Done = value == "end"
+			p.Percent, p.ETA = estimate(p, duration)
+			onProgress(p)
+		}
+	}
+}
+
+// estimate derives percent complete and time remaining from the encoder position
+func estimate(p Progress, duration time.Duration) (float64, time.Duration) {
+	if p.Done {
+		return 100, 0
+	}
+	if duration <= 0 {
+		return 0, 0
+	}
+
+	percent := float64(p.OutTime) / float64(duration) * 100
+	if percent > 100 {
+		percent = 100
+	}
+
+	var eta time.Duration
+	if p.Speed > 0 {
+		eta = time.Duration(float64(duration-p.OutTime) / p.Speed)
+		if eta < 0 {
+			eta = 0
+		}
+	}
+
+	return percent, eta
+}
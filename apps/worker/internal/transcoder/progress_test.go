@@ -0,0 +1,69 @@
+package transcoder
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadProgress(t *testing.T) {
+	input := strings.Join([]string{
+		"frame=0",
+		"out_time_us=N/A",
+		"speed=N/A",
+		"progress=continue",
+		"frame=120",
+		"out_time_us=5000000",
+		"speed=2.5x",
+		"progress=continue",
+		"out_time_us=10000000",
+		"speed= 2x",
+		"progress=continue",
+		"progress=end",
+	}, "\n")
+
+	var got []Progress
+	readProgress(strings.NewReader(input), 20*time.Second, func(p Progress) {
+		got = append(got, p)
+	})
+
+	want := []Progress{
+		{Percent: 0, Speed: 0, OutTime: 0, ETA: 0},
+		{Percent: 25, Speed: 2.5, OutTime: 5 * time.Second, ETA: 6 * time.Second},
+		{Percent: 50, Speed: 2, OutTime: 10 * time.Second, ETA: 5 * time.Second},
+		{Percent: 100, Speed: 2, OutTime: 10 * time.Second, ETA: 0, Done: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d reports, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("report %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name        string
+		p           Progress
+		duration    time.Duration
+		wantPercent float64
+		wantETA     time.Duration
+	}{
+		{"halfway at realtime", Progress{OutTime: 30 * time.Second, Speed: 1}, time.Minute, 50, 30 * time.Second},
+		{"halfway at 3x", Progress{OutTime: 30 * time.Second, Speed: 3}, time.Minute, 50, 10 * time.Second},
+		{"unknown speed", Progress{OutTime: 15 * time.Second}, time.Minute, 25, 0},
+		{"unknown duration", Progress{OutTime: 15 * time.Second, Speed: 1}, 0, 0, 0},
+		{"past the end", Progress{OutTime: 70 * time.Second, Speed: 1}, time.Minute, 100, 0},
+		{"done", Progress{OutTime: 10 * time.Second, Speed: 1, Done: true}, time.Minute, 100, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			percent, eta := estimate(tt.p, tt.duration)
+			if percent != tt.wantPercent || eta != tt.wantETA {
+				t.Errorf("estimate() = (%g, %v), want (%g, %v)", percent, eta, tt.wantPercent, tt.wantETA)
+			}
+		})
+	}
+}
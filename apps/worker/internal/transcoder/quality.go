@@ -0,0 +1,131 @@
+package transcoder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+)
+
+// QualityPercentile is the per-frame VMAF percentile reported as QualityScores.VMAFLow
+const QualityPercentile = 5
+
+// QualityScores are objective quality scores of an encode measured against its source
+type QualityScores struct {
+	VMAFMean float64 // Mean VMAF (0-100)
+	VMAFMin  float64 // Worst frame
+	VMAFLow  float64 // QualityPercentile-th percentile frame, less noisy than the minimum
+	PSNRMean float64 // Mean luma PSNR in dB
+	SSIMMean float64 // Mean SSIM (0-1)
+}
+
+// vmafLog is the subset of libvmaf's JSON log we read
+type vmafLog struct {
+	Frames []struct {
+		Metrics map[string]float64 `json:"metrics"`
+	} `json:"frames"`
+	PooledMetrics map[string]struct {
+		Min  float64 `json:"min"`
+		Mean float64 `json:"mean"`
+	} `json:"pooled_metrics"`
+}
+
+// MeasureQuality scores an encoded rendition against the source with libvmaf, computing
+// PSNR and SSIM in the same pass. Both inputs are scaled to width x height (normally the
+// source's display size) so renditions of every size are judged at the same viewing size.
+// The reference is resampled to the rendition's frame rate so frames line up when the
+// profile capped the frame rate. corrections (see CorrectionFilters) is applied to the
+// reference first, so a deinterlaced or tone-mapped rendition is compared like for like.
+func MeasureQuality(ctx context.Context, renditionPath, sourcePath string, width, height int, threads int, corrections string) (*QualityScores, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("cannot measure quality without a reference size")
+	}
+
+	rendition, err := Probe(ctx, renditionPath)
+	if err != nil {
+		return nil, err
+	}
+
+	logFile, err := os.CreateTemp(filepath.Dir(renditionPath), "vmaf-*.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create VMAF log: %w", err)
+	}
+	logPath := logFile.Name()
+	logFile.Close()
+	defer os.Remove(logPath)
+
+	scale := fmt.Sprintf("scale=%d:%d:flags=bicubic", width&^1, height&^1)
+	reference := scale
+	if rendition.FrameRate > 0 {
+		reference = fmt.Sprintf("fps=%g,%s", rendition.FrameRate, scale)
+	}
+	if corrections != "" {
+		reference = corrections + "," + reference
+	}
+	if threads <= 0 {
+		threads = 1
+	}
+	filter := fmt.Sprintf(
+		"[0:v]%s,setpts=PTS-STARTPTS[dist];[1:v]%s,setpts=PTS-STARTPTS[ref];"+
+			"[dist][ref]libvmaf=log_fmt=json:log_path=%s:feature=name=psnr|name=float_ssim:n_threads=%d",
+		scale, reference, logPath, threads)
+
+	args := []string{
+		"-i", renditionPath,
+		"-i", sourcePath,
+		"-lavfi", filter,
+		"-f", "null", "-",
+	}
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, &CommandError{Op: "ffmpeg quality measurement", Err: err, Output: stderr.String()}
+	}
+
+	raw, err := os.ReadFile(logPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read VMAF log: %w", err)
+	}
+	var scores vmafLog
+	if err := json.Unmarshal(raw, &scores); err != nil {
+		return nil, fmt.Errorf("failed to parse VMAF log: %w", err)
+	}
+
+	vmaf, ok := scores.PooledMetrics["vmaf"]
+	if !ok || len(scores.Frames) == 0 {
+		return nil, fmt.Errorf("VMAF log has no scores")
+	}
+
+	frames := make([]float64, 0, len(scores.Frames))
+	for _, f := range scores.Frames {
+		frames = append(frames, f.Metrics["vmaf"])
+	}
+
+	return &QualityScores{
+		VMAFMean: vmaf.Mean,
+		VMAFMin:  vmaf.Min,
+		VMAFLow:  percentile(frames, QualityPercentile),
+		PSNRMean: scores.PooledMetrics["psnr_y"].Mean,
+		SSIMMean: scores.PooledMetrics["float_ssim"].Mean,
+	}, nil
+}
+
+// percentile returns the p-th percentile (nearest rank) of values
+func percentile(values []float64, p float64) float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
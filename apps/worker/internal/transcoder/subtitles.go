@@ -0,0 +1,103 @@
+package transcoder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// textSubtitleCodecs are the subtitle formats that can be converted to WebVTT or mov_text.
+// Bitmap formats (PGS, DVD, DVB) would need OCR and are skipped.
+var textSubtitleCodecs = map[string]bool{
+	"subrip":   true,
+	"srt":      true,
+	"ass":      true,
+	"ssa":      true,
+	"webvtt":   true,
+	"mov_text": true,
+	"text":     true,
+}
+
+// SubtitleStream is a subtitle track found in a source file
+type SubtitleStream struct {
+	Index    int    // Stream index in the file, for -map 0:N
+	Position int    // Index among the file's subtitle streams, for the subtitles filter's si option
+	Codec    string // e.g. "subrip", "mov_text", "hdmv_pgs_subtitle"
+	Language string // ISO 639 tag from the stream metadata, empty or "und" if unknown
+}
+
+// IsText reports whether the track is text-based and can be converted
+func (s SubtitleStream) IsText() bool {
+	return textSubtitleCodecs[s.Codec]
+}
+
+// ConvertToWebVTT writes a subtitle track as a WebVTT file. streamIndex selects an embedded
+// stream of inputPath; pass -1 when inputPath is itself a subtitle file (SRT, ASS, VTT).
+func ConvertToWebVTT(ctx context.Context, inputPath, outputPath string, streamIndex int) error {
+	args := []string{"-i", inputPath}
+	if streamIndex >= 0 {
+		args = append(args, "-map", "0:"+strconv.Itoa(streamIndex))
+	}
+	args = append(args, "-c:s", "webvtt", "-f", "webvtt", "-y", outputPath)
+	return runSubtitleCommand(ctx, args)
+}
+
+// ExtractClosedCaptions writes the EIA-608/708 captions embedded in a video stream as WebVTT.
+// The lavfi movie source exposes them as a separate subtitle output ("subcc").
+func ExtractClosedCaptions(ctx context.Context, inputPath, outputPath string) error {
+	args := []string{
+		"-f", "lavfi",
+		"-i", fmt.Sprintf("movie=%s[out+subcc]", escapeFilterValue(inputPath)),
+		"-map", "0:s:0",
+		"-c:s", "webvtt",
+		"-f", "webvtt",
+		"-y", outputPath,
+	}
+	return runSubtitleCommand(ctx, args)
+}
+
+// BurnInFilter returns a subtitles filter that renders a track into the picture.
+// position selects an embedded subtitle stream of path (see SubtitleStream.Position);
+// pass -1 when path is a subtitle file.
+func BurnInFilter(path string, position int) string {
+	filter := "subtitles=filename=" + escapeFilterValue(path)
+	if position >= 0 {
+		filter += ":si=" + strconv.Itoa(position)
+	}
+	return filter
+}
+
+// subtitleCodec returns the subtitle encoder for a container extension, or "" when the
+// container cannot carry text subtitles
+func subtitleCodec(extension string) string {
+	switch extension {
+	case ".mp4", ".mov":
+		return "mov_text"
+	case ".mkv":
+		return "srt"
+	case ".webm":
+		return "webvtt"
+	default:
+		return ""
+	}
+}
+
+// escapeFilterValue escapes a path for use as a filter option value inside a filtergraph.
+// FFmpeg unescapes twice: once when splitting the graph and once when parsing options.
+func escapeFilterValue(value string) string {
+	option := strings.NewReplacer(`\`, `\\`, `'`, `\'`, `:`, `\:`).Replace(value)
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`, `[`, `\[`, `]`, `\]`, `,`, `\,`, `;`, `\;`).Replace(option)
+}
+
+func runSubtitleCommand(ctx context.Context, args []string) error {
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return &CommandError{Op: "ffmpeg subtitle conversion", Err: err, Output: stderr.String()}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package transcoder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// ThumbnailsVTTName is the file name of the WebVTT track mapping time ranges to sprite tiles
+	ThumbnailsVTTName = "thumbnails.vtt"
+	// spritePattern is the FFmpeg output pattern for sprite sheet images
+	spritePattern = "sprite_%03d.jpg"
+)
+
+// ThumbnailOptions controls scrub preview generation
+type ThumbnailOptions struct {
+	IntervalSeconds float64 // Time between captured frames
+	Width           int     // Tile width in pixels; height follows the source aspect ratio
+	Columns         int     // Tiles per sprite sheet row
+	Rows            int     // Tile rows per sprite sheet
+}
+
+// ThumbnailSet lists the files produced by GenerateThumbnails
+type ThumbnailSet struct {
+	SpritePaths []string // Sprite sheet images in playback order
+	VTTPath     string   // WebVTT track referencing the sprites by file name
+}
+
+// GenerateThumbnails captures a frame every opts.IntervalSeconds, tiles the frames into
+// sprite sheets of opts.Columns x opts.Rows and writes a WebVTT track that maps each
+// interval to its tile via #xywh media fragments. durationSeconds is the source duration.
+func GenerateThumbnails(ctx context.Context, inputPath, outputDir string, durationSeconds float64, opts ThumbnailOptions) (*ThumbnailSet, error) {
+	if durationSeconds <= 0 {
+		return nil, fmt.Errorf("cannot generate thumbnails: %w", ErrNoDuration)
+	}
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create thumbnails output dir: %w", err)
+	}
+
+	filter := fmt.Sprintf("fps=1/%g,scale=%d:-2,tile=%dx%d", opts.IntervalSeconds, opts.Width, opts.Columns, opts.Rows)
+	args := []string{
+		"-i", inputPath,
+		"-vf", filter,
+		"-an",
+		"-q:v", "4",
+		"-y",
+		filepath.Join(outputDir, spritePattern),
+	}
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, &CommandError{Op: "ffmpeg thumbnail extraction", Err: err, Output: stderr.String()}
+	}
+
+	spritePaths, err := filepath.Glob(filepath.Join(outputDir, "sprite_*.jpg"))
+	if err != nil || len(spritePaths) == 0 {
+		return nil, fmt.Errorf("ffmpeg produced no sprite sheets")
+	}
+	sort.Strings(spritePaths)
+
+	// The tile filter pads every sheet to the full grid, so any sheet gives the tile size
+	sheet, err := Probe(ctx, spritePaths[0])
+	if err != nil {
+		return nil, err
+	}
+	tileWidth := sheet.Width / opts.Columns
+	tileHeight := sheet.Height / opts.Rows
+
+	vttPath := filepath.Join(outputDir, ThumbnailsVTTName)
+	vtt := buildThumbnailsVTT(spritePaths, durationSeconds, tileWidth, tileHeight, opts)
+	if err := os.WriteFile(vttPath, []byte(vtt), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write thumbnails VTT: %w", err)
+	}
+
+	return &ThumbnailSet{SpritePaths: spritePaths, VTTPath: vttPath}, nil
+}
+
+// buildThumbnailsVTT writes one cue per captured frame pointing at its tile in a sprite sheet
+func buildThumbnailsVTT(spritePaths []string, durationSeconds float64, tileWidth, tileHeight int, opts ThumbnailOptions) string {
+	perSheet := opts.Columns * opts.Rows
+	count := int(math.Ceil(durationSeconds / opts.IntervalSeconds))
+	if capacity := len(spritePaths) * perSheet; count > capacity {
+		count = capacity
+	}
+
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	for i := 0; i < count; i++ {
+		start := float64(i) * opts.IntervalSeconds
+		end := math.Min(start+opts.IntervalSeconds, durationSeconds)
+		tile := i % perSheet
+		x := (tile % opts.Columns) * tileWidth
+		y := (tile / opts.Columns) * tileHeight
+
+		fmt.Fprintf(&b, "\n%s --> %s\n", vttTimestamp(start), vttTimestamp(end))
+		fmt.Fprintf(&b, "%s#xywh=%d,%d,%d,%d\n", filepath.Base(spritePaths[i/perSheet]), x, y, tileWidth, tileHeight)
+	}
+	return b.String()
+}
+
+// vttTimestamp formats seconds as a WebVTT timestamp (HH:MM:SS.mmm)
+func vttTimestamp(seconds float64) string {
+	ms := int64(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,68 @@
+package transcoder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildThumbnailsVTT(t *testing.T) {
+	opts := ThumbnailOptions{IntervalSeconds: 10, Width: 90, Columns: 2, Rows: 2}
+
+	// Five frames fill the first 2x2 sheet and start the second; the last cue ends with the source
+	got := buildThumbnailsVTT([]string{"/tmp/thumbs/sprite_001.jpg", "/tmp/thumbs/sprite_002.jpg"}, 45, 90, 50, opts)
+	want := "WEBVTT\n" +
+		"\n00:00:00.000 --> 00:00:10.000\nsprite_001.jpg#xywh=0,0,90,50\n" +
+		"\n00:00:10.000 --> 00:00:20.000\nsprite_001.jpg#xywh=90,0,90,50\n" +
+		"\n00:00:20.000 --> 00:00:30.000\nsprite_001.jpg#xywh=0,50,90,50\n" +
+		"\n00:00:30.000 --> 00:00:40.000\nsprite_001.jpg#xywh=90,50,90,50\n" +
+		"\n00:00:40.000 --> 00:00:45.000\nsprite_002.jpg#xywh=0,0,90,50\n"
+	if got != want {
+		t.Errorf("buildThumbnailsVTT() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestBuildThumbnailsVTTCueCount(t *testing.T) {
+	opts := ThumbnailOptions{IntervalSeconds: 10, Width: 160, Columns: 2, Rows: 2}
+	tests := []struct {
+		name     string
+		sprites  int
+		duration float64
+		want     int
+	}{
+		{name: "exact multiple of the interval", sprites: 1, duration: 30, want: 3},
+		{name: "partial last interval", sprites: 1, duration: 30.5, want: 4},
+		{name: "shorter than one interval", sprites: 1, duration: 4, want: 1},
+		// Cues never point past the sheets FFmpeg wrote
+		{name: "capped by the sheets produced", sprites: 1, duration: 100, want: 4},
+		{name: "several sheets", sprites: 3, duration: 100, want: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sprites := make([]string, tt.sprites)
+			for i := range sprites {
+				sprites[i] = "sprite.jpg"
+			}
+			vtt := buildThumbnailsVTT(sprites, tt.duration, 160, 90, opts)
+			if got := strings.Count(vtt, " --> "); got != tt.want {
+				t.Errorf("got %d cues, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVTTTimestamp(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{0, "00:00:00.000"},
+		{1.5, "00:00:01.500"},
+		{59.9996, "00:01:00.000"},
+		{3723.25, "01:02:03.250"},
+	}
+	for _, tt := range tests {
+		if got := vttTimestamp(tt.seconds); got != tt.want {
+			t.Errorf("vttTimestamp(%g) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
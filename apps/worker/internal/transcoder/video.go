@@ -0,0 +1,160 @@
+package transcoder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Per-profile modes for deinterlacing and tone mapping
+const (
+	CorrectionAuto  = "auto"  // Apply when the source needs it
+	CorrectionForce = "force" // Always apply
+	CorrectionOff   = "off"   // Never apply
+)
+
+// Scan types recorded in MediaInfo.FieldOrder
+const (
+	FieldOrderProgressive = "progressive"
+	FieldOrderTFF         = "tff" // Interlaced, top field first
+	FieldOrderBFF         = "bff" // Interlaced, bottom field first
+)
+
+// HDR transfer characteristics recognized for tone mapping
+const (
+	HDRFormatPQ  = "pq"  // SMPTE ST 2084 (HDR10, Dolby Vision base layers)
+	HDRFormatHLG = "hlg" // ARIB STD-B67 hybrid log-gamma (broadcast, most phones)
+)
+
+const (
+	// idetFrames is how many frames interlace detection analyses
+	idetFrames = 500
+	// toneMapFilter converts PQ/HLG BT.2020 video to BT.709 SDR: linearize, tone map the
+	// highlights into SDR range with Hable's curve, then convert primaries and transfer
+	toneMapFilter = "zscale=t=linear:npl=100,format=gbrpf32le,zscale=p=bt709," +
+		"tonemap=tonemap=hable:desat=0,zscale=t=bt709:m=bt709:r=tv,format=yuv420p"
+)
+
+// idetSummaryRegex matches idet's multi-frame summary, which is more robust than the single-frame one
+var idetSummaryRegex = regexp.MustCompile(`Multi frame detection: TFF:\s*(\d+)\s+BFF:\s*(\d+)\s+Progressive:\s*(\d+)`)
+
+// HDRFormat returns HDRFormatPQ or HDRFormatHLG for HDR sources, or "" for SDR
+func (m *MediaInfo) HDRFormat() string {
+	switch m.ColorTransfer {
+	case "smpte2084":
+		return HDRFormatPQ
+	case "arib-std-b67":
+		return HDRFormatHLG
+	default:
+		return ""
+	}
+}
+
+// Interlaced reports whether interlace detection found the source to be interlaced
+func (m *MediaInfo) Interlaced() bool {
+	return m.FieldOrder == FieldOrderTFF || m.FieldOrder == FieldOrderBFF
+}
+
+// Corrections decides whether the profile deinterlaces and tone maps a source.
+// An empty mode means CorrectionAuto.
+func (p Profile) Corrections(source *MediaInfo) (deinterlace, toneMap bool) {
+	if p.AudioOnly {
+		return false, false
+	}
+	deinterlace = applyCorrection(p.Deinterlace, source != nil && source.Interlaced())
+	toneMap = applyCorrection(p.ToneMap, source != nil && source.HDRFormat() != "")
+	return deinterlace, toneMap
+}
+
+func applyCorrection(mode string, needed bool) bool {
+	switch mode {
+	case CorrectionForce:
+		return true
+	case CorrectionOff:
+		return false
+	default:
+		return needed
+	}
+}
+
+// DetectFieldOrder runs the idet filter over a stretch of the source and returns its scan type.
+// Sampling starts a tenth of the way in to skip slates and black leaders, which read as progressive.
+func DetectFieldOrder(ctx context.Context, inputPath string, durationSeconds float64) (string, error) {
+	var args []string
+	if durationSeconds > 0 {
+		args = append(args, "-ss", formatSeconds(durationSeconds/10))
+	}
+	args = append(args,
+		"-i", inputPath,
+		"-map", "0:v:0",
+		"-vf", "idet",
+		"-frames:v", strconv.Itoa(idetFrames),
+		"-an", "-sn",
+		"-f", "null", "-",
+	)
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", &CommandError{Op: "ffmpeg interlace detection", Err: err, Output: stderr.String()}
+	}
+	return parseIdetSummary(stderr.String())
+}
+
+// parseIdetSummary returns the scan type idet's multi-frame summary in output points to
+func parseIdetSummary(output string) (string, error) {
+	m := idetSummaryRegex.FindStringSubmatch(output)
+	if m == nil {
+		return "", fmt.Errorf("idet printed no summary")
+	}
+	tff, _ := strconv.Atoi(m[1])
+	bff, _ := strconv.Atoi(m[2])
+	progressive, _ := strconv.Atoi(m[3])
+
+	// Undetermined frames (static scenes) say nothing either way and are ignored
+	switch {
+	case tff+bff <= progressive:
+		return FieldOrderProgressive, nil
+	case tff >= bff:
+		return FieldOrderTFF, nil
+	default:
+		return FieldOrderBFF, nil
+	}
+}
+
+// CorrectionFilters returns the filter chain for the chosen corrections, or "" for none.
+// Quality measurement applies the same chain to the reference so it matches the rendition.
+func CorrectionFilters(deinterlace, toneMap bool) string {
+	var filters []string
+	if deinterlace {
+		filters = append(filters, deinterlaceFilter())
+	}
+	if toneMap {
+		filters = append(filters, toneMapFilter)
+	}
+	return strings.Join(filters, ",")
+}
+
+var (
+	deinterlacerOnce sync.Once
+	deinterlacer     string
+)
+
+// deinterlaceFilter returns the deinterlacing stage: bwdif where FFmpeg has it, yadif otherwise.
+// Both emit one frame per frame, so the frame rate is unchanged.
+func deinterlaceFilter() string {
+	deinterlacerOnce.Do(func() {
+		deinterlacer = "yadif=mode=send_frame:deint=all"
+		out, err := exec.Command("ffmpeg", "-hide_banner", "-filters").Output()
+		if err == nil && strings.Contains(string(out), " bwdif ") {
+			deinterlacer = "bwdif=mode=send_frame:deint=all"
+		}
+	})
+	return deinterlacer
+}
@@ -0,0 +1,107 @@
+package transcoder
+
+import (
+	"fmt"
+	"testing"
+)
+
+// idetOutput returns idet's summary lines with the given multi-frame counts. The single-frame
+// line always reads as interlaced, so a result that follows it shows the wrong line was used.
+func idetOutput(tff, bff, progressive, undetermined int) string {
+	return "[Parsed_idet_0 @ 0x55d0c3a4c2c0] Repeated Fields: Neither:   499 Top:     1 Bottom:     0\n" +
+		"[Parsed_idet_0 @ 0x55d0c3a4c2c0] Single frame detection: TFF:   400 BFF:     0 Progressive:    20 Undetermined:    80\n" +
+		fmt.Sprintf("[Parsed_idet_0 @ 0x55d0c3a4c2c0] Multi frame detection: TFF: %5d BFF: %5d Progressive: %5d Undetermined: %5d\n",
+			tff, bff, progressive, undetermined)
+}
+
+func TestParseIdetSummary(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{name: "progressive", output: idetOutput(0, 0, 480, 20), want: FieldOrderProgressive},
+		{name: "top field first", output: idetOutput(450, 2, 10, 38), want: FieldOrderTFF},
+		{name: "bottom field first", output: idetOutput(3, 460, 12, 25), want: FieldOrderBFF},
+		{name: "interlaced frames equal progressive", output: idetOutput(100, 100, 200, 100), want: FieldOrderProgressive},
+		{name: "interlaced frames just outnumber progressive", output: idetOutput(101, 100, 200, 99), want: FieldOrderTFF},
+		{name: "field orders tied", output: idetOutput(150, 150, 100, 100), want: FieldOrderTFF},
+		// Static scenes leave most frames undetermined; only the decided frames count
+		{name: "mostly undetermined", output: idetOutput(30, 0, 10, 460), want: FieldOrderTFF},
+		{name: "all undetermined", output: idetOutput(0, 0, 0, 500), want: FieldOrderProgressive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseIdetSummary(tt.output)
+			if err != nil || got != tt.want {
+				t.Errorf("parseIdetSummary() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	if got, err := parseIdetSummary("Output #0, null, to 'pipe:':\n"); err == nil {
+		t.Errorf("parseIdetSummary() without a summary = %q, want an error", got)
+	}
+}
+
+func TestHDRFormat(t *testing.T) {
+	tests := []struct {
+		transfer string
+		want     string
+	}{
+		{"smpte2084", HDRFormatPQ},
+		{"arib-std-b67", HDRFormatHLG},
+		{"bt709", ""},
+		{"bt2020-10", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		info := MediaInfo{ColorTransfer: tt.transfer}
+		if got := info.HDRFormat(); got != tt.want {
+			t.Errorf("HDRFormat() for %q = %q, want %q", tt.transfer, got, tt.want)
+		}
+	}
+}
+
+func TestCorrections(t *testing.T) {
+	sdr := &MediaInfo{FieldOrder: FieldOrderProgressive, ColorTransfer: "bt709"}
+	interlacedHDR := &MediaInfo{FieldOrder: FieldOrderBFF, ColorTransfer: "smpte2084"}
+
+	tests := []struct {
+		name            string
+		profile         Profile
+		source          *MediaInfo
+		wantDeinterlace bool
+		wantToneMap     bool
+	}{
+		{name: "auto on progressive SDR", source: sdr},
+		{name: "auto on interlaced HDR", source: interlacedHDR, wantDeinterlace: true, wantToneMap: true},
+		{name: "explicit auto", profile: Profile{Deinterlace: CorrectionAuto, ToneMap: CorrectionAuto}, source: interlacedHDR, wantDeinterlace: true, wantToneMap: true},
+		{name: "forced on progressive SDR", profile: Profile{Deinterlace: CorrectionForce, ToneMap: CorrectionForce}, source: sdr, wantDeinterlace: true, wantToneMap: true},
+		{name: "off on interlaced HDR", profile: Profile{Deinterlace: CorrectionOff, ToneMap: CorrectionOff}, source: interlacedHDR},
+		{name: "field order not detected", source: &MediaInfo{ColorTransfer: "arib-std-b67"}, wantToneMap: true},
+		{name: "no source", profile: Profile{ToneMap: CorrectionForce}, wantToneMap: true},
+		{name: "audio only", profile: Profile{AudioOnly: true, Deinterlace: CorrectionForce, ToneMap: CorrectionForce}, source: interlacedHDR},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deinterlace, toneMap := tt.profile.Corrections(tt.source)
+			if deinterlace != tt.wantDeinterlace || toneMap != tt.wantToneMap {
+				t.Errorf("Corrections() = %t, %t, want %t, %t", deinterlace, toneMap, tt.wantDeinterlace, tt.wantToneMap)
+			}
+		})
+	}
+}
+
+func TestCorrectionFilters(t *testing.T) {
+	if got := CorrectionFilters(false, false); got != "" {
+		t.Errorf("CorrectionFilters(false, false) = %q, want none", got)
+	}
+	if got := CorrectionFilters(false, true); got != toneMapFilter {
+		t.Errorf("CorrectionFilters(false, true) = %q, want %q", got, toneMapFilter)
+	}
+	// Deinterlacing runs before tone mapping, on the source's own fields
+	if got, want := CorrectionFilters(true, true), deinterlaceFilter()+","+toneMapFilter; got != want {
+		t.Errorf("CorrectionFilters(true, true) = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,76 @@
+package transcoder
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Watermark positions, shared by the logo and the text
+const (
+	PositionTopLeft     = "top-left"
+	PositionTopRight    = "top-right"
+	PositionBottomLeft  = "bottom-left"
+	PositionBottomRight = "bottom-right"
+	PositionCenter      = "center"
+)
+
+// Watermark is a logo and/or line of text burned into every frame of a rendition.
+// Sizes are relative to the output height so the watermark looks the same on every rung.
+type Watermark struct {
+	ImagePath string  // Local logo image (PNG with alpha works best); empty for text only
+	Position  string  // Logo corner, see the Position constants
+	Margin    int     // Distance from the frame edges in output pixels
+	Opacity   float64 // 0-1, applied to the logo and the text
+	Scale     float64 // Logo height as a fraction of the output height
+
+	Text         string  // Optional text overlay
+	TextPosition string  // Text corner, see the Position constants
+	TextSize     float64 // Font size as a fraction of the output height
+	TextColor    string  // FFmpeg color, e.g. "white" or "#ffcc00"
+}
+
+// textFilter returns the drawtext stage for the watermark's text
+func (w *Watermark) textFilter() string {
+	x, y := placement(w.TextPosition, w.Margin, "w", "h", "tw", "th")
+	return fmt.Sprintf("drawtext=text=%s:expansion=none:fontsize=h*%s:fontcolor=%s@%s:x=%s:y=%s",
+		escapeFilterValue(w.Text), formatFactor(w.TextSize), w.TextColor, formatFactor(w.Opacity), x, y)
+}
+
+// overlayGraph returns a filtergraph that runs chain on the main video (input 0), then
+// overlays the logo (input 1) sized against the result. The output is labelled [vout].
+// The logo input must be looped (-loop 1) so it lasts as long as the video.
+func (w *Watermark) overlayGraph(chain string) string {
+	x, y := placement(w.Position, w.Margin, "main_w", "main_h", "overlay_w", "overlay_h")
+	return fmt.Sprintf(
+		"[0:v]%s[base];"+
+			"[1:v]format=rgba,colorchannelmixer=aa=%s[logo];"+
+			"[logo][base]scale2ref=w=oh*a:h=main_h*%s[wm][ref];"+
+			"[ref][wm]overlay=x=%s:y=%s:shortest=1[vout]",
+		chain, formatFactor(w.Opacity), formatFactor(w.Scale), x, y)
+}
+
+// placement returns x/y expressions putting an object of size objW x objH in a corner
+// (or the center) of a frame of size frameW x frameH, margin pixels from the edges
+func placement(position string, margin int, frameW, frameH, objW, objH string) (x, y string) {
+	m := strconv.Itoa(margin)
+	left, right := m, fmt.Sprintf("%s-%s-%s", frameW, objW, m)
+	top, bottom := m, fmt.Sprintf("%s-%s-%s", frameH, objH, m)
+
+	switch position {
+	case PositionTopLeft:
+		return left, top
+	case PositionBottomLeft:
+		return left, bottom
+	case PositionBottomRight:
+		return right, bottom
+	case PositionCenter:
+		return fmt.Sprintf("(%s-%s)/2", frameW, objW), fmt.Sprintf("(%s-%s)/2", frameH, objH)
+	default:
+		return right, top
+	}
+}
+
+// formatFactor formats a fraction for a filter expression
+func formatFactor(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,96 @@
+package transcoder
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestPlacement(t *testing.T) {
+	tests := []struct {
+		position string
+		wantX    string
+		wantY    string
+	}{
+		{PositionTopLeft, "16", "16"},
+		{PositionTopRight, "W-w-16", "16"},
+		{PositionBottomLeft, "16", "H-h-16"},
+		{PositionBottomRight, "W-w-16", "H-h-16"},
+		{PositionCenter, "(W-w)/2", "(H-h)/2"},
+		{"", "W-w-16", "16"},
+	}
+	for _, tt := range tests {
+		x, y := placement(tt.position, 16, "W", "H", "w", "h")
+		if x != tt.wantX || y != tt.wantY {
+			t.Errorf("placement(%q) = %s, %s, want %s, %s", tt.position, x, y, tt.wantX, tt.wantY)
+		}
+	}
+}
+
+func TestTextFilter(t *testing.T) {
+	w := &Watermark{
+		Margin:       16,
+		Opacity:      0.5,
+		Text:         "it's: live",
+		TextPosition: PositionBottomLeft,
+		TextSize:     0.04,
+		TextColor:    "#ffcc00",
+	}
+	want := `drawtext=text=it\\\'s\\: live:expansion=none:fontsize=h*0.04:fontcolor=#ffcc00@0.5:x=16:y=h-th-16`
+	if got := w.textFilter(); got != want {
+		t.Errorf("textFilter() = %s, want %s", got, want)
+	}
+}
+
+func TestOverlayGraph(t *testing.T) {
+	w := &Watermark{ImagePath: "logo.png", Position: PositionTopRight, Margin: 16, Opacity: 0.8, Scale: 0.1}
+	want := "[0:v]scale=-2:720[base];" +
+		"[1:v]format=rgba,colorchannelmixer=aa=0.8[logo];" +
+		"[logo][base]scale2ref=w=oh*a:h=main_h*0.1[wm][ref];" +
+		"[ref][wm]overlay=x=main_w-overlay_w-16:y=16:shortest=1[vout]"
+	if got := w.overlayGraph("scale=-2:720"); got != want {
+		t.Errorf("overlayGraph() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestBuildArgsWatermark(t *testing.T) {
+	profile := Profile{Name: "720p", Scale: "-2:720", VideoCodec: "libx264", AudioCodec: "aac", Preset: "fast"}
+
+	t.Run("text only", func(t *testing.T) {
+		w := &Watermark{Margin: 16, Opacity: 1, Text: "draft", TextPosition: PositionBottomLeft, TextSize: 0.04, TextColor: "white"}
+		args := buildArgs("in.mp4", "out.mp4", profile, Options{Watermark: w}, 0, "")
+		want := []string{"-vf", "scale=-2:720," + w.textFilter()}
+		if !containsArgs(args, want) {
+			t.Errorf("buildArgs() = %q, want it to contain %q", args, want)
+		}
+		if slices.Contains(args, "-filter_complex") || slices.Contains(args, "-map") {
+			t.Errorf("buildArgs() = %q, want a plain filter chain without maps", args)
+		}
+	})
+
+	t.Run("logo and text", func(t *testing.T) {
+		w := &Watermark{
+			ImagePath: "logo.png", Position: PositionTopRight, Margin: 16, Opacity: 1, Scale: 0.1,
+			Text: "draft", TextPosition: PositionBottomLeft, TextSize: 0.04, TextColor: "white",
+		}
+		args := buildArgs("in.mp4", "out.mp4", profile, Options{Watermark: w}, 0, "")
+		// The logo is looped as a second input and overlaid after the text is drawn
+		want := []string{"-i", "in.mp4", "-loop", "1", "-i", "logo.png", "-filter_complex", w.overlayGraph("scale=-2:720," + w.textFilter())}
+		if !reflect.DeepEqual(args[:len(want)], want) {
+			t.Errorf("buildArgs() starts with %q, want %q", args[:len(want)], want)
+		}
+		if maps := []string{"-map", "[vout]", "-map", "0:a:0?"}; !containsArgs(args, maps) {
+			t.Errorf("buildArgs() = %q, want it to contain %q", args, maps)
+		}
+	})
+}
+
+// containsArgs reports whether want appears in args as a contiguous run
+func containsArgs(args, want []string) bool {
+	for i := 0; i+len(want) <= len(args); i++ {
+		if slices.Equal(args[i:i+len(want)], want) {
+			return true
+		}
+	}
+	return false
+}
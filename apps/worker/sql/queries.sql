@@ -3,9 +3,18 @@ SELECT * FROM jobs
 WHERE id = $1;
 
 -- name: UpdateJobStatus :one
+-- Cancellation is final, so a cancelled job is never moved to another status.
+-- Failures with an error code go through FailJob.
 UPDATE jobs
-SET status = $2, error_message = $3
-WHERE id = $1
+SET status = $2, error_message = $3, error_code = NULL
+WHERE id = $1 AND status <> 'cancelled'
+RETURNING *;
+
+-- name: FailJob :one
+-- Mark a job as failed with its classified error; jobs that already finished are left alone
+UPDATE jobs
+SET status = 'failed', error_message = $2, error_code = $3
+WHERE id = $1 AND status NOT IN ('completed', 'partial', 'cancelled')
 RETURNING *;
 
 -- name: GetRenditionsByJobID :many
@@ -19,13 +28,64 @@ SET output_key = $2
 WHERE id = $1
 RETURNING *;
 
+-- name: UpdateRenditionHLSPlaylistKey :one
+UPDATE renditions
+SET hls_playlist_key = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateJobHLSMasterKey :one
+UPDATE jobs
+SET hls_master_key = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateRenditionDASHRepresentation :one
+UPDATE renditions
+SET dash_representation_id = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateJobDASHManifestKey :one
+UPDATE jobs
+SET dash_manifest_key = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: UpsertMediaProbe :one
+-- Store the ffprobe results for a job's input (replaced on retry)
+INSERT INTO media_probes (
+    job_id, duration_seconds, container, video_codec, audio_codec, width, height,
+    frame_rate, bit_rate, rotation, audio_channels, audio_channel_layout,
+    field_order, color_transfer, color_primaries, hdr_format
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+ON CONFLICT (job_id) DO UPDATE
+SET duration_seconds = EXCLUDED.duration_seconds,
+    container = EXCLUDED.container,
+    video_codec = EXCLUDED.video_codec,
+    audio_codec = EXCLUDED.audio_codec,
+    width = EXCLUDED.width,
+    height = EXCLUDED.height,
+    frame_rate = EXCLUDED.frame_rate,
+    bit_rate = EXCLUDED.bit_rate,
+    rotation = EXCLUDED.rotation,
+    audio_channels = EXCLUDED.audio_channels,
+    audio_channel_layout = EXCLUDED.audio_channel_layout,
+    field_order = EXCLUDED.field_order,
+    color_transfer = EXCLUDED.color_transfer,
+    color_primaries = EXCLUDED.color_primaries,
+    hdr_format = EXCLUDED.hdr_format
+RETURNING *;
+
 -- name: StartJobProcessing :one
 -- Atomically claim a job for processing by setting worker_id and started_at
 UPDATE jobs
 SET status = 'processing',
     worker_id = $2,
     started_at = NOW(),
-    error_message = NULL
+    error_message = NULL,
+    error_code = NULL
 WHERE id = $1 AND (status = 'queued' OR (status = 'processing' AND started_at < NOW() - INTERVAL '10 minutes'))
 RETURNING *;
 
@@ -36,22 +96,218 @@ SET status = 'queued',
     retry_count = retry_count + 1,
     worker_id = NULL,
     started_at = NULL
-WHERE id = $1
+WHERE id = $1 AND status <> 'cancelled'
 RETURNING *;
 
+-- name: ReleaseWorkerJob :exec
+-- Return a job claimed by a dead worker to queued so its recovered queue entry can claim it again
+UPDATE jobs
+SET status = 'queued',
+    worker_id = NULL,
+    started_at = NULL
+WHERE id = $1 AND status = 'processing' AND worker_id = $2;
+
 -- name: GetStaleJobs :many
--- Find jobs that have been processing for too long (stuck workers)
+-- Find jobs that have been processing for too long (stuck workers).
+-- A split job's chunks are timed out one by one (GetStaleJobChunks), so it is only found here
+-- once every chunk was encoded over 10 minutes ago: its stitch stalled or was never queued.
 SELECT * FROM jobs
 WHERE status = 'processing'
 AND started_at < NOW() - INTERVAL '10 minutes'
+AND NOT EXISTS (
+    SELECT 1 FROM job_chunks WHERE job_chunks.job_id = jobs.id
+    AND (job_chunks.status <> 'completed' OR job_chunks.finished_at > NOW() - INTERVAL '10 minutes')
+)
 LIMIT 100;
 
 -- name: ResetStalledJob :one
--- Reset a stalled job back to queued status
+-- Reset a stalled job back to queued status, counting the lost attempt as a retry.
+-- A job reclaimed since it was found stale is left alone.
 UPDATE jobs
 SET status = 'queued',
+    retry_count = retry_count + 1,
     worker_id = NULL,
     started_at = NULL
 WHERE id = $1 AND status = 'processing'
+AND started_at < NOW() - INTERVAL '10 minutes'
+RETURNING *;
+
+
+-- name: GetProfile :one
+SELECT * FROM profiles
+WHERE name = $1;
+
+-- name: StartRendition :one
+-- Mark a rendition as encoding, clearing the outcome of any previous attempt
+UPDATE renditions
+SET status = 'processing',
+    started_at = NOW(),
+    finished_at = NULL,
+    error_message = NULL
+WHERE id = $1
+RETURNING *;
+
+-- name: CompleteRendition :one
+UPDATE renditions
+SET status = 'completed',
+    finished_at = NOW(),
+    output_size_bytes = $2,
+    duration_seconds = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: FailRendition :one
+UPDATE renditions
+SET status = 'failed',
+    finished_at = NOW(),
+    error_message = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: UpsertJobArtifact :one
+INSERT INTO job_artifacts (job_id, kind, output_key, content_type, language)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (job_id, output_key) DO UPDATE
+SET kind = EXCLUDED.kind,
+    content_type = EXCLUDED.content_type,
+    language = EXCLUDED.language
+RETURNING *;
+
+-- name: UpdateJobLadder :one
+UPDATE jobs
+SET ladder = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: SetRenditionTargetBitrate :one
+UPDATE renditions
+SET target_bitrate_kbps = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteRendition :exec
+-- Drop a rung the per-title analysis found redundant
+DELETE FROM renditions
+WHERE id = $1;
+
+-- name: UpdateRenditionQuality :one
+UPDATE renditions
+SET vmaf_mean = $2,
+    vmaf_min = $3,
+    vmaf_p5 = $4,
+    psnr_mean = $5,
+    ssim_mean = $6
+WHERE id = $1
 RETURNING *;
 
+-- name: UpdateRenditionLoudness :one
+UPDATE renditions
+SET loudness_input_i = $2,
+    loudness_input_tp = $3,
+    loudness_input_lra = $4,
+    loudness_output_i = $5,
+    loudness_output_tp = $6,
+    loudness_output_lra = $7
+WHERE id = $1
+RETURNING *;
+
+-- name: GetMediaProbe :one
+SELECT * FROM media_probes
+WHERE job_id = $1;
+
+-- name: DeleteJobChunks :exec
+-- Drop the chunks of an earlier split before a job is split again
+DELETE FROM job_chunks
+WHERE job_id = $1;
+
+-- name: CreateJobChunk :one
+INSERT INTO job_chunks (job_id, chunk_index, source_key, start_seconds, duration_seconds)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetJobChunks :many
+SELECT * FROM job_chunks
+WHERE job_id = $1
+ORDER BY chunk_index;
+
+-- name: StartJobChunk :one
+-- Atomically claim a pending or failed chunk for encoding, or one whose worker stalled
+UPDATE job_chunks
+SET status = 'processing',
+    attempts = attempts + 1,
+    worker_id = $3,
+    started_at = NOW(),
+    finished_at = NULL,
+    error_message = NULL
+WHERE job_id = $1 AND chunk_index = $2
+AND (status IN ('pending', 'failed') OR (status = 'processing' AND started_at < NOW() - INTERVAL '10 minutes'))
+RETURNING *;
+
+-- name: CompleteJobChunk :one
+UPDATE job_chunks
+SET status = 'completed',
+    finished_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: FailJobChunk :one
+UPDATE job_chunks
+SET status = 'failed',
+    finished_at = NOW(),
+    error_message = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: CountUnfinishedJobChunks :one
+SELECT COUNT(*) FROM job_chunks
+WHERE job_id = $1 AND status <> 'completed';
+
+-- name: StartJobStitch :one
+-- Claim a chunked job for stitching once every chunk is encoded. A retried stitch finds the job re-queued.
+UPDATE jobs
+SET status = 'processing',
+    worker_id = $2,
+    started_at = NOW(),
+    error_message = NULL,
+    error_code = NULL
+WHERE id = $1 AND status IN ('processing', 'queued')
+AND NOT EXISTS (SELECT 1 FROM job_chunks WHERE job_chunks.job_id = jobs.id AND job_chunks.status <> 'completed')
+RETURNING *;
+
+-- name: GetJobChunk :one
+SELECT * FROM job_chunks
+WHERE job_id = $1 AND chunk_index = $2;
+
+-- name: GetStaleJobChunks :many
+-- Find chunks of running jobs that have been encoding for too long (stuck workers), or waiting
+-- to be encoded for too long (their queue entry or scheduled retry was lost)
+SELECT * FROM job_chunks
+WHERE (
+    (status = 'processing' AND started_at < NOW() - INTERVAL '10 minutes')
+    OR (status IN ('pending', 'failed') AND updated_at < NOW() - INTERVAL '10 minutes')
+)
+AND EXISTS (SELECT 1 FROM jobs WHERE jobs.id = job_chunks.job_id AND jobs.status = 'processing')
+LIMIT 100;
+
+-- name: ResetStalledJobChunk :one
+-- Return a stalled chunk to pending before it is queued again; a claim already counted any lost
+-- attempt. Bumping updated_at keeps the chunk from being found stale again while it waits.
+-- A chunk claimed since it was found stale is left alone.
+UPDATE job_chunks
+SET status = 'pending',
+    worker_id = NULL,
+    started_at = NULL
+WHERE id = $1
+AND (
+    (status = 'processing' AND started_at < NOW() - INTERVAL '10 minutes')
+    OR (status IN ('pending', 'failed') AND updated_at < NOW() - INTERVAL '10 minutes')
+)
+RETURNING *;
+
+-- name: ReleaseWorkerJobChunk :exec
+-- Return a chunk claimed by a dead worker to pending so its recovered queue entry can claim it again
+UPDATE job_chunks
+SET status = 'pending',
+    worker_id = NULL,
+    started_at = NULL
+WHERE job_id = $1 AND chunk_index = $2 AND status = 'processing' AND worker_id = $3;
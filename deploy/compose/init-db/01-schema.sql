@@ -1,7 +1,13 @@
 -- Cloud Distributed Transcode Pipeline
 
 -- Job status enum
-CREATE TYPE job_status AS ENUM ('queued', 'processing', 'completed', 'failed');
+CREATE TYPE job_status AS ENUM ('queued', 'processing', 'completed', 'partial', 'failed', 'cancelled');
+
+-- Rendition status enum
+CREATE TYPE rendition_status AS ENUM ('pending', 'processing', 'completed', 'failed');
+
+-- Chunk status enum (chunked jobs)
+CREATE TYPE chunk_status AS ENUM ('pending', 'processing', 'completed', 'failed');
 
 -- Jobs table: tracks each transcode request
 CREATE TABLE jobs (
@@ -9,10 +15,15 @@ CREATE TABLE jobs (
     input_key TEXT NOT NULL,              -- S3 key for uploaded file (e.g., "uploads/{id}/input.mp4")
     status job_status NOT NULL DEFAULT 'queued',
     error_message TEXT,                   -- Error details if status = 'failed'
+    error_code TEXT,                      -- Machine-readable failure kind (e.g., "invalid_input") if status = 'failed'
     retry_count INT NOT NULL DEFAULT 0,   -- Number of retry attempts so far
     max_retries INT NOT NULL DEFAULT 3,   -- Maximum retry attempts before moving to dead letter
     started_at TIMESTAMPTZ,               -- When processing started (for timeout detection)
     worker_id TEXT,                       -- ID of worker currently processing this job
+    options JSONB NOT NULL DEFAULT '{}',  -- Processing options requested at creation (e.g., {"packaging": ["hls"]})
+    hls_master_key TEXT,                  -- S3 key for the HLS master playlist (NULL unless HLS was requested)
+    dash_manifest_key TEXT,               -- S3 key for the DASH MPD manifest (NULL unless DASH was requested)
+    ladder JSONB,                         -- Per-title ladder chosen by the worker (NULL unless per_title was requested)
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
@@ -23,10 +34,117 @@ CREATE TABLE renditions (
     job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
     resolution TEXT NOT NULL,             -- Supported "480p", "720p", "1080p"
     output_key TEXT,                      -- S3 key for output file (NULL until completed)
+    hls_playlist_key TEXT,                -- S3 key for the HLS media playlist (NULL unless HLS was requested)
+    dash_representation_id TEXT,          -- Representation ID in the job's DASH manifest (NULL unless DASH was requested)
+    status rendition_status NOT NULL DEFAULT 'pending',
+    error_message TEXT,                   -- Error details if status = 'failed'
+    started_at TIMESTAMPTZ,               -- When encoding of this rendition started
+    finished_at TIMESTAMPTZ,              -- When this rendition completed or failed
+    output_size_bytes BIGINT,             -- Size of the encoded output file
+    duration_seconds DOUBLE PRECISION,    -- Duration of the encoded output
+    target_bitrate_kbps INT,              -- Per-title video bitrate overriding the profile's rate control
+    vmaf_mean DOUBLE PRECISION,           -- Quality scores against the source (NULL unless measured)
+    vmaf_min DOUBLE PRECISION,
+    vmaf_p5 DOUBLE PRECISION,             -- 5th percentile frame VMAF
+    psnr_mean DOUBLE PRECISION,           -- Luma PSNR in dB
+    ssim_mean DOUBLE PRECISION,
+    loudness_input_i DOUBLE PRECISION,    -- EBU R128 loudness before normalization: integrated LUFS,
+    loudness_input_tp DOUBLE PRECISION,   -- true peak (dBTP)
+    loudness_input_lra DOUBLE PRECISION,  -- and loudness range (LU); NULL unless normalized
+    loudness_output_i DOUBLE PRECISION,   -- The same measured on the encoded rendition
+    loudness_output_tp DOUBLE PRECISION,
+    loudness_output_lra DOUBLE PRECISION,
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     UNIQUE(job_id, resolution)            -- One rendition per resolution per job
 );
 
+-- Media probes table: source metadata captured by ffprobe before transcoding
+CREATE TABLE media_probes (
+    job_id UUID PRIMARY KEY REFERENCES jobs(id) ON DELETE CASCADE,
+    duration_seconds DOUBLE PRECISION,    -- Container duration
+    container TEXT,                       -- ffprobe format name (e.g., "mov,mp4,m4a,3gp,3g2,mj2")
+    video_codec TEXT,                     -- NULL if the source has no video stream
+    audio_codec TEXT,                     -- NULL if the source has no audio stream
+    width INT,
+    height INT,
+    frame_rate DOUBLE PRECISION,          -- Average frames per second
+    bit_rate BIGINT,                      -- Overall bitrate in bits per second
+    rotation INT NOT NULL DEFAULT 0,      -- Display rotation in degrees (0, 90, 180, 270)
+    audio_channels INT,
+    audio_channel_layout TEXT,            -- e.g., "stereo", "5.1"
+    field_order TEXT,                     -- Scan type detected with idet: "progressive", "tff" or "bff"
+    color_transfer TEXT,                  -- e.g., "bt709", "smpte2084" (PQ), "arib-std-b67" (HLG)
+    color_primaries TEXT,                 -- e.g., "bt709", "bt2020"
+    hdr_format TEXT,                      -- "pq" or "hlg"; NULL for SDR sources
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Job artifacts table: auxiliary outputs of a job other than renditions (thumbnails, etc.)
+CREATE TABLE job_artifacts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,                   -- e.g., "sprite", "thumbnails_vtt"
+    output_key TEXT NOT NULL,             -- S3 key of the artifact
+    content_type TEXT NOT NULL,
+    language TEXT,                        -- Subtitle/caption language (ISO 639), NULL for other artifacts
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(job_id, output_key)            -- Re-running a job replaces its artifacts
+);
+
+-- Job chunks table: keyframe-aligned pieces of a chunked job's source, encoded by any worker
+CREATE TABLE job_chunks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    chunk_index INT NOT NULL,             -- Position in the source, from 0
+    source_key TEXT NOT NULL,             -- S3 key of the stream-copied source chunk
+    start_seconds DOUBLE PRECISION NOT NULL, -- Where the chunk starts in the source
+    duration_seconds DOUBLE PRECISION NOT NULL,
+    status chunk_status NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,      -- Encode attempts so far; a failed chunk is retried on its own
+    worker_id TEXT,                       -- Worker encoding the chunk (or the last one that tried)
+    error_message TEXT,                   -- Error details if status = 'failed'
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(), -- Last change; a chunk waiting this long lost its queue entry
+    UNIQUE(job_id, chunk_index)
+);
+
+-- Profiles table: encoding settings, looked up by renditions.resolution
+CREATE TABLE profiles (
+    name TEXT PRIMARY KEY,                -- e.g., "720p"; what jobs request as a resolution
+    scale TEXT NOT NULL DEFAULT '',       -- FFmpeg scale filter (e.g., "-2:720"); empty for audio-only profiles
+    video_codec TEXT NOT NULL DEFAULT 'libx264',
+    audio_codec TEXT NOT NULL DEFAULT 'aac',
+    preset TEXT NOT NULL DEFAULT 'fast',
+    rate_control TEXT NOT NULL DEFAULT 'crf', -- crf, vbr (single-pass target bitrate) or abr_2pass
+    crf INT,                              -- Constant quality (rate_control = 'crf')
+    video_bitrate TEXT,                   -- Target video bitrate for vbr/abr_2pass (e.g., "2500k")
+    max_rate TEXT,                        -- Peak video bitrate cap (e.g., "3000k")
+    buf_size TEXT,                        -- Rate control buffer for max_rate (e.g., "6000k")
+    keyframe_interval DOUBLE PRECISION DEFAULT 2, -- Seconds between forced keyframes; NULL leaves GOP to the encoder
+    audio_bitrate TEXT,                   -- Target audio bitrate (e.g., "128k")
+    max_fps INT,                          -- Frame rate cap; sources above it are reduced
+    container TEXT NOT NULL DEFAULT 'mp4', -- Output container and file extension (mp4, mov, mkv, webm, m4a, mp3, opus)
+    audio_only BOOLEAN NOT NULL DEFAULT FALSE, -- Drop the video stream (-vn); scale and video settings are ignored
+    audio_sample_rate INT,                -- Output sample rate in Hz (e.g., 44100); NULL keeps the source rate
+    audio_channels INT,                   -- Output channel count (e.g., 2); NULL keeps the source layout
+    min_vmaf DOUBLE PRECISION,            -- Renditions scoring a lower mean VMAF fail; NULL disables the check
+    watermark JSONB,                      -- Logo/text overlay burned into video renditions; NULL for none
+    loudness_target DOUBLE PRECISION,     -- Integrated loudness (LUFS, e.g. -23) to normalize to; NULL disables loudnorm
+    true_peak_limit DOUBLE PRECISION,     -- True peak ceiling (dBTP) when normalizing; NULL for -1
+    deinterlace TEXT NOT NULL DEFAULT 'auto', -- auto (when idet detects interlacing), force or off
+    tone_map TEXT NOT NULL DEFAULT 'auto',    -- HDR to BT.709 SDR: auto (PQ/HLG sources), force or off
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Default ladder (matches the worker's built-in profiles)
+INSERT INTO profiles (name, scale) VALUES
+    ('480p', '-2:480'),
+    ('720p', '-2:720'),
+    ('1080p', '-2:1080');
+
 -- Index for faster job lookups by status (useful for worker queries)
 CREATE INDEX idx_jobs_status ON jobs(status);
 
@@ -48,3 +166,15 @@ CREATE TRIGGER update_jobs_updated_at
     FOR EACH ROW
     EXECUTE FUNCTION update_updated_at_column();
 
+-- Trigger to auto-update profiles.updated_at on any update
+CREATE TRIGGER update_profiles_updated_at
+    BEFORE UPDATE ON profiles
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Trigger to auto-update job_chunks.updated_at on any update
+CREATE TRIGGER update_job_chunks_updated_at
+    BEFORE UPDATE ON job_chunks
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
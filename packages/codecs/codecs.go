@@ -0,0 +1,40 @@
+// Package codecs lists the encoders, presets and containers transcoding profiles may use.
+// The API validates profiles against it and the worker checks them again before encoding,
+// so the two can't disagree about what a profile may ask for.
+package codecs
+
+// PresetSpeeds ranks the x264-style preset names profiles use, fastest first.
+// Encoders with numeric speed controls map these onto their own scale.
+var PresetSpeeds = map[string]int{
+	"ultrafast": 0,
+	"superfast": 1,
+	"veryfast":  2,
+	"faster":    3,
+	"fast":      4,
+	"medium":    5,
+	"slow":      6,
+	"slower":    7,
+	"veryslow":  8,
+}
+
+// Video describes what one FFmpeg video encoder accepts
+type Video struct {
+	// MaxCRF is the top of the encoder's constant quality scale
+	MaxCRF int
+	// Containers lists the output containers that can carry the codec
+	Containers map[string]bool
+	// TwoPass is set when FFmpeg can run the encoder as a two-pass encode
+	TwoPass bool
+}
+
+// VideoCodecs lists the supported video encoders keyed by FFmpeg encoder name
+var VideoCodecs = map[string]Video{
+	"libx264":    {MaxCRF: 51, Containers: map[string]bool{"mp4": true, "mov": true, "mkv": true}, TwoPass: true},
+	"libx265":    {MaxCRF: 51, Containers: map[string]bool{"mp4": true, "mov": true, "mkv": true}, TwoPass: true},
+	"libsvtav1":  {MaxCRF: 63, Containers: map[string]bool{"mp4": true, "mkv": true, "webm": true}},
+	"libaom-av1": {MaxCRF: 63, Containers: map[string]bool{"mp4": true, "mkv": true, "webm": true}, TwoPass: true},
+	"libvpx-vp9": {MaxCRF: 63, Containers: map[string]bool{"webm": true, "mkv": true, "mp4": true}, TwoPass: true},
+}
+
+// WebMAudioCodecs are the only audio encoders the WebM container accepts
+var WebMAudioCodecs = map[string]bool{"libopus": true, "libvorbis": true}
@@ -0,0 +1,3 @@
+module github.com/JakeHolcombe16/Cloud-Distributed-Transcode-Pipeline/packages/codecs
+
+go 1.23
@@ -0,0 +1,34 @@
+// Package jobqueue names the Redis keys the API, GraphQL service and worker share, and holds
+// the operations more than one service performs on them.
+package jobqueue
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// JobQueueKey is the Redis list of queue entries waiting for a worker
+	JobQueueKey = "jobs:pending"
+	// DelayedQueueKey is the Redis sorted set of retries waiting for their backoff, scored by due time (Unix ms)
+	DelayedQueueKey = "jobs:delayed"
+	// ProgressKeyPrefix is the prefix for per-job progress hashes (one field per rendition)
+	ProgressKeyPrefix = "job:progress:"
+	// CancelKeyPrefix is the prefix for job cancellation flags watched by workers
+	CancelKeyPrefix = "job:cancel:"
+	// CancelTTL is how long a cancellation flag is kept, long enough to outlive any pending retry
+	CancelTTL = 24 * time.Hour
+)
+
+// Cancel flags a job as cancelled for the worker processing it and removes any copies of the
+// job ID still waiting in the queue or for a retry
+func Cancel(ctx context.Context, client redis.Cmdable, jobID string) error {
+	pipe := client.TxPipeline()
+	pipe.Set(ctx, CancelKeyPrefix+jobID, "1", CancelTTL)
+	pipe.LRem(ctx, JobQueueKey, 0, jobID)
+	pipe.ZRem(ctx, DelayedQueueKey, jobID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
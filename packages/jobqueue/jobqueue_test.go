@@ -0,0 +1,43 @@
+package jobqueue
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestCancel(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	mr.Lpush(JobQueueKey, "job-1")
+	mr.Lpush(JobQueueKey, "job-2")
+	mr.Lpush(JobQueueKey, "job-1")
+	if _, err := mr.ZAdd(DelayedQueueKey, 1, "job-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mr.ZAdd(DelayedQueueKey, 2, "job-3"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Cancel(ctx, client, "job-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := mr.Get(CancelKeyPrefix + "job-1"); err != nil || got != "1" {
+		t.Errorf("cancel flag = %q (%v), want \"1\"", got, err)
+	}
+	if ttl := mr.TTL(CancelKeyPrefix + "job-1"); ttl != CancelTTL {
+		t.Errorf("cancel flag TTL = %v, want %v", ttl, CancelTTL)
+	}
+	if queued, _ := mr.List(JobQueueKey); len(queued) != 1 || queued[0] != "job-2" {
+		t.Errorf("queue = %v, want [job-2]", queued)
+	}
+	if delayed, _ := mr.ZMembers(DelayedQueueKey); len(delayed) != 1 || delayed[0] != "job-3" {
+		t.Errorf("delayed set = %v, want [job-3]", delayed)
+	}
+}